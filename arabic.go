@@ -0,0 +1,170 @@
+package etiquette
+
+import "unicode"
+
+// Arabic letters are joined to their neighbours, so each one has up to four shapes: isolated, final (joined
+// to the letter before it), initial (joined to the letter after it) and medial (joined to both).
+// font.Drawer draws the glyph of each rune as is, so shapeArabic picks the right shape of each letter
+// from the Arabic Presentation Forms-B block, which fonts with Arabic generally have glyphs for.
+
+// arabicForms are the presentation forms of an Arabic letter, 0 if it doesn't have one.
+// Letters without initial and medial forms only join to the letter before them.
+type arabicForms struct {
+	isolated, final, initial, medial rune
+}
+
+// Presentation forms of the Arabic letters, by letter.
+var arabicLetters = map[rune]arabicForms{
+	0x0621: {isolated: 0xFE80},                // hamza
+	0x0622: {isolated: 0xFE81, final: 0xFE82}, // alef with madda above
+	0x0623: {isolated: 0xFE83, final: 0xFE84}, // alef with hamza above
+	0x0624: {isolated: 0xFE85, final: 0xFE86}, // waw with hamza above
+	0x0625: {isolated: 0xFE87, final: 0xFE88}, // alef with hamza below
+	0x0626: {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},  // yeh with hamza above
+	0x0627: {isolated: 0xFE8D, final: 0xFE8E}, // alef
+	0x0628: {0xFE8F, 0xFE90, 0xFE91, 0xFE92},  // beh
+	0x0629: {isolated: 0xFE93, final: 0xFE94}, // teh marbuta
+	0x062A: {0xFE95, 0xFE96, 0xFE97, 0xFE98},  // teh
+	0x062B: {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},  // theh
+	0x062C: {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},  // jeem
+	0x062D: {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},  // hah
+	0x062E: {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},  // khah
+	0x062F: {isolated: 0xFEA9, final: 0xFEAA}, // dal
+	0x0630: {isolated: 0xFEAB, final: 0xFEAC}, // thal
+	0x0631: {isolated: 0xFEAD, final: 0xFEAE}, // reh
+	0x0632: {isolated: 0xFEAF, final: 0xFEB0}, // zain
+	0x0633: {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},  // seen
+	0x0634: {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},  // sheen
+	0x0635: {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},  // sad
+	0x0636: {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},  // dad
+	0x0637: {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},  // tah
+	0x0638: {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},  // zah
+	0x0639: {0xFEC9, 0xFECA, 0xFECB, 0xFECC},  // ain
+	0x063A: {0xFECD, 0xFECE, 0xFECF, 0xFED0},  // ghain
+	0x0641: {0xFED1, 0xFED2, 0xFED3, 0xFED4},  // feh
+	0x0642: {0xFED5, 0xFED6, 0xFED7, 0xFED8},  // qaf
+	0x0643: {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},  // kaf
+	0x0644: {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},  // lam
+	0x0645: {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},  // meem
+	0x0646: {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},  // noon
+	0x0647: {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},  // heh
+	0x0648: {isolated: 0xFEED, final: 0xFEEE}, // waw
+	0x0649: {isolated: 0xFEEF, final: 0xFEF0}, // alef maksura
+	0x064A: {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},  // yeh
+}
+
+// Lam followed by alef is drawn as a single ligature, isolated or final.
+var lamAlef = map[rune][2]rune{
+	0x0622: {0xFEF5, 0xFEF6}, // alef with madda above
+	0x0623: {0xFEF7, 0xFEF8}, // alef with hamza above
+	0x0625: {0xFEF9, 0xFEFA}, // alef with hamza below
+	0x0627: {0xFEFB, 0xFEFC}, // alef
+}
+
+const (
+	lam     = 0x0644
+	tatweel = 0x0640
+	zwj     = 0x200D
+)
+
+// arabicJoining is how a rune joins to its neighbours.
+type arabicJoining int
+
+const (
+	joinNone arabicJoining = iota
+	// Only joins to the letter before it.
+	joinRight
+	// Joins to the letters before and after it.
+	joinDual
+	// Combining marks are skipped, they're drawn over the letter they follow.
+	joinTransparent
+)
+
+func joining(r rune) arabicJoining {
+	switch forms, ok := arabicLetters[r]; {
+	case ok && forms.medial != 0:
+		return joinDual
+	case ok && forms.final != 0:
+		return joinRight
+	// Joined to on both sides, but never drawn differently.
+	case r == tatweel || r == zwj:
+		return joinDual
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return joinTransparent
+	default:
+		return joinNone
+	}
+}
+
+// arabicJoins reports whether r, in the Arabic script, is a letter that still needs joining after shapeArabic:
+// one it has no presentation forms for. Presentation forms, marks, digits and punctuation are drawn as is.
+func arabicJoins(r rune) bool {
+	return unicode.IsLetter(r) && r < 0xFB50 && r != tatweel
+}
+
+// shapeArabic replaces the Arabic letters of text with the presentation form for their position,
+// and lam followed by alef with their ligature.
+// Text is in logical order, reordering it for display doesn't change the shapes.
+func shapeArabic(text string) string {
+	runes := []rune(text)
+
+	// Most labels don't have any Arabic.
+	arabic := false
+	for _, r := range runes {
+		_, ok := arabicLetters[r]
+		arabic = arabic || ok
+	}
+	if !arabic {
+		return text
+	}
+
+	// neighbour returns the joining of the closest rune that isn't a combining mark from i, in direction dir.
+	neighbour := func(i, dir int) arabicJoining {
+		for i += dir; i >= 0 && i < len(runes); i += dir {
+			if j := joining(runes[i]); j != joinTransparent {
+				return j
+			}
+		}
+		return joinNone
+	}
+
+	shaped := make([]rune, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		forms, ok := arabicLetters[r]
+		if !ok {
+			shaped = append(shaped, r)
+			continue
+		}
+
+		// Letters join the letter before them if it joins on both sides,
+		// and the letter after them if they join on both sides themselves.
+		prev := joining(r) != joinNone && neighbour(i, -1) == joinDual
+		next := joining(r) == joinDual && neighbour(i, 1) != joinNone
+
+		if r == lam && i+1 < len(runes) {
+			if ligature, ok := lamAlef[runes[i+1]]; ok {
+				if prev {
+					shaped = append(shaped, ligature[1])
+				} else {
+					shaped = append(shaped, ligature[0])
+				}
+				i++
+				continue
+			}
+		}
+
+		switch {
+		case prev && next:
+			shaped = append(shaped, forms.medial)
+		case prev:
+			shaped = append(shaped, forms.final)
+		case next:
+			shaped = append(shaped, forms.initial)
+		default:
+			shaped = append(shaped, forms.isolated)
+		}
+	}
+
+	return string(shaped)
+}
@@ -0,0 +1,46 @@
+package etiquette
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShapeArabic(t *testing.T) {
+	for _, test := range []struct {
+		name, text, shaped string
+	}{
+		{"latin", "Hello", "Hello"},
+		{"dual joining", "بيت", "ﺑﻴﺖ"},
+		{"right joining", "دار", "ﺩﺍﺭ"},
+		{"words", "من هو", "ﻣﻦ ﻫﻮ"},
+		{"lam alef", "لا", "ﻻ"},
+		{"lam alef final", "سلام", "ﺳﻼﻡ"},
+		{"marks", "بَيت", "ﺑَﻴﺖ"},
+		{"tatweel", "بـ", "ﺑـ"},
+		{"hamza", "ءب", "ﺀﺏ"},
+		{"mixed", "Room غرفة 3", "Room ﻏﺮﻓﺔ 3"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := shapeArabic(test.text); got != test.shaped {
+				t.Errorf("shapeArabic(%q) = %+q, want %+q", test.text, got, test.shaped)
+			}
+		})
+	}
+}
+
+func TestCheckArabic(t *testing.T) {
+	r := testRenderer(t)
+
+	// The Go fonts don't have Arabic, but shaped text only needs the presentation forms.
+	_, err := r.check("سلام", TextOpts{})
+	var missing MissingGlyphsError
+	if !errors.As(err, &missing) || string(missing.Runes) != "ﺳﻼﻡ" {
+		t.Errorf("got error %v, want the presentation forms missing", err)
+	}
+
+	// Persian peh doesn't have a presentation form in Forms-B.
+	var shaping ShapingError
+	if _, err := r.check("پ", TextOpts{}); !errors.As(err, &shaping) {
+		t.Errorf("got error %v, want a ShapingError", err)
+	}
+}
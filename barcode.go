@@ -0,0 +1,71 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+type BarcodeOpts struct {
+	// Module is the width of the narrowest bar of linear barcodes, in mm.
+	// If 0, defaults to 2 pixels.
+	// Matrix barcodes always use the biggest modules that fit the tape.
+	Module float64
+	// Length is the minimum length of the label, in mm.
+	// Labels are never shorter than Bounds.MinDy.
+	Length float64
+	// Align is where the barcode goes if the label is longer than it.
+	Align Align
+
+	// Frame is drawn around the barcode.
+	Frame Frame
+}
+
+// Default width of the narrowest bar of linear barcodes, in pixels.
+const defaultModule = 2
+
+// Barcode converts a barcode with one pixel per module, from the barcode package, to an image suitable for printing.
+// The bars of linear barcodes (one pixel high) span the width of the tape, and matrix barcodes are scaled to fit it.
+func Barcode(b Bounds, code *monochrome.Image, opts BarcodeOpts) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	height := b.Dx - 2*opts.Frame.size(b)
+	cb := code.Bounds()
+
+	// Size of each module across and along the tape.
+	var across, along int
+	if cb.Dy() == 1 {
+		across = height
+		along = defaultModule
+		if opts.Module != 0 {
+			along = max(1, b.MMToPx(opts.Module))
+		}
+	} else {
+		across = height / cb.Dy()
+		if across == 0 {
+			return nil, fmt.Errorf("barcode is %d modules high, more than the %dpx tape", cb.Dy(), height)
+		}
+		along = across
+	}
+
+	// Draw it landscape, with the start of the barcode at the start of the label.
+	rotated := code.Rotate270()
+	rb := rotated.Bounds()
+	dst := monochrome.New(image.Rect(0, 0, rb.Dx()*across, rb.Dy()*along))
+	for i := 0; i < rb.Dy(); i++ {
+		for j := 0; j < rb.Dx(); j++ {
+			if rotated.BlackAt(rb.Min.X+j, rb.Min.Y+i) {
+				dst.FillRect(image.Rect(j*across, i*along, (j+1)*across, (i+1)*along), true)
+			}
+		}
+	}
+
+	return Image(b, dst, ImageOpts{
+		Length: opts.Length,
+		Align:  opts.Align,
+		Frame:  opts.Frame,
+	})
+}
@@ -0,0 +1,29 @@
+// Package barcode encodes barcodes as images with one pixel per module
+// (the narrowest bar or space of linear barcodes, or square of matrix barcodes).
+//
+// Linear barcodes are one pixel high. Images include the quiet zone the symbology requires.
+package barcode
+
+import (
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// linear draws a linear barcode from the widths of its alternating bars and spaces, starting with a bar.
+func linear(quietZone int, widths []int) *monochrome.Image {
+	dx := 2 * quietZone
+	for _, w := range widths {
+		dx += w
+	}
+
+	img := monochrome.New(image.Rect(0, 0, dx, 1))
+
+	x := quietZone
+	for i, w := range widths {
+		img.FillRect(image.Rect(x, 0, x+w, 1), i%2 == 0)
+		x += w
+	}
+
+	return img
+}
@@ -0,0 +1,210 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+
+	"go.afab.re/etiquette/internal/imagetest"
+)
+
+func TestReedSolomon(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		field *gf256
+		first int
+		data  []byte
+		ecc   []byte
+	}{
+		{
+			// HELLO WORLD in alphanumeric mode, version 1-M.
+			name:  "qr",
+			field: qrField,
+			first: 0,
+			data:  []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17},
+			ecc:   []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23},
+		},
+		{
+			// 123456 in a 10x10 symbol, from ISO/IEC 16022.
+			name:  "data matrix",
+			field: dataMatrixField,
+			first: 1,
+			data:  []byte{142, 164, 186},
+			ecc:   []byte{114, 25, 5, 88, 102},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.field.ecc(test.data, len(test.ecc), test.first); !bytes.Equal(got, test.ecc) {
+				t.Errorf("got %v, want %v", got, test.ecc)
+			}
+		})
+	}
+}
+
+func TestQRCodewords(t *testing.T) {
+	// Byte mode, a count of 1, 'A', the terminator, and alternating pad bytes.
+	want := []byte{0x40, 0x14, 0x10, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC}
+	want = append(want, qrField.ecc(want, 10, 0)...)
+
+	if got := newQR(1).codewords([]byte("A"), QRLevelM); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
+
+func TestQRInterleave(t *testing.T) {
+	// Version 5-Q has two blocks of 15 data codewords and two of 16, each with 18 error correction codewords.
+	data := bytes.Repeat([]byte("x"), 60)
+	got := newQR(5).codewords(data, QRLevelQ)
+
+	if len(got) != 134 {
+		t.Fatalf("got %d codewords, want 134", len(got))
+	}
+
+	blocks := make([][]byte, 4)
+	i := 0
+	for col := 0; col < 16; col++ {
+		for b := range blocks {
+			// The short blocks don't have a 16th data codeword.
+			if col == 15 && b < 2 {
+				continue
+			}
+			blocks[b] = append(blocks[b], got[i])
+			i++
+		}
+	}
+
+	eccs := make([][]byte, 4)
+	for col := 0; col < 18; col++ {
+		for b := range eccs {
+			eccs[b] = append(eccs[b], got[i])
+			i++
+		}
+	}
+
+	// Byte mode, a count of 60, and the first 'x'.
+	if want := []byte{0x43, 0xC7, 0x87}; !bytes.HasPrefix(blocks[0], want) {
+		t.Errorf("got data %x, want it to start with %x", blocks[0], want)
+	}
+	for b := range blocks {
+		if want := qrField.ecc(blocks[b], 18, 0); !bytes.Equal(eccs[b], want) {
+			t.Errorf("block %d: got error correction %x, want %x", b, eccs[b], want)
+		}
+	}
+}
+
+func TestQRFormat(t *testing.T) {
+	// From the table of format information in ISO/IEC 18004.
+	for _, test := range []struct {
+		level QRLevel
+		mask  int
+		bits  string
+	}{
+		{QRLevelL, 0, "111011111000100"},
+		{QRLevelL, 1, "111001011110011"},
+		{QRLevelM, 0, "101010000010010"},
+		{QRLevelM, 5, "100000011001110"},
+		{QRLevelQ, 0, "011010101011111"},
+		{QRLevelH, 0, "001011010001001"},
+	} {
+		t.Run(fmt.Sprintf("%v%d", test.level, test.mask), func(t *testing.T) {
+			q := newQR(1)
+			q.drawFormat(test.level, test.mask)
+
+			// Both copies, least significant bit first.
+			var first, second []byte
+			for i := 0; i < 15; i++ {
+				var x, y int
+				switch {
+				case i <= 5:
+					x, y = 8, i
+				case i <= 7:
+					x, y = 8, i+1
+				case i == 8:
+					x, y = 7, 8
+				default:
+					x, y = 14-i, 8
+				}
+				first = append(first, bit(q.modules[y][x]))
+
+				if i < 8 {
+					second = append(second, bit(q.modules[8][q.size-1-i]))
+				} else {
+					second = append(second, bit(q.modules[q.size-15+i][8]))
+				}
+			}
+			slices.Reverse(first)
+			slices.Reverse(second)
+
+			if string(first) != test.bits {
+				t.Errorf("got first copy %s, want %s", first, test.bits)
+			}
+			if string(second) != test.bits {
+				t.Errorf("got second copy %s, want %s", second, test.bits)
+			}
+		})
+	}
+}
+
+func bit(black bool) byte {
+	if black {
+		return '1'
+	}
+	return '0'
+}
+
+func TestDataMatrixASCII(t *testing.T) {
+	for _, test := range []struct {
+		data      []rune
+		codewords []byte
+	}{
+		{[]rune("123456"), []byte{142, 164, 186}},
+		{[]rune("A1"), []byte{66, 50}},
+		{[]rune("12A"), []byte{142, 66}},
+		{[]rune{0xE9}, []byte{235, 106}},
+		{[]rune{fnc1, '0', '1'}, []byte{232, 131}},
+	} {
+		if got := dataMatrixASCII(test.data); !bytes.Equal(got, test.codewords) {
+			t.Errorf("%q: got %v, want %v", string(test.data), got, test.codewords)
+		}
+	}
+}
+
+func TestCode128Values(t *testing.T) {
+	for _, test := range []struct {
+		data   []rune
+		values []int
+	}{
+		{[]rune("ABC"), []int{code128StartB, 33, 34, 35}},
+		{[]rune("123456"), []int{code128StartC, 12, 34, 56}},
+		// An odd run of digits starts in code set C, and switches for the last one.
+		{[]rune("12345"), []int{code128StartC, 12, 34, code128CodeB, 21}},
+		{[]rune("AB1234"), []int{code128StartB, 33, 34, code128CodeC, 12, 34}},
+		{[]rune("a\n"), []int{code128StartB, 65, code128CodeA, 74}},
+		{append([]rune{fnc1}, []rune("01123456")...), []int{code128StartC, code128FNC1, 1, 12, 34, 56}},
+	} {
+		if got := code128Values(test.data); !slices.Equal(got, test.values) {
+			t.Errorf("%q: got %v, want %v", string(test.data), got, test.values)
+		}
+	}
+}
+
+func TestGolden(t *testing.T) {
+	qr, err := QR([]byte("HELLO WORLD"), QRLevelM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagetest.CompareGolden(t, qr, "testdata/qr.png")
+
+	dm, err := DataMatrix([]byte("123456"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagetest.CompareGolden(t, dm, "testdata/datamatrix.png")
+
+	code, err := Code128("ABC-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagetest.CompareGolden(t, code, "testdata/code128.png")
+}
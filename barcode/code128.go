@@ -0,0 +1,177 @@
+package barcode
+
+import (
+	"fmt"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Widths of the bars and spaces of each Code 128 symbol, indexed by value.
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128CodeA  = 101
+	code128FNC1   = 102
+	code128StartA = 103
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+
+	// Quiet zone before and after the barcode, in modules.
+	code128QuietZone = 10
+)
+
+// fnc1 is the FNC1 function character in data to encode.
+const fnc1 = -1
+
+// Code128 encodes ASCII data as a Code 128 barcode.
+// The shortest encoding is picked automatically: runs of digits are encoded two at a time.
+func Code128(data string) (*monochrome.Image, error) {
+	var chars []rune
+	for _, c := range data {
+		if c > 127 {
+			return nil, fmt.Errorf("can't encode non ASCII character %q in Code 128", c)
+		}
+		chars = append(chars, c)
+	}
+
+	if len(chars) == 0 {
+		return nil, fmt.Errorf("no data to encode")
+	}
+
+	return code128(chars), nil
+}
+
+// code128 encodes ASCII characters or fnc1.
+func code128(data []rune) *monochrome.Image {
+	values := code128Values(data)
+
+	// Checksum weights are the position of each value, except the start character.
+	checksum := values[0]
+	for i, v := range values[1:] {
+		checksum += (i + 1) * v
+	}
+	values = append(values, checksum%103, code128Stop)
+
+	var widths []int
+	for _, v := range values {
+		for _, w := range code128Patterns[v] {
+			widths = append(widths, int(w-'0'))
+		}
+	}
+
+	return linear(code128QuietZone, widths)
+}
+
+// code128Values picks code sets for data, and returns the values to encode it starting with a start character.
+func code128Values(data []rune) []int {
+	// digits returns the number of digits at the start of data.
+	// FNC1 can be encoded in code set C, so it doesn't end the run.
+	digits := func(data []rune) int {
+		n := 0
+		for _, c := range data {
+			if c == fnc1 {
+				continue
+			}
+			if c < '0' || c > '9' {
+				break
+			}
+			n++
+		}
+		return n
+	}
+
+	var values []int
+	set := 0
+
+	for i := 0; i < len(data); {
+		// Switch to code set C for runs of 4 or more digits,
+		// if there's an odd number of them the first one is encoded in the current set.
+		if set != code128CodeC {
+			n := digits(data[i:])
+			if n >= 4 && (n%2 == 0 || set == 0) {
+				set = code128CodeC
+				values = append(values, code128Switch(set, len(values) == 0))
+			}
+		}
+
+		c := data[i]
+		switch {
+		case c == fnc1:
+			if set == 0 {
+				set = code128CodeB
+				values = append(values, code128Switch(set, true))
+			}
+			values = append(values, code128FNC1)
+			i++
+			continue
+
+		case set == code128CodeC:
+			if i+1 < len(data) && isDigit(c) && isDigit(data[i+1]) {
+				values = append(values, int(c-'0')*10+int(data[i+1]-'0'))
+				i += 2
+				continue
+			}
+			// Not a digit pair, switch back.
+			set = 0
+		}
+
+		// Code set A has control characters, B has lowercase letters.
+		want := set
+		switch {
+		case c < ' ' && set != code128CodeA:
+			want = code128CodeA
+		case c >= '`' && set != code128CodeB:
+			want = code128CodeB
+		case set == 0 || set == code128CodeC:
+			want = code128CodeB
+		}
+		if want != set {
+			values = append(values, code128Switch(want, len(values) == 0))
+			set = want
+		}
+
+		if c < ' ' {
+			values = append(values, int(c)+64)
+		} else {
+			values = append(values, int(c)-' ')
+		}
+		i++
+	}
+
+	return values
+}
+
+// code128Switch returns the value to switch to a code set, or to start with it.
+func code128Switch(set int, start bool) int {
+	if !start {
+		return set
+	}
+
+	switch set {
+	case code128CodeA:
+		return code128StartA
+	case code128CodeC:
+		return code128StartC
+	default:
+		return code128StartB
+	}
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,81 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Contact is contact information, encoded in a QR code.
+type Contact struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+func (c Contact) validate() error {
+	if c.Name == "" && c.Phone == "" && c.Email == "" {
+		return fmt.Errorf("missing contact name, phone or email")
+	}
+	return nil
+}
+
+// MECARD returns the contact in the compact MECARD format: MECARD:N:name;TEL:phone;EMAIL:email;;
+func (c Contact) MECARD() (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	var p strings.Builder
+	p.WriteString("MECARD:")
+	for _, field := range []struct{ name, value string }{
+		{"N", c.Name},
+		{"TEL", c.Phone},
+		{"EMAIL", c.Email},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&p, "%s:%s;", field.name, mecardEscaper.Replace(field.value))
+		}
+	}
+	p.WriteString(";")
+
+	return p.String(), nil
+}
+
+// Characters with special meaning in MECARD payloads are escaped with a backslash.
+var mecardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	`:`, `\:`,
+)
+
+// VCard returns the contact as a vCard 3.0, which more readers understand than MECARD, but is bigger.
+func (c Contact) VCard() (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+
+	lines := []string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		// The name is required, but can be empty.
+		"N:" + vCardEscaper.Replace(c.Name) + ";;;;",
+		"FN:" + vCardEscaper.Replace(c.Name),
+	}
+	if c.Phone != "" {
+		lines = append(lines, "TEL:"+vCardEscaper.Replace(c.Phone))
+	}
+	if c.Email != "" {
+		lines = append(lines, "EMAIL:"+vCardEscaper.Replace(c.Email))
+	}
+	lines = append(lines, "END:VCARD")
+
+	return strings.Join(lines, "\r\n"), nil
+}
+
+var vCardEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\n", `\n`,
+)
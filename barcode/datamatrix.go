@@ -0,0 +1,267 @@
+package barcode
+
+import (
+	"fmt"
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// dataMatrixSize is a square ECC 200 Data Matrix symbol size.
+type dataMatrixSize struct {
+	// Size of the symbol in modules, excluding the quiet zone.
+	size int
+	// Number of data regions along each side.
+	regions int
+	data    int
+	ecc     int
+}
+
+// Square symbols with a single Reed-Solomon block.
+var dataMatrixSizes = []dataMatrixSize{
+	{10, 1, 3, 5},
+	{12, 1, 5, 7},
+	{14, 1, 8, 10},
+	{16, 1, 12, 12},
+	{18, 1, 18, 14},
+	{20, 1, 22, 18},
+	{22, 1, 30, 20},
+	{24, 1, 36, 24},
+	{26, 1, 44, 28},
+	{32, 2, 62, 36},
+	{36, 2, 86, 42},
+	{40, 2, 114, 48},
+	{44, 2, 144, 56},
+	{48, 2, 174, 68},
+}
+
+const (
+	dataMatrixPad        = 129
+	dataMatrixFNC1       = 232
+	dataMatrixUpperShift = 235
+
+	// Quiet zone around the symbol, in modules.
+	dataMatrixQuietZone = 1
+)
+
+var dataMatrixField = newGF256(0x12d)
+
+// DataMatrix encodes data as an ECC 200 Data Matrix, using the smallest square symbol it fits in.
+func DataMatrix(data []byte) (*monochrome.Image, error) {
+	var chars []rune
+	for _, c := range data {
+		chars = append(chars, rune(c))
+	}
+	return dataMatrix(chars)
+}
+
+// dataMatrix encodes bytes or fnc1.
+func dataMatrix(data []rune) (*monochrome.Image, error) {
+	codewords := dataMatrixASCII(data)
+
+	var size dataMatrixSize
+	for _, s := range dataMatrixSizes {
+		if s.data >= len(codewords) {
+			size = s
+			break
+		}
+	}
+	if size.size == 0 {
+		return nil, fmt.Errorf("%d codewords is too much data for a Data Matrix, the maximum is %d", len(codewords), dataMatrixSizes[len(dataMatrixSizes)-1].data)
+	}
+
+	// Pad, scrambling all but the first pad so they don't make visible patterns.
+	for i := len(codewords); i < size.data; i++ {
+		if i == len(codewords) {
+			codewords = append(codewords, dataMatrixPad)
+			continue
+		}
+
+		pseudoRandom := 149*(i+1)%253 + 1
+		pad := dataMatrixPad + pseudoRandom
+		if pad > 254 {
+			pad -= 254
+		}
+		codewords = append(codewords, byte(pad))
+	}
+
+	codewords = append(codewords, dataMatrixField.ecc(codewords, size.ecc, 1)...)
+
+	return size.draw(codewords), nil
+}
+
+// dataMatrixASCII encodes data in ASCII mode.
+func dataMatrixASCII(data []rune) []byte {
+	var codewords []byte
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == fnc1:
+			codewords = append(codewords, dataMatrixFNC1)
+
+		// Pairs of digits are encoded together.
+		case isDigit(c) && i+1 < len(data) && isDigit(data[i+1]):
+			codewords = append(codewords, byte(130+(c-'0')*10+(data[i+1]-'0')))
+			i++
+
+		case c > 127:
+			codewords = append(codewords, dataMatrixUpperShift, byte(c-128+1))
+
+		default:
+			codewords = append(codewords, byte(c+1))
+		}
+	}
+	return codewords
+}
+
+// draw the symbol for codewords.
+func (s dataMatrixSize) draw(codewords []byte) *monochrome.Image {
+	// Size of each data region, and all the data regions together.
+	region := s.size/s.regions - 2
+	mapping := region * s.regions
+
+	img := monochrome.New(image.Rect(0, 0, s.size+2*dataMatrixQuietZone, s.size+2*dataMatrixQuietZone))
+	set := func(x, y int, black bool) {
+		img.SetBlack(dataMatrixQuietZone+x, dataMatrixQuietZone+y, black)
+	}
+
+	// Finder patterns around each data region: solid on the left and bottom,
+	// alternating on the top and right.
+	step := region + 2
+	for ry := 0; ry < s.regions; ry++ {
+		for rx := 0; rx < s.regions; rx++ {
+			x0, y0 := rx*step, ry*step
+			for i := 0; i < step; i++ {
+				set(x0, y0+i, true)
+				set(x0+i, y0+step-1, true)
+				set(x0+i, y0, i%2 == 0)
+				set(x0+step-1, y0+i, i%2 == 1)
+			}
+		}
+	}
+
+	for y, row := range dataMatrixPlacement(mapping, mapping) {
+		for x, bit := range row {
+			black := bit.fixed
+			if bit.codeword >= 0 {
+				black = codewords[bit.codeword]&(1<<(7-bit.bit)) != 0
+			}
+
+			// Skip over the finder patterns between regions.
+			set(x+1+2*(x/region), y+1+2*(y/region), black)
+		}
+	}
+
+	return img
+}
+
+// dataMatrixBit is the bit of a codeword a module of the mapping matrix is, or if it's a fixed module.
+type dataMatrixBit struct {
+	codeword int
+	// Most significant bit first.
+	bit int
+	// Set if codeword is -1.
+	fixed bool
+	set   bool
+}
+
+// dataMatrixPlacement places codewords in an nrow x ncol mapping matrix (all the data regions combined),
+// following the algorithm from ISO/IEC 16022 Annex F.
+func dataMatrixPlacement(nrow, ncol int) [][]dataMatrixBit {
+	bits := make([][]dataMatrixBit, nrow)
+	for i := range bits {
+		bits[i] = make([]dataMatrixBit, ncol)
+	}
+
+	module := func(row, col, codeword, bit int) {
+		if row < 0 {
+			row += nrow
+			col += 4 - ((nrow + 4) % 8)
+		}
+		if col < 0 {
+			col += ncol
+			row += 4 - ((ncol + 4) % 8)
+		}
+		bits[row][col] = dataMatrixBit{codeword: codeword, bit: bit, set: true}
+	}
+
+	// Each codeword is placed as an L shape, except in the corners.
+	utah := func(row, col, codeword int) {
+		module(row-2, col-2, codeword, 0)
+		module(row-2, col-1, codeword, 1)
+		module(row-1, col-2, codeword, 2)
+		module(row-1, col-1, codeword, 3)
+		module(row-1, col, codeword, 4)
+		module(row, col-2, codeword, 5)
+		module(row, col-1, codeword, 6)
+		module(row, col, codeword, 7)
+	}
+	corner := func(codeword int, positions [8][2]int) {
+		for bit, p := range positions {
+			module(p[0], p[1], codeword, bit)
+		}
+	}
+
+	codeword := 0
+	row, col := 4, 0
+	for {
+		switch {
+		case row == nrow && col == 0:
+			corner(codeword, [8][2]int{{nrow - 1, 0}, {nrow - 1, 1}, {nrow - 1, 2}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+			codeword++
+		case row == nrow-2 && col == 0 && ncol%4 != 0:
+			corner(codeword, [8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 4}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}})
+			codeword++
+		case row == nrow-2 && col == 0 && ncol%8 == 4:
+			corner(codeword, [8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+			codeword++
+		case row == nrow+4 && col == 2 && ncol%8 == 0:
+			corner(codeword, [8][2]int{{nrow - 1, 0}, {nrow - 1, ncol - 1}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 3}, {1, ncol - 2}, {1, ncol - 1}})
+			codeword++
+		}
+
+		// Sweep up and to the right.
+		for {
+			if row < nrow && col >= 0 && !bits[row][col].set {
+				utah(row, col, codeword)
+				codeword++
+			}
+			row -= 2
+			col += 2
+			if row < 0 || col >= ncol {
+				break
+			}
+		}
+		row++
+		col += 3
+
+		// Sweep down and to the left.
+		for {
+			if row >= 0 && col < ncol && !bits[row][col].set {
+				utah(row, col, codeword)
+				codeword++
+			}
+			row += 2
+			col -= 2
+			if row >= nrow || col < 0 {
+				break
+			}
+		}
+		row += 3
+		col++
+
+		if row >= nrow && col >= ncol {
+			break
+		}
+	}
+
+	// Fill in the bottom right corner if it's unused.
+	if !bits[nrow-1][ncol-1].set {
+		bits[nrow-1][ncol-1] = dataMatrixBit{codeword: -1, fixed: true, set: true}
+		bits[nrow-2][ncol-2] = dataMatrixBit{codeword: -1, fixed: true, set: true}
+		bits[nrow-1][ncol-2] = dataMatrixBit{codeword: -1, set: true}
+		bits[nrow-2][ncol-1] = dataMatrixBit{codeword: -1, set: true}
+	}
+
+	return bits
+}
@@ -0,0 +1,208 @@
+package barcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// AI is a GS1 Application Identifier, and its value.
+type AI struct {
+	// ID is the Application Identifier, for example "01" for a GTIN.
+	ID    string
+	Value string
+}
+
+func (a AI) String() string {
+	return "(" + a.ID + ")" + a.Value
+}
+
+// aiFormat is the format of the value of an Application Identifier.
+type aiFormat struct {
+	name string
+	// Minimum and maximum length of the value.
+	min, max int
+	numeric  bool
+	// The last digit is a check digit.
+	check bool
+	date  bool
+	// The AI has a fourth digit, the position of the decimal point in the value.
+	decimal bool
+}
+
+// Supported Application Identifiers. AIs ending with the position of the decimal point
+// are listed without it.
+var aiFormats = map[string]aiFormat{
+	"00":  {name: "SSCC", min: 18, max: 18, numeric: true, check: true},
+	"01":  {name: "GTIN", min: 14, max: 14, numeric: true, check: true},
+	"02":  {name: "CONTENT", min: 14, max: 14, numeric: true, check: true},
+	"10":  {name: "BATCH/LOT", min: 1, max: 20},
+	"11":  {name: "PROD DATE", min: 6, max: 6, numeric: true, date: true},
+	"13":  {name: "PACK DATE", min: 6, max: 6, numeric: true, date: true},
+	"15":  {name: "BEST BEFORE", min: 6, max: 6, numeric: true, date: true},
+	"17":  {name: "USE BY", min: 6, max: 6, numeric: true, date: true},
+	"21":  {name: "SERIAL", min: 1, max: 20},
+	"30":  {name: "VAR. COUNT", min: 1, max: 8, numeric: true},
+	"37":  {name: "COUNT", min: 1, max: 8, numeric: true},
+	"310": {name: "NET WEIGHT (kg)", min: 6, max: 6, numeric: true, decimal: true},
+	"320": {name: "NET WEIGHT (lb)", min: 6, max: 6, numeric: true, decimal: true},
+	"400": {name: "ORDER NUMBER", min: 1, max: 30},
+	"410": {name: "SHIP TO LOC", min: 13, max: 13, numeric: true, check: true},
+	"414": {name: "LOC No.", min: 13, max: 13, numeric: true, check: true},
+	"422": {name: "ORIGIN", min: 3, max: 3, numeric: true},
+}
+
+// AIs starting with these two digits have a predefined length, and don't need to be followed by FNC1.
+var aiPredefinedLength = map[string]bool{
+	"00": true, "01": true, "02": true, "03": true, "04": true,
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"20": true, "31": true, "32": true, "33": true, "34": true, "35": true, "36": true, "41": true,
+}
+
+// ParseAIs parses Application Identifiers in their human readable form: "(01)09501101530003(17)261231(10)AB12".
+func ParseAIs(s string) ([]AI, error) {
+	var ais []AI
+	for s != "" {
+		if s[0] != '(' {
+			return nil, fmt.Errorf("expected ( before Application Identifier, got %q", s)
+		}
+
+		id, rest, ok := strings.Cut(s[1:], ")")
+		if !ok {
+			return nil, fmt.Errorf("missing ) after Application Identifier %q", s[1:])
+		}
+
+		value := rest
+		if i := strings.IndexByte(rest, '('); i >= 0 {
+			value = rest[:i]
+		}
+
+		ais = append(ais, AI{ID: id, Value: value})
+		s = rest[len(value):]
+	}
+
+	if len(ais) == 0 {
+		return nil, fmt.Errorf("no Application Identifiers")
+	}
+	return ais, nil
+}
+
+// validate checks the AI is supported and its value is valid, and returns it normalized:
+// GTINs and GLNs shorter than 14 and 13 digits are padded with zeros.
+func (a AI) validate() (AI, error) {
+	id := a.ID
+	if len(id) == 4 && isDigit(rune(id[3])) {
+		id = id[:3]
+	}
+
+	format, ok := aiFormats[id]
+	if !ok || format.decimal != (id != a.ID) {
+		return AI{}, fmt.Errorf("unsupported Application Identifier %q", a.ID)
+	}
+
+	// GTIN-8, GTIN-12 and GTIN-13 are written as GTIN-14.
+	if (id == "01" || id == "02") && len(a.Value) < format.max && len(a.Value) >= 8 {
+		a.Value = strings.Repeat("0", format.max-len(a.Value)) + a.Value
+	}
+
+	if len(a.Value) < format.min || len(a.Value) > format.max {
+		if format.min == format.max {
+			return AI{}, fmt.Errorf("%s %s: expected %d characters, got %d", a, format.name, format.max, len(a.Value))
+		}
+		return AI{}, fmt.Errorf("%s %s: expected %d to %d characters, got %d", a, format.name, format.min, format.max, len(a.Value))
+	}
+
+	for _, c := range a.Value {
+		if format.numeric && !isDigit(c) {
+			return AI{}, fmt.Errorf("%s %s: %q isn't a digit", a, format.name, c)
+		}
+		if !isGS1Char(c) {
+			return AI{}, fmt.Errorf("%s %s: %q isn't allowed", a, format.name, c)
+		}
+	}
+
+	if format.check {
+		want := checkDigit(a.Value[:len(a.Value)-1])
+		if got := a.Value[len(a.Value)-1]; got != want {
+			return AI{}, fmt.Errorf("%s %s: invalid check digit %c, expected %c", a, format.name, got, want)
+		}
+	}
+
+	if format.date {
+		month, _ := strconv.Atoi(a.Value[2:4])
+		day, _ := strconv.Atoi(a.Value[4:6])
+		// Day 00 means the end of the month.
+		if month < 1 || month > 12 || day > 31 {
+			return AI{}, fmt.Errorf("%s %s: invalid YYMMDD date", a, format.name)
+		}
+	}
+
+	return a, nil
+}
+
+// checkDigit computes the GS1 mod 10 check digit of digits.
+func checkDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := int(digits[len(digits)-1-i] - '0')
+		// Weights alternate 3, 1, starting from the right.
+		if i%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// isGS1Char reports if c is part of GS1 AI encodable character set 82.
+func isGS1Char(c rune) bool {
+	switch {
+	case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', isDigit(c):
+		return true
+	default:
+		return strings.ContainsRune("!\"%&'()*+,-./:;<=>?_", c)
+	}
+}
+
+// gs1Data validates ais, and concatenates them starting with fnc1,
+// and with fnc1 separating values that don't have a predefined length.
+func gs1Data(ais []AI) ([]rune, error) {
+	if len(ais) == 0 {
+		return nil, fmt.Errorf("no Application Identifiers")
+	}
+
+	data := []rune{fnc1}
+	for i, ai := range ais {
+		ai, err := ai.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, []rune(ai.ID+ai.Value)...)
+
+		if i != len(ais)-1 && !aiPredefinedLength[ai.ID[:2]] {
+			data = append(data, fnc1)
+		}
+	}
+	return data, nil
+}
+
+// GS1128 encodes Application Identifiers as a GS1-128 barcode, after validating them.
+func GS1128(ais ...AI) (*monochrome.Image, error) {
+	data, err := gs1Data(ais)
+	if err != nil {
+		return nil, err
+	}
+	return code128(data), nil
+}
+
+// GS1DataMatrix encodes Application Identifiers as a GS1 Data Matrix, after validating them.
+func GS1DataMatrix(ais ...AI) (*monochrome.Image, error) {
+	data, err := gs1Data(ais)
+	if err != nil {
+		return nil, err
+	}
+	return dataMatrix(data)
+}
@@ -0,0 +1,518 @@
+package barcode
+
+import (
+	"fmt"
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// QRLevel is the error correction level of a QR code.
+// Higher levels can be read when more of the code is damaged, but hold less data.
+type QRLevel int
+
+const (
+	// QRLevelL recovers from 7% of the code being damaged.
+	QRLevelL QRLevel = iota
+	// QRLevelM recovers from 15% of the code being damaged.
+	QRLevelM
+	// QRLevelQ recovers from 25% of the code being damaged.
+	QRLevelQ
+	// QRLevelH recovers from 30% of the code being damaged.
+	QRLevelH
+)
+
+func (l QRLevel) String() string {
+	switch l {
+	case QRLevelL:
+		return "L"
+	case QRLevelM:
+		return "M"
+	case QRLevelQ:
+		return "Q"
+	case QRLevelH:
+		return "H"
+	default:
+		return fmt.Sprintf("QRLevel(%d)", int(l))
+	}
+}
+
+// formatBits are the bits used for the level in the format information.
+func (l QRLevel) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Error correction codewords per block, indexed by level and version.
+var qrECCPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// Error correction blocks, indexed by level and version.
+var qrBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+const (
+	qrMaxVersion = 40
+	// Quiet zone around the code, in modules.
+	qrQuietZone = 4
+)
+
+var qrField = newGF256(0x11d)
+
+// QR encodes data as a QR code in byte mode, using the smallest version it fits in.
+func QR(data []byte, level QRLevel) (*monochrome.Image, error) {
+	if level < QRLevelL || level > QRLevelH {
+		return nil, fmt.Errorf("invalid QR code level %v", level)
+	}
+
+	version := 1
+	for ; ; version++ {
+		if version > qrMaxVersion {
+			return nil, fmt.Errorf("%d bytes is too much data for a QR code with level %v", len(data), level)
+		}
+		if qrDataBits(len(data), version) <= qrDataCodewords(version, level)*8 {
+			break
+		}
+	}
+
+	q := newQR(version)
+	q.drawFunctionPatterns()
+	q.drawCodewords(q.codewords(data, level))
+
+	// Pick the mask that makes the code easiest to scan.
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(level, mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		// Masking twice undoes it.
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(level, best)
+
+	img := monochrome.New(image.Rect(0, 0, q.size+2*qrQuietZone, q.size+2*qrQuietZone))
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			img.SetBlack(qrQuietZone+x, qrQuietZone+y, q.modules[y][x])
+		}
+	}
+	return img, nil
+}
+
+// qrDataBits is the number of bits needed to encode n bytes in byte mode.
+func qrDataBits(n, version int) int {
+	// Mode, character count, and data.
+	return 4 + qrCountBits(version) + 8*n
+}
+
+// qrCountBits is the size of the character count in byte mode.
+func qrCountBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// qrRawModules is the number of modules available for data and error correction,
+// once all the function patterns are drawn.
+func qrRawModules(version int) int {
+	n := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		n -= (25*align-10)*align - 55
+		if version >= 7 {
+			// Version information.
+			n -= 36
+		}
+	}
+	return n
+}
+
+func qrDataCodewords(version int, level QRLevel) int {
+	return qrRawModules(version)/8 - qrECCPerBlock[level][version]*qrBlocks[level][version]
+}
+
+type qr struct {
+	version int
+	size    int
+	modules [][]bool
+	// Modules that are part of function patterns, and don't hold data.
+	function [][]bool
+}
+
+func newQR(version int) *qr {
+	size := version*4 + 17
+
+	q := qr{
+		version:  version,
+		size:     size,
+		modules:  make([][]bool, size),
+		function: make([][]bool, size),
+	}
+	for i := 0; i < size; i++ {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+	return &q
+}
+
+func (q *qr) set(x, y int, black bool) {
+	q.modules[y][x] = black
+	q.function[y][x] = true
+}
+
+// codewords encodes data, adds error correction, and interleaves the blocks.
+func (q *qr) codewords(data []byte, level QRLevel) []byte {
+	capacity := qrDataCodewords(q.version, level)
+
+	var bits qrBits
+	bits.append(0b0100, 4)
+	bits.append(len(data), qrCountBits(q.version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	// Terminator, and pad to a whole byte.
+	bits.append(0, min(4, capacity*8-bits.n))
+	bits.append(0, (8-bits.n%8)%8)
+
+	// Alternating pad bytes.
+	for pad := 0xec; len(bits.bytes) < capacity; pad ^= 0xec ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	numBlocks := qrBlocks[level][q.version]
+	eccLen := qrECCPerBlock[level][q.version]
+	raw := qrRawModules(q.version) / 8
+	// Some blocks have one more data codeword than the others.
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	var blocks, eccs [][]byte
+	for i, k := 0, 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+
+		block := bits.bytes[k : k+n]
+		k += n
+
+		blocks = append(blocks, block)
+		eccs = append(eccs, qrField.ecc(block, eccLen, 0))
+	}
+
+	var result []byte
+	for i := 0; i <= shortLen-eccLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < eccLen; i++ {
+		for _, ecc := range eccs {
+			result = append(result, ecc[i])
+		}
+	}
+	return result
+}
+
+func (q *qr) drawFunctionPatterns() {
+	// Timing patterns.
+	for i := 0; i < q.size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	positions := q.alignmentPositions()
+	for i, x := range positions {
+		for j, y := range positions {
+			// Skip the ones overlapping finders.
+			last := len(positions) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignment(x, y)
+		}
+	}
+
+	// Reserve the format information, it's drawn once the mask is picked.
+	q.drawFormat(0, 0)
+
+	if q.version >= 7 {
+		rem := q.version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1f25)
+		}
+		bits := q.version<<12 | rem
+
+		for i := 0; i < 18; i++ {
+			black := (bits>>i)&1 != 0
+			a, b := q.size-11+i%3, i/3
+			q.set(a, b, black)
+			q.set(b, a, black)
+		}
+	}
+}
+
+// drawFinder draws a finder pattern and its separator, centered on x, y.
+func (q *qr) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= q.size || yy < 0 || yy >= q.size {
+				continue
+			}
+
+			// Chebyshev distance from the center.
+			dist := max(abs(dx), abs(dy))
+			q.set(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centered on x, y.
+func (q *qr) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.set(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the coordinates of the centers of alignment patterns, along each axis.
+func (q *qr) alignmentPositions() []int {
+	if q.version == 1 {
+		return nil
+	}
+
+	num := q.version/7 + 2
+	step := (q.version*8 + num*3 + 5) / (num*4 - 4) * 2
+
+	positions := make([]int, num)
+	positions[0] = 6
+	for i, pos := num-1, q.size-7; i > 0; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+// drawFormat draws the format information, both copies of it.
+func (q *qr) drawFormat(level QRLevel, mask int) {
+	data := level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	bit := func(i int) bool {
+		return (bits>>i)&1 != 0
+	}
+
+	// Around the top left finder.
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	// Split between the other two finders.
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+
+	// Always black.
+	q.set(8, q.size-8, true)
+}
+
+// drawCodewords places codewords in a zig zag, two columns at a time, from the bottom right.
+func (q *qr) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		// Skip the vertical timing pattern.
+		if right == 6 {
+			right = 5
+		}
+
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				upward := (right+1)&2 == 0
+
+				y := vert
+				if upward {
+					y = q.size - 1 - vert
+				}
+
+				if q.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+
+				q.modules[y][x] = codewords[i/8]&(1<<(7-i%8)) != 0
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules selected by mask.
+func (q *qr) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.function[y][x] {
+				continue
+			}
+
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+
+			q.modules[y][x] = q.modules[y][x] != flip
+		}
+	}
+}
+
+// penalty scores how hard the code is to scan, lower is better.
+func (q *qr) penalty() int {
+	var penalty, dark int
+
+	// line returns the modules of row i, or column i.
+	line := func(i int, column bool) []bool {
+		l := make([]bool, q.size)
+		for j := range l {
+			if column {
+				l[j] = q.modules[j][i]
+			} else {
+				l[j] = q.modules[i][j]
+			}
+		}
+		return l
+	}
+
+	for i := 0; i < q.size; i++ {
+		for _, column := range []bool{false, true} {
+			l := line(i, column)
+
+			// Runs of five or more modules of the same color.
+			run := 1
+			for j := 1; j <= len(l); j++ {
+				if j < len(l) && l[j] == l[j-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += 3 + run - 5
+				}
+				run = 1
+			}
+
+			// Patterns that look like finders: 1:1:3:1:1 with four light modules on either side.
+			for j := 0; j+7 <= len(l); j++ {
+				if !(l[j] && !l[j+1] && l[j+2] && l[j+3] && l[j+4] && !l[j+5] && l[j+6]) {
+					continue
+				}
+				if light(l, j-4, j) || light(l, j+7, j+11) {
+					penalty += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+
+			// 2x2 blocks of the same color.
+			if x+1 < q.size && y+1 < q.size {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+
+	// Proportion of dark modules away from 50%, in steps of 5%.
+	total := q.size * q.size
+	penalty += 10 * ((abs(dark*20-total*10)+total-1)/total - 1)
+
+	return penalty
+}
+
+// light reports if modules from start to end are light, or outside the code (in the quiet zone).
+func light(l []bool, start, end int) bool {
+	for i := start; i < end; i++ {
+		if i >= 0 && i < len(l) && l[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// qrBits accumulates bits, most significant first.
+type qrBits struct {
+	bytes []byte
+	// Number of bits.
+	n int
+}
+
+// append the n least significant bits of v.
+func (b *qrBits) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if b.n%8 == 0 {
+			b.bytes = append(b.bytes, 0)
+		}
+		if (v>>i)&1 != 0 {
+			b.bytes[b.n/8] |= 1 << (7 - b.n%8)
+		}
+		b.n++
+	}
+}
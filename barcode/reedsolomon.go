@@ -0,0 +1,68 @@
+package barcode
+
+// gf256 is a Galois field with 256 elements, defined by a primitive polynomial.
+type gf256 struct {
+	exp [510]byte
+	log [256]byte
+}
+
+func newGF256(poly int) *gf256 {
+	var f gf256
+
+	x := 1
+	for i := 0; i < 255; i++ {
+		f.exp[i] = byte(x)
+		f.exp[i+255] = byte(x)
+		f.log[x] = byte(i)
+
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= poly
+		}
+	}
+
+	return &f
+}
+
+func (f *gf256) mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return f.exp[int(f.log[a])+int(f.log[b])]
+}
+
+// generator returns the coefficients of the Reed-Solomon generator polynomial
+// (x - α^first)(x - α^(first+1))...(x - α^(first+n-1)), highest degree first excluding the leading 1.
+func (f *gf256) generator(n, first int) []byte {
+	gen := []byte{1}
+	for i := 0; i < n; i++ {
+		root := f.exp[(first+i)%255]
+
+		// Multiply by (x - root), subtraction is addition.
+		next := make([]byte, len(gen)+1)
+		for j, c := range gen {
+			next[j] ^= c
+			next[j+1] ^= f.mul(c, root)
+		}
+		gen = next
+	}
+	return gen[1:]
+}
+
+// ecc returns n Reed-Solomon error correction codewords for data.
+func (f *gf256) ecc(data []byte, n, first int) []byte {
+	gen := f.generator(n, first)
+
+	// Remainder of data * x^n divided by the generator.
+	rem := make([]byte, n)
+	for _, d := range data {
+		factor := d ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+
+		for i, g := range gen {
+			rem[i] ^= f.mul(g, factor)
+		}
+	}
+	return rem
+}
@@ -0,0 +1,66 @@
+package barcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WiFi is a WiFi network to join, encoded in a QR code.
+type WiFi struct {
+	SSID     string
+	Password string
+	// Security is WPA (which includes WPA2 and WPA3), WEP or nopass.
+	// If empty, defaults to WPA, or nopass if there's no password.
+	Security string
+	// Hidden is set if the network doesn't broadcast its SSID.
+	Hidden bool
+}
+
+// Payload returns the text to encode in a QR code: WIFI:T:WPA;S:ssid;P:pass;;
+func (w WiFi) Payload() (string, error) {
+	if w.SSID == "" {
+		return "", fmt.Errorf("missing WiFi SSID")
+	}
+
+	security := w.Security
+	if security == "" {
+		security = "WPA"
+		if w.Password == "" {
+			security = "nopass"
+		}
+	}
+
+	switch security {
+	case "WPA", "WEP":
+		if w.Password == "" {
+			return "", fmt.Errorf("missing password for %s WiFi network", security)
+		}
+	case "nopass":
+		if w.Password != "" {
+			return "", fmt.Errorf("password set for WiFi network without one")
+		}
+	default:
+		return "", fmt.Errorf("unknown WiFi security %q, expected WPA, WEP or nopass", w.Security)
+	}
+
+	var p strings.Builder
+	fmt.Fprintf(&p, "WIFI:T:%s;S:%s;", security, wifiEscaper.Replace(w.SSID))
+	if w.Password != "" {
+		fmt.Fprintf(&p, "P:%s;", wifiEscaper.Replace(w.Password))
+	}
+	if w.Hidden {
+		p.WriteString("H:true;")
+	}
+	p.WriteString(";")
+
+	return p.String(), nil
+}
+
+// Characters with special meaning in WIFI: payloads are escaped with a backslash.
+var wifiEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	`:`, `\:`,
+	`"`, `\"`,
+)
@@ -0,0 +1,259 @@
+package etiquette
+
+import (
+	"slices"
+	"strings"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// visual reorders each line of text from logical order, the order it's written in, to the order its glyphs are
+// drawn left to right, so right to left text like Hebrew prints correctly.
+//
+// It follows the implicit rules of the Unicode Bidirectional Algorithm (UAX #9):
+// the direction of each line is that of its first letter, numbers stay left to right, and brackets are mirrored.
+// Explicit embeddings, overrides and isolates are ignored.
+func visual(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = visualLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func visualLine(line string) string {
+	runes := []rune(line)
+
+	classes := make([]bidi.Class, len(runes))
+	rtl := false
+	for i, r := range runes {
+		p, _ := bidi.LookupRune(r)
+		classes[i] = p.Class()
+		rtl = rtl || classes[i] == bidi.R || classes[i] == bidi.AL || classes[i] == bidi.AN
+	}
+	// Most labels don't have any right to left text.
+	if !rtl {
+		return line
+	}
+
+	levels := resolveLevels(classes)
+
+	// Combining marks stay after the letter they're on, so they're drawn over it.
+	type cluster struct {
+		start, end int
+		level      int
+	}
+	var clusters []cluster
+	for i := range runes {
+		if classes[i] == bidi.NSM && len(clusters) != 0 {
+			clusters[len(clusters)-1].end = i + 1
+			continue
+		}
+		clusters = append(clusters, cluster{start: i, end: i + 1, level: levels[i]})
+	}
+
+	// L2: reverse every sequence at each level or higher, from the highest level to the lowest odd level.
+	highest, lowestOdd := 0, 2
+	for _, c := range clusters {
+		highest = max(highest, c.level)
+		if c.level%2 == 1 {
+			lowestOdd = min(lowestOdd, c.level)
+		}
+	}
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(clusters); {
+			if clusters[i].level < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(clusters) && clusters[j].level >= level {
+				j++
+			}
+			slices.Reverse(clusters[i:j])
+			i = j
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range clusters {
+		for _, r := range runes[c.start:c.end] {
+			// L4: mirror brackets drawn right to left.
+			if c.level%2 == 1 {
+				r = []rune(bidi.ReverseString(string(r)))[0]
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// resolveLevels returns the embedding level of each character of a line, from the bidi class of each character.
+// Even levels are left to right, odd levels right to left.
+func resolveLevels(classes []bidi.Class) []int {
+	types := slices.Clone(classes)
+
+	// P2, P3: the line is right to left if its first strong character is.
+	base := 0
+	for _, t := range types {
+		if t == bidi.L {
+			break
+		}
+		if t == bidi.R || t == bidi.AL {
+			base = 1
+			break
+		}
+	}
+	// Start and end of line type.
+	sos := bidi.L
+	if base == 1 {
+		sos = bidi.R
+	}
+
+	// X9 removes explicit formatting characters and boundary neutrals, they take the type of the character before them.
+	// W1: so do combining marks.
+	prev := sos
+	for i, t := range types {
+		if t == bidi.NSM || t == bidi.BN || t > bidi.Control {
+			types[i] = prev
+		}
+		prev = types[i]
+	}
+
+	// W2: numbers after Arabic letters are Arabic numbers.
+	strong := sos
+	for i, t := range types {
+		switch t {
+		case bidi.L, bidi.R, bidi.AL:
+			strong = t
+		case bidi.EN:
+			if strong == bidi.AL {
+				types[i] = bidi.AN
+			}
+		}
+	}
+
+	// W3: Arabic letters are right to left.
+	for i, t := range types {
+		if t == bidi.AL {
+			types[i] = bidi.R
+		}
+	}
+
+	// W4: a single separator between two numbers of the same type is part of them.
+	for i := 1; i < len(types)-1; i++ {
+		before, after := types[i-1], types[i+1]
+		switch {
+		case types[i] == bidi.ES && before == bidi.EN && after == bidi.EN:
+			types[i] = bidi.EN
+		case types[i] == bidi.CS && before == after && (before == bidi.EN || before == bidi.AN):
+			types[i] = before
+		}
+	}
+
+	// W5: terminators, like currency symbols, next to European numbers are part of them.
+	for i := 0; i < len(types); {
+		if types[i] != bidi.ET {
+			i++
+			continue
+		}
+		j := i
+		for j < len(types) && types[j] == bidi.ET {
+			j++
+		}
+		if (i > 0 && types[i-1] == bidi.EN) || (j < len(types) && types[j] == bidi.EN) {
+			for k := i; k < j; k++ {
+				types[k] = bidi.EN
+			}
+		}
+		i = j
+	}
+
+	// W6: other separators and terminators are neutral.
+	for i, t := range types {
+		if t == bidi.ES || t == bidi.ET || t == bidi.CS {
+			types[i] = bidi.ON
+		}
+	}
+
+	// W7: European numbers in left to right text are left to right.
+	strong = sos
+	for i, t := range types {
+		switch t {
+		case bidi.L, bidi.R:
+			strong = t
+		case bidi.EN:
+			if strong == bidi.L {
+				types[i] = bidi.L
+			}
+		}
+	}
+
+	// N1, N2: neutrals between characters of the same direction take it, numbers counting as right to left.
+	// Other neutrals take the direction of the line.
+	neutral := func(t bidi.Class) bool {
+		return t == bidi.B || t == bidi.S || t == bidi.WS || t == bidi.ON
+	}
+	direction := func(t bidi.Class) bidi.Class {
+		if t == bidi.L {
+			return bidi.L
+		}
+		return bidi.R
+	}
+	for i := 0; i < len(types); {
+		if !neutral(types[i]) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(types) && neutral(types[j]) {
+			j++
+		}
+		before, after := sos, sos
+		if i > 0 {
+			before = direction(types[i-1])
+		}
+		if j < len(types) {
+			after = direction(types[j])
+		}
+		t := sos
+		if before == after {
+			t = before
+		}
+		for k := i; k < j; k++ {
+			types[k] = t
+		}
+		i = j
+	}
+
+	// I1, I2: characters of the other direction than the line, and numbers, go up a level.
+	levels := make([]int, len(types))
+	for i, t := range types {
+		switch {
+		case base == 0 && t == bidi.R:
+			levels[i] = 1
+		case base == 0 && (t == bidi.EN || t == bidi.AN):
+			levels[i] = 2
+		case base == 1 && (t == bidi.L || t == bidi.EN || t == bidi.AN):
+			levels[i] = 2
+		default:
+			levels[i] = base
+		}
+	}
+
+	// L1: tabs, and whitespace before them or at the end of the line, are at the level of the line.
+	trailing := true
+	for i := len(classes) - 1; i >= 0; i-- {
+		switch c := classes[i]; {
+		case c == bidi.S || c == bidi.B:
+			levels[i] = base
+			trailing = true
+		case trailing && (c == bidi.WS || c == bidi.BN || c > bidi.Control):
+			levels[i] = base
+		default:
+			trailing = false
+		}
+	}
+
+	return levels
+}
@@ -0,0 +1,30 @@
+package etiquette
+
+import "testing"
+
+func TestVisual(t *testing.T) {
+	for _, test := range []struct {
+		name, logical, visual string
+	}{
+		{"ltr", "Hello (world) 123", "Hello (world) 123"},
+		{"rtl", "שלום", "םולש"},
+		{"rtl with number", "חדר 123", "123 רדח"},
+		{"rtl in ltr", "Room חדר גדול, floor 2", "Room לודג רדח, floor 2"},
+		{"number after rtl in ltr", "Room חדר 12", "Room 12 רדח"},
+		{"number between rtl in ltr", "abc אבג 123 דהו", "abc והד 123 גבא"},
+		{"ltr in rtl", "שלום world ok", "world ok םולש"},
+		{"brackets", "(שלום)", "(םולש)"},
+		{"price", "מחיר: $12.50", "$12.50 :ריחמ"},
+		{"marks", "שָׁלוֹם", "םוֹלשָׁ"},
+		{"trailing space", "abc שלום ", "abc םולש "},
+		{"trailing space rtl", "שלום ", " םולש"},
+		{"lines", "שלום\nabc", "םולש\nabc"},
+		{"arabic", "ﺳﻼﻡ 12", "12 ﻡﻼﺳ"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := visual(test.logical); got != test.visual {
+				t.Errorf("visual(%q) = %q, want %q", test.logical, got, test.visual)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"golang.org/x/image/math/fixed"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// CableFlag renders text twice, with a blank gap between the copies to wrap around a cable of the given
+// diameter in mm. Folded around the cable, the label sticks to itself as a flag with the text readable
+// from both sides.
+// opts.Length is the minimum length of each side of the flag, and each copy is aligned mirroring the other.
+func (r *Renderer) CableFlag(b Bounds, text string, diameter float64, opts TextOpts) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	if diameter <= 0 {
+		return nil, fmt.Errorf("invalid cable diameter %vmm", diameter)
+	}
+
+	inner := Bounds{
+		Dx:  b.Dx - 2*opts.Frame.size(b),
+		DPI: b.DPI,
+	}
+
+	// Render each side without the frame, which goes around the whole flag.
+	sideOpts := opts
+	sideOpts.Frame = Frame{}
+	first, err := r.Text(inner, text, sideOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.Align {
+	case AlignStart:
+		sideOpts.Align = AlignEnd
+	case AlignEnd:
+		sideOpts.Align = AlignStart
+	}
+	second, err := r.Text(inner, text, sideOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	// The gap wraps around the cable.
+	gap := monochrome.New(image.Rect(0, 0, inner.Dx, b.MMToPx(math.Pi*diameter)))
+
+	return Image(b, join(first, gap, second), ImageOpts{
+		Frame: opts.Frame,
+	})
+}
+
+// CableWrap renders text across the tape, repeated along a label long enough to wrap around a cable
+// of the given diameter in mm, so at least one copy is readable from any side.
+// opts.Length, MaxLength and Condense are ignored.
+func (r *Renderer) CableWrap(b Bounds, text string, diameter float64, opts TextOpts) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	if diameter <= 0 {
+		return nil, fmt.Errorf("invalid cable diameter %vmm", diameter)
+	}
+
+	text, err := r.check(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		rows = append(rows, []string{line})
+	}
+
+	dx := b.Dx - 2*opts.Frame.size(b)
+	length := b.MMToPx(math.Pi * diameter)
+
+	margin := opts.Margin
+	if margin == 0 {
+		margin = defaultMargin
+	}
+	marginPx := b.MMToPx(margin)
+
+	spacing := fixed.Int26_6(math.Round(opts.LetterSpacing * float64(b.DPI) / mmPerInch * 64))
+
+	// Biggest font that fits a copy across the tape, and around the cable.
+	size := opts.Size
+	if size == 0 {
+		size, err = r.fit(px((length-marginPx)/len(rows)), b.DPI, 0, opts.Style)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var copy *image.Gray
+	for ; size > 0; size-- {
+		face, err := r.face(size, b.DPI, opts.Style, opts.Hinting)
+		if err != nil {
+			return nil, err
+		}
+
+		deco := r.decorations(opts.Decoration, size, b.DPI, opts.Style, face)
+		copy = drawRows(px(len(rows)*linePitch(face)), 0, face, rows, spacing, deco)
+		if copy.Bounds().Dx() <= dx || opts.Size != 0 {
+			break
+		}
+	}
+	if copy.Bounds().Dx() > dx {
+		return nil, fmt.Errorf("text is too long to fit across %dpx tape", dx)
+	}
+
+	// Spread as many copies as fit evenly around the cable, with a margin between them.
+	copies := max(1, length/(copy.Bounds().Dy()+marginPx))
+	dst := image.NewGray(image.Rect(0, 0, copy.Bounds().Dx(), max(length, copy.Bounds().Dy())))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	slot := dst.Bounds().Dy() / copies
+	for i := 0; i < copies; i++ {
+		y := i*slot + (slot-copy.Bounds().Dy())/2
+		draw.Draw(dst, copy.Bounds().Sub(copy.Bounds().Min).Add(image.Pt(0, y)), copy, copy.Bounds().Min, draw.Src)
+	}
+
+	return Image(b, dst, ImageOpts{
+		Frame: opts.Frame,
+	})
+}
@@ -0,0 +1,70 @@
+package etiquette
+
+import (
+	"image"
+	"image/draw"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Captioned renders a barcode with one pixel per module, from the barcode package,
+// with a text caption after it.
+// The caption is rendered like Text, and opts apply to the whole label.
+func (r *Renderer) Captioned(b Bounds, code *monochrome.Image, caption string, opts TextOpts) (*monochrome.Image, error) {
+	// Render each part to fit inside the frame, without any minimum length.
+	inner := Bounds{
+		Dx:  b.Dx - 2*opts.Frame.size(b),
+		DPI: b.DPI,
+	}
+
+	codeImg, err := Barcode(inner, code, BarcodeOpts{})
+	if err != nil {
+		return nil, err
+	}
+
+	captionImg, err := r.textGray(inner, caption, opts.partOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	return Image(b, joinGray(monochrome.Gray(codeImg), captionImg), opts.imageOpts())
+}
+
+// join images of the same width end to end, the first one at the start of the label.
+func join(imgs ...*monochrome.Image) *monochrome.Image {
+	var dy int
+	for _, img := range imgs {
+		dy += img.Bounds().Dy()
+	}
+
+	dst := monochrome.New(image.Rect(0, 0, imgs[0].Bounds().Dx(), dy))
+
+	// Labels start at Max.Y.
+	y := dy
+	for _, img := range imgs {
+		y -= img.Bounds().Dy()
+		monochrome.Draw(dst, img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(0, y)), img, img.Bounds().Min, draw.Src)
+	}
+
+	return dst
+}
+
+// joinGray joins grayscale images of the same width end to end like join,
+// so they can be converted to monochrome together.
+func joinGray(imgs ...*image.Gray) *image.Gray {
+	var dy int
+	for _, img := range imgs {
+		dy += img.Bounds().Dy()
+	}
+
+	dst := image.NewGray(image.Rect(0, 0, imgs[0].Bounds().Dx(), dy))
+
+	// Labels start at Max.Y.
+	y := dy
+	for _, img := range imgs {
+		y -= img.Bounds().Dy()
+		draw.Draw(dst, img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(0, y)), img, img.Bounds().Min, draw.Src)
+	}
+
+	return dst
+}
@@ -0,0 +1,289 @@
+//go:build linux
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"regexp"
+	"sync"
+
+	"golang.org/x/image/font/opentype"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/barcode"
+	"go.afab.re/etiquette/media"
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/pt700"
+	"go.afab.re/etiquette/usblp"
+)
+
+type Config struct {
+	// Printer is the path (/dev/usb/lpN) or USB serial number (serial:XXXX) of the printer,
+	// or a serial port (serial:/dev/ttyXXX?baud=9600).
+	// If empty, the only connected printer is used.
+	Printer string
+	// Font is used for text.
+	// If nil, defaults to Go Regular, with Go Bold and Italic for the other styles.
+	Font *opentype.Font
+	// Logger logs rendering and printing, see pt700.OpenOpts.
+	// If nil, nothing is logged.
+	Logger *slog.Logger
+
+	// Default options of each kind of label.
+	Text  etiquette.TextOpts
+	Image etiquette.ImageOpts
+	Open  pt700.OpenOpts
+	Print pt700.PrintOpts
+}
+
+// Client prints labels on a printer.
+// The printer is only opened while printing, so other programs can use it in between.
+// It is safe for concurrent use, jobs are printed one at a time.
+type Client struct {
+	config Config
+
+	// Guards renderer, which isn't safe for concurrent use, and the printer.
+	mu       sync.Mutex
+	renderer *etiquette.Renderer
+}
+
+func New(config Config) (*Client, error) {
+	var r *etiquette.Renderer
+	if config.Font != nil {
+		r = etiquette.NewRenderer(config.Font)
+		r.Logger = config.Logger
+	} else {
+		var err error
+		r, err = DefaultRenderer(config.Logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Open.Logger == nil {
+		config.Open.Logger = config.Logger
+	}
+
+	return &Client{
+		config:   config,
+		renderer: r,
+	}, nil
+}
+
+// PrintText prints each text as a label, in one job.
+func (c *Client) PrintText(texts ...string) (pt700.PrintResult, error) {
+	return c.print(func(b etiquette.Bounds) ([]*monochrome.Image, error) {
+		var imgs []*monochrome.Image
+		for i, text := range texts {
+			img, err := c.renderer.Text(b, text, c.config.Text)
+			if err != nil {
+				return nil, fmt.Errorf("label %d: %w", i, err)
+			}
+			imgs = append(imgs, img)
+		}
+		return imgs, nil
+	})
+}
+
+// PrintImage prints img as a label.
+func (c *Client) PrintImage(img image.Image) (pt700.PrintResult, error) {
+	return c.print(func(b etiquette.Bounds) ([]*monochrome.Image, error) {
+		mono, err := etiquette.Image(b, img, c.config.Image)
+		if err != nil {
+			return nil, err
+		}
+		return []*monochrome.Image{mono}, nil
+	})
+}
+
+// PrintQR prints a QR code of payload as a label, with caption beside it if it isn't empty.
+func (c *Client) PrintQR(payload, caption string) (pt700.PrintResult, error) {
+	return c.print(func(b etiquette.Bounds) ([]*monochrome.Image, error) {
+		code, err := barcode.QR([]byte(payload), barcode.QRLevelM)
+		if err != nil {
+			return nil, err
+		}
+
+		var img *monochrome.Image
+		if caption == "" {
+			img, err = etiquette.Barcode(b, code, etiquette.BarcodeOpts{
+				Length: c.config.Text.Length,
+				Align:  c.config.Text.Align,
+				Frame:  c.config.Text.Frame,
+			})
+		} else {
+			img, err = c.renderer.Captioned(b, code, caption, c.config.Text)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*monochrome.Image{img}, nil
+	})
+}
+
+// placeholderPattern matches placeholders like {name} in templates.
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_-]+)\}`)
+
+// PrintTemplate prints a text label for each of values, in one job.
+// Placeholders like {name} in template are replaced with the value of name.
+// Every placeholder must have a value.
+func (c *Client) PrintTemplate(template string, values ...map[string]string) (pt700.PrintResult, error) {
+	texts := make([]string, len(values))
+	for i, vals := range values {
+		text, err := fill(template, vals)
+		if err != nil {
+			return pt700.PrintResult{}, fmt.Errorf("label %d: %w", i, err)
+		}
+		texts[i] = text
+	}
+
+	return c.PrintText(texts...)
+}
+
+// fill replaces the placeholders in template with values.
+func fill(template string, values map[string]string) (string, error) {
+	var missing string
+	text := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		v, ok := values[placeholder[1:len(placeholder)-1]]
+		if !ok && missing == "" {
+			missing = placeholder
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("no value for %s", missing)
+	}
+	return text, nil
+}
+
+// Status is the status of the printer, with an estimate of the tape left in it.
+type Status struct {
+	pt700.Status
+
+	// Remaining is the estimated length of tape left in mm, if RemainingOk is set.
+	// It's only known once the length of the cassette has been recorded, with etiquette -cassette.
+	Remaining   float64
+	RemainingOk bool
+}
+
+// Status returns the status of the printer.
+func (c *Client) Status() (Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	printer, name, err := c.open()
+	if err != nil {
+		return Status{}, err
+	}
+	defer printer.Close()
+
+	status, err := printer.Status()
+	if err != nil {
+		return Status{}, err
+	}
+
+	s := Status{Status: status}
+	// The estimate is best effort.
+	if tapes, err := media.Load(); err != nil {
+		c.warn("can't estimate the tape left", err)
+	} else {
+		s.Remaining, s.RemainingOk = tapes[name].Remaining(status.MediaWidth)
+	}
+	return s, nil
+}
+
+// print opens the printer, renders the labels for the tape loaded, and prints them.
+// The tape used is recorded, to estimate how much is left.
+func (c *Client) print(render func(b etiquette.Bounds) ([]*monochrome.Image, error)) (pt700.PrintResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	printer, name, err := c.open()
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+	defer printer.Close()
+
+	status, err := printer.Status()
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+	if err := status.Err(); err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	b, err := etiquette.MediaBounds(status.MediaWidth)
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	imgs, err := render(b)
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	res, err := printer.Print(c.config.Print, imgs...)
+	c.recordTape(name, status.MediaWidth, res.TapeUsed)
+	return res, err
+}
+
+// recordTape adds used mm of tape to the tape used in the printer, if the length of its cassette is known.
+// Labels were printed even if it fails, so it only logs a warning.
+func (c *Client) recordTape(name string, width pt700.MediaWidth, used float64) {
+	if used == 0 {
+		return
+	}
+
+	tapes, err := media.Load()
+	if err != nil {
+		c.warn("can't record the tape used", err)
+		return
+	}
+	if _, ok := tapes[name].Remaining(width); !ok {
+		return
+	}
+
+	if err := media.Update(name, func(m *media.Media) { m.Used += used }); err != nil {
+		c.warn("can't record the tape used", err)
+	}
+}
+
+func (c *Client) warn(msg string, err error) {
+	if c.config.Logger != nil {
+		c.config.Logger.Warn(msg, "err", err)
+	}
+}
+
+// open opens the printer, and returns the name identifying it across runs: its serial:XXXX if it has one.
+func (c *Client) open() (*pt700.PT700, string, error) {
+	if c.config.Printer != "" {
+		path, err := usblp.Find(c.config.Printer)
+		if err != nil {
+			return nil, "", err
+		}
+		printer, err := pt700.Open(path, c.config.Open)
+		return printer, usblp.Name(path), err
+	}
+
+	descs, warnings, err := pt700.Discover()
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch len(descs) {
+	case 0:
+		return nil, "", errors.Join(append([]error{errors.New("no PT-700 printer connected")}, warnings...)...)
+	case 1:
+		name := descs[0].Path
+		if descs[0].Serial != "" {
+			name = "serial:" + descs[0].Serial
+		}
+		printer, err := descs[0].Open(c.config.Open)
+		return printer, name, err
+	default:
+		return nil, "", fmt.Errorf("%d PT-700 printers connected, set Config.Printer", len(descs))
+	}
+}
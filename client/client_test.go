@@ -0,0 +1,32 @@
+//go:build linux
+
+package client
+
+import "testing"
+
+func TestFill(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		template string
+		values   map[string]string
+		want     string
+		err      bool
+	}{
+		{"no placeholders", "Hello", nil, "Hello", false},
+		{"placeholders", "{name}\n{room}-{desk_no}", map[string]string{"name": "Ada", "room": "B2", "desk_no": "7"}, "Ada\nB2-7", false},
+		{"repeated", "{x}{x}", map[string]string{"x": "ab"}, "abab", false},
+		{"empty value", "[{x}]", map[string]string{"x": ""}, "[]", false},
+		{"not a placeholder", "{a b} {001..005}", nil, "{a b} {001..005}", false},
+		{"missing", "{name} {room}", map[string]string{"name": "Ada"}, "", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := fill(test.template, test.values)
+			if (err != nil) != test.err {
+				t.Fatalf("got error %v, want error %v", err, test.err)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Package client is a high level API to print labels,
+// for programs that don't need the control of the etiquette and pt700 packages.
+package client
+
+import (
+	"log/slog"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+
+	"go.afab.re/etiquette"
+)
+
+// DefaultRenderer returns a Renderer using the Go fonts.
+func DefaultRenderer(logger *slog.Logger) (*etiquette.Renderer, error) {
+	ft, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+
+	r := etiquette.NewRenderer(ft)
+	r.Logger = logger
+
+	for style, ttf := range map[etiquette.Style][]byte{
+		etiquette.StyleBold:       gobold.TTF,
+		etiquette.StyleItalic:     goitalic.TTF,
+		etiquette.StyleBoldItalic: gobolditalic.TTF,
+	} {
+		ft, err := opentype.Parse(ttf)
+		if err != nil {
+			return nil, err
+		}
+		r.AddFont(style, ft)
+	}
+
+	return r, nil
+}
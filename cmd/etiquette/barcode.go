@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/barcode"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Barcode types supported by -barcode.
+var symbologies = map[string]func(data string) (*monochrome.Image, error){
+	"code128": barcode.Code128,
+	"datamatrix": func(data string) (*monochrome.Image, error) {
+		return barcode.DataMatrix([]byte(data))
+	},
+	"gs1-128": func(data string) (*monochrome.Image, error) {
+		ais, err := barcode.ParseAIs(data)
+		if err != nil {
+			return nil, err
+		}
+		return barcode.GS1128(ais...)
+	},
+	"qr": func(data string) (*monochrome.Image, error) {
+		return barcode.QR([]byte(data), barcode.QRLevelM)
+	},
+	"gs1-datamatrix": func(data string) (*monochrome.Image, error) {
+		ais, err := barcode.ParseAIs(data)
+		if err != nil {
+			return nil, err
+		}
+		return barcode.GS1DataMatrix(ais...)
+	},
+}
+
+// barcodes renders each line as a barcode.
+func barcodes(b etiquette.Bounds, symbology string, labels io.Reader, opts etiquette.BarcodeOpts) ([]*monochrome.Image, error) {
+	encode, ok := symbologies[symbology]
+	if !ok {
+		return nil, fmt.Errorf("unknown barcode type %q, expected code128, datamatrix, qr, gs1-128 or gs1-datamatrix", symbology)
+	}
+
+	var imgs []*monochrome.Image
+
+	scanner := bufio.NewScanner(labels)
+	for scanner.Scan() {
+		code, err := encode(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", len(imgs), err)
+		}
+
+		img, err := etiquette.Barcode(b, code, opts)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", len(imgs), err)
+		}
+
+		imgs = append(imgs, img)
+	}
+
+	return imgs, scanner.Err()
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Largest pin offset printed by the calibrate command.
+const maxPinOffset = 4
+
+// calibration is the calibrate command.
+type calibration struct {
+	// set saves the pin offset instead of printing the ladder, if not nil.
+	set *int
+}
+
+// parseCalibrate parses the arguments of the calibrate command, and returns the remaining arguments.
+func parseCalibrate(args []string) (*calibration, []string, error) {
+	fs := newCommand("calibrate", "[-set N] [/dev/usb/lpN|serial:XXXX]",
+		fmt.Sprintf("Print a ladder of pin offsets from -%d to %d, each with lines the same distance from both edges of the print area.\nPick the offset with the lines centered on the tape, and save it with -set.", maxPinOffset, maxPinOffset))
+
+	set := fs.Int("set", 0, "Save the pin offset to use with this printer, instead of printing the ladder.")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var c calibration
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "set" {
+			c.set = set
+		}
+	})
+
+	return &c, fs.Args(), nil
+}
+
+// ladder renders a rung for each pin offset, starting with -maxPinOffset.
+// The rungs are drawn shifted instead of shifting the printer, so they can all be printed on one label.
+func (c calibration) ladder(r *etiquette.Renderer, b etiquette.Bounds) (*monochrome.Image, error) {
+	var rungs []*monochrome.Image
+	dy := 0
+	for offset := -maxPinOffset; offset <= maxPinOffset; offset++ {
+		number, err := r.Text(etiquette.Bounds{Dx: b.Dx / 2, DPI: b.DPI}, fmt.Sprintf("%+d", offset), etiquette.TextOpts{})
+		if err != nil {
+			return nil, err
+		}
+
+		rung := monochrome.New(image.Rect(0, 0, b.Dx, number.Bounds().Dy()+1))
+		monochrome.Draw(rung, number.Bounds().Sub(number.Bounds().Min).Add(image.Pt(b.Dx/4, 1)), number, number.Bounds().Min, draw.Src)
+
+		for _, x := range []int{maxPinOffset + offset, b.Dx - 1 - maxPinOffset + offset} {
+			rung.FillRect(image.Rect(x, 0, x+1, rung.Bounds().Dy()), true)
+		}
+		// Separate rungs.
+		rung.FillRect(image.Rect(0, 0, b.Dx, 1), true)
+
+		rungs = append(rungs, rung)
+		dy += rung.Bounds().Dy()
+	}
+
+	// Labels are printed from Max.Y, the first rung goes there.
+	img := monochrome.New(image.Rect(0, 0, b.Dx, dy))
+	for _, rung := range rungs {
+		monochrome.Draw(img, image.Rect(0, dy-rung.Bounds().Dy(), b.Dx, dy), rung, image.Point{}, draw.Src)
+		dy -= rung.Bounds().Dy()
+	}
+
+	return etiquette.Image(b, img, etiquette.ImageOpts{})
+}
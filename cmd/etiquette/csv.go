@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// csvLabels renders a label for each row of CSV, or of the first sheet of an .xlsx spreadsheet, with a header row.
+// The first of columns is the title, and the others are under it.
+func csvLabels(b etiquette.Bounds, labels io.Reader, columns string, now time.Time, logger *slog.Logger, opts etiquette.TextOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := io.ReadAll(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	if bytes.HasPrefix(raw, xlsxMagic) {
+		rows, err = xlsxRows(raw)
+	} else {
+		rows, err = csv.NewReader(bytes.NewReader(raw)).ReadAll()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+
+	var indices []int
+	for _, column := range strings.Split(columns, ",") {
+		i := -1
+		for j, name := range rows[0] {
+			if strings.TrimSpace(name) == strings.TrimSpace(column) {
+				i = j
+			}
+		}
+		if i == -1 {
+			return nil, fmt.Errorf("no column %q in header %q", column, rows[0])
+		}
+		indices = append(indices, i)
+	}
+
+	var imgs []*monochrome.Image
+	for _, row := range rows[1:] {
+		var texts []string
+		for _, i := range indices {
+			// Spreadsheet rows stop at their last cell.
+			var text string
+			if i < len(row) {
+				text = row[i]
+			}
+			texts = append(texts, expandTime(text, now))
+		}
+
+		img, err := r.Titled(b, texts[0], strings.Join(texts[1:], "\n"), opts)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", len(imgs), err)
+		}
+		imgs = append(imgs, img)
+	}
+
+	return imgs, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command is a subcommand, described by its usage and by gen-docs.
+type command struct {
+	name     string
+	synopsis string
+	doc      string
+	flags    *flag.FlagSet
+}
+
+// commands are the subcommands whose flags have been defined, in order.
+var commands []*command
+
+// usageOutput is where subcommands print their usage, gen-docs discards it.
+var usageOutput io.Writer = os.Stderr
+
+// newCommand returns the flags of a subcommand, with a usage of synopsis and doc.
+func newCommand(name, synopsis, doc string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(usageOutput)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "%s [options] %s\n\n%s\n\n", os.Args[0], strings.TrimSpace(name+" "+synopsis), doc)
+		fs.PrintDefaults()
+	}
+
+	commands = append(commands, &command{
+		name:     name,
+		synopsis: synopsis,
+		doc:      doc,
+		flags:    fs,
+	})
+	return fs
+}
+
+// genDocs writes a man page, or a markdown reference, of the options and subcommands to stdout.
+// It's hidden from the usage, it's meant for packagers.
+func genDocs(args []string) error {
+	fs := flag.NewFlagSet("gen-docs", flag.ContinueOnError)
+	format := fs.String("format", "man", "man or markdown.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Parsing -h defines the flags of each subcommand, and registers them.
+	usageOutput = io.Discard
+	commands = nil
+	parseQR([]string{"wifi", "-h"})
+	parseQR([]string{"contact", "-h"})
+	parseQR([]string{"asset", "-h"})
+	parseCalibrate([]string{"-h"})
+	parseTestPage([]string{"-h"})
+	parseDoctor([]string{"-h"})
+
+	switch *format {
+	case "man":
+		writeMan(os.Stdout)
+	case "markdown":
+		writeMarkdown(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q, expected man or markdown", *format)
+	}
+	return nil
+}
+
+const docName = "etiquette"
+
+// docFlag is a flag as it's documented.
+type docFlag struct {
+	// name with a placeholder for its value, if it has one: -size float.
+	name  string
+	usage string
+}
+
+func docFlags(fs *flag.FlagSet) []docFlag {
+	var flags []docFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		value, help := flag.UnquoteUsage(f)
+
+		name := "-" + f.Name
+		if value != "" {
+			name += " " + value
+		}
+		switch f.DefValue {
+		case "", "0", "false", "0s":
+		default:
+			help += fmt.Sprintf(" (default %q)", f.DefValue)
+		}
+
+		flags = append(flags, docFlag{name: name, usage: help})
+	})
+	return flags
+}
+
+func writeMan(w io.Writer) {
+	fmt.Fprintf(w, ".TH %s 1\n", strings.ToUpper(docName))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- print labels on Brother PT-700 printers\n", docName)
+
+	fmt.Fprintf(w, ".SH DESCRIPTION\n")
+	for _, para := range strings.Split(fmt.Sprintf(usage, docName), "\n\n") {
+		if para = strings.TrimSpace(para); para == "" {
+			continue
+		}
+		if strings.HasPrefix(para, docName+" ") {
+			fmt.Fprintf(w, ".PP\n.B %s\n", roff(para))
+			continue
+		}
+		fmt.Fprintf(w, ".PP\n%s\n", roff(para))
+	}
+
+	fmt.Fprintf(w, ".SH OPTIONS\n")
+	manFlags(w, flag.CommandLine)
+
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, ".SS %s\n.B %s [options] %s\n.PP\n%s\n", c.name, docName, roff(strings.TrimSpace(c.name+" "+c.synopsis)), roff(c.doc))
+		manFlags(w, c.flags)
+	}
+}
+
+func manFlags(w io.Writer, fs *flag.FlagSet) {
+	for _, f := range docFlags(fs) {
+		fmt.Fprintf(w, ".TP\n.B %s\n%s\n", roff(f.name), roff(f.usage))
+	}
+}
+
+// roff escapes text for man pages.
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+
+	// Lines starting with . or ' are requests.
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func writeMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# %s\n\n", docName)
+	for _, para := range strings.Split(fmt.Sprintf(usage, docName), "\n\n") {
+		if para = strings.TrimSpace(para); para == "" {
+			continue
+		}
+		if strings.HasPrefix(para, docName+" ") {
+			fmt.Fprintf(w, "```\n%s\n```\n\n", para)
+			continue
+		}
+		fmt.Fprintf(w, "%s\n\n", para)
+	}
+
+	fmt.Fprintf(w, "## Options\n\n")
+	markdownFlags(w, flag.CommandLine)
+
+	for _, c := range commands {
+		fmt.Fprintf(w, "## %s\n\n```\n%s [options] %s\n```\n\n%s\n\n", c.name, docName, strings.TrimSpace(c.name+" "+c.synopsis), c.doc)
+		markdownFlags(w, c.flags)
+	}
+}
+
+func markdownFlags(w io.Writer, fs *flag.FlagSet) {
+	for _, f := range docFlags(fs) {
+		fmt.Fprintf(w, "* `%s`: %s\n", f.name, f.usage)
+	}
+	fmt.Fprintln(w)
+}
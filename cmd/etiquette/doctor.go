@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"go.afab.re/etiquette/pt700"
+	"go.afab.re/etiquette/usblp"
+)
+
+// parseDoctor parses the arguments of the doctor command, and returns the remaining arguments.
+func parseDoctor(args []string) ([]string, error) {
+	fs := newCommand("doctor", "",
+		"Check everything needed to print: the usblp driver, connected printers, permissions and printer status.\nInclude the output in bug reports.")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+// doctor runs checks, and reports each one as it goes.
+type doctor struct {
+	w              io.Writer
+	checks, failed int
+	warned         int
+}
+
+func (d *doctor) ok(format string, args ...any) {
+	d.report("ok", format, args...)
+}
+
+func (d *doctor) warn(format string, args ...any) {
+	d.warned++
+	d.report("WARN", format, args...)
+}
+
+func (d *doctor) fail(format string, args ...any) {
+	d.failed++
+	d.report("FAIL", format, args...)
+}
+
+func (d *doctor) report(result, format string, args ...any) {
+	d.checks++
+	fmt.Fprintf(d.w, "%-4s  %s\n", result, fmt.Sprintf(format, args...))
+}
+
+// Where the usblp module shows up in sysfs, if it's loaded or built into the kernel.
+const sysUSBLP = "/sys/module/usblp"
+
+// runDoctor checks everything needed to print, and returns an error if anything failed.
+func runDoctor(w io.Writer) error {
+	d := doctor{w: w}
+
+	if _, err := os.Stat(sysUSBLP); errors.Is(err, fs.ErrNotExist) {
+		d.fail("usblp driver isn't loaded, load it with: sudo modprobe usblp")
+	} else {
+		d.ok("usblp driver loaded")
+	}
+
+	devices, warnings, err := usblp.Connected()
+	if err != nil {
+		d.fail("listing usblp printers: %v", err)
+	}
+	for _, warning := range warnings {
+		d.warn("usblp printer %v", warning)
+	}
+
+	// Brother devices the usblp driver doesn't handle can't be printed to.
+	usb, warnings, err := usblp.USBDevices()
+	if err != nil {
+		d.warn("listing USB devices: %v", err)
+	}
+	for _, warning := range warnings {
+		d.warn("USB device %v", warning)
+	}
+	handled := make(map[string]bool)
+	for _, device := range devices {
+		handled[device.BusPath] = true
+	}
+	for _, info := range usb {
+		if info.VendorID == pt700.BrotherVendorID && !handled[info.BusPath] {
+			d.warn("%v isn't a usblp printer. Printers in P-Lite mode show up as a USB drive instead, turn it off.", info)
+		}
+	}
+
+	descs, _, err := pt700.Discover()
+	if err != nil {
+		d.fail("finding PT-700 printers: %v", err)
+	}
+
+	supported := make(map[string]bool)
+	for _, desc := range descs {
+		supported[desc.Path] = true
+	}
+	for _, device := range devices {
+		if !supported[device.Path] {
+			d.ok("%s: %v isn't a supported printer, ignoring it", device.Path, device.DeviceInfo)
+		}
+	}
+
+	if len(descs) == 0 {
+		d.fail("no PT-700 printer connected")
+	}
+
+	for _, desc := range descs {
+		d.ok("%s: %s %v", desc.Path, desc.Model, desc.DeviceInfo)
+		d.printer(desc)
+	}
+
+	fmt.Fprintf(w, "\n%d checks: %d failed, %d warnings\n", d.checks, d.failed, d.warned)
+	if d.failed != 0 {
+		return fmt.Errorf("%d checks failed", d.failed)
+	}
+	return nil
+}
+
+// printer checks a printer can be opened, and is ready to print.
+func (d *doctor) printer(desc pt700.Desc) {
+	p, err := desc.Open(pt700.OpenOpts{})
+	if err != nil {
+		d.fail("%s: %v", desc.Path, err)
+		return
+	}
+	defer p.Close()
+	d.ok("%s: opened", desc.Path)
+
+	status, err := p.Status()
+	if err != nil {
+		d.fail("%s: reading status: %v", desc.Path, err)
+		return
+	}
+
+	if err := status.Err(); err != nil {
+		d.fail("%s: %v", desc.Path, err)
+		return
+	}
+	d.ok("%s: ready, %s tape", desc.Path, status.Media())
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/escpos"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// escposLabels renders a label for each receipt read as ESC/POS.
+func escposLabels(b etiquette.Bounds, labels io.Reader, logger *slog.Logger, opts etiquette.ImageOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	receipts, err := escpos.Parse(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var imgs []*monochrome.Image
+	for i, receipt := range receipts {
+		if len(receipt.Unsupported) != 0 {
+			fmt.Fprintf(os.Stderr, "Warning: label %d: ignoring unsupported ESC/POS commands %s\n", i, strings.Join(receipt.Unsupported, ", "))
+		}
+
+		img, err := r.ESCPOS(b, receipt, opts)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", i, err)
+		}
+		imgs = append(imgs, img)
+	}
+
+	return imgs, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of labels a single label can be expanded to, to catch typos.
+const maxExpanded = 10000
+
+// rangePattern matches numeric ranges like {001..050}.
+var rangePattern = regexp.MustCompile(`\{(-?[0-9]+)\.\.(-?[0-9]+)\}`)
+
+// expand a label into a series of numbered labels,
+// with ranges like {001..050} expanded like shell brace expansion, and {n} replaced by seq.
+// Dates and times are expanded too.
+func expand(label string, seq *sequence, now time.Time) ([]string, error) {
+	labels, err := expandRanges(expandTime(label, now))
+	if err != nil {
+		return nil, err
+	}
+
+	if seq == nil {
+		return labels, nil
+	}
+	if len(labels)*seq.count > maxExpanded {
+		return nil, fmt.Errorf("expands to more than %d labels", maxExpanded)
+	}
+
+	var expanded []string
+	for _, label := range labels {
+		numbered, err := seq.expand(label)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, numbered...)
+	}
+
+	return expanded, nil
+}
+
+// timePattern matches {date}, {time}, and {date:2006-01-02} with a Go time layout.
+var timePattern = regexp.MustCompile(`\{(date|time)(?::([^}]*))?\}`)
+
+// Default layouts of {date} and {time}.
+var timeLayouts = map[string]string{
+	"date": "2006-01-02",
+	"time": "15:04",
+}
+
+// expandTime replaces dates and times in label with now.
+func expandTime(label string, now time.Time) string {
+	return timePattern.ReplaceAllStringFunc(label, func(placeholder string) string {
+		m := timePattern.FindStringSubmatch(placeholder)
+
+		layout := m[2]
+		if layout == "" {
+			layout = timeLayouts[m[1]]
+		}
+		return now.Format(layout)
+	})
+}
+
+// expandRanges expands the first range in label, and then recursively the rest of it.
+// Numbers are padded with zeros to the width of the range if either end starts with a 0.
+func expandRanges(label string) ([]string, error) {
+	loc := rangePattern.FindStringSubmatchIndex(label)
+	if loc == nil {
+		return []string{label}, nil
+	}
+
+	first, last := label[loc[2]:loc[3]], label[loc[4]:loc[5]]
+
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return nil, err
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil {
+		return nil, err
+	}
+
+	// count overflows for ranges spanning most of the ints.
+	count := max(from, to) - min(from, to) + 1
+	if count <= 0 || count > maxExpanded {
+		return nil, fmt.Errorf("range %s expands to more than %d labels", label[loc[0]:loc[1]], maxExpanded)
+	}
+
+	var width int
+	if padded(first) || padded(last) {
+		width = max(len(first), len(last))
+	}
+
+	step := 1
+	if to < from {
+		step = -1
+	}
+
+	rest, err := expandRanges(label[loc[1]:])
+	if err != nil {
+		return nil, err
+	}
+	// Check before expanding, a few ranges expand to a huge number of labels.
+	if count*len(rest) > maxExpanded {
+		return nil, fmt.Errorf("expands to more than %d labels", maxExpanded)
+	}
+
+	labels := make([]string, 0, count*len(rest))
+	for n := from; ; n += step {
+		for _, r := range rest {
+			labels = append(labels, label[:loc[0]]+fmt.Sprintf("%0*d", width, n)+r)
+		}
+
+		if n == to {
+			break
+		}
+	}
+
+	return labels, nil
+}
+
+// padded reports if a number is written with leading zeros.
+func padded(n string) bool {
+	n = strings.TrimPrefix(n, "-")
+	return len(n) > 1 && n[0] == '0'
+}
+
+// sequence is a series of numbers set with -sequence.
+type sequence struct {
+	start  int
+	count  int
+	format string
+}
+
+// parseSequence parses a sequence like "start=1 count=50 format=%03d".
+// Only count is required, start defaults to 1 and format to %d.
+func parseSequence(s string) (*sequence, error) {
+	seq := sequence{
+		start:  1,
+		format: "%d",
+	}
+
+	for _, field := range strings.FieldsFunc(s, func(c rune) bool { return c == ' ' || c == ',' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("sequence: expected key=value, got %q", field)
+		}
+
+		var err error
+		switch key {
+		case "start":
+			seq.start, err = strconv.Atoi(value)
+		case "count":
+			seq.count, err = strconv.Atoi(value)
+		case "format":
+			seq.format = value
+		default:
+			return nil, fmt.Errorf("sequence: unknown key %q, expected start, count or format", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sequence: %s: %w", key, err)
+		}
+	}
+
+	if seq.count <= 0 || seq.count > maxExpanded {
+		return nil, fmt.Errorf("sequence: count must be between 1 and %d", maxExpanded)
+	}
+	if strings.Count(seq.format, "%") != 1 {
+		return nil, fmt.Errorf("sequence: format %q must have a single verb, like %%03d", seq.format)
+	}
+
+	return &seq, nil
+}
+
+// expand replaces {n} in label with each number of the sequence.
+func (s sequence) expand(label string) ([]string, error) {
+	if !strings.Contains(label, "{n}") {
+		return nil, fmt.Errorf("no {n} to number label %q with -sequence", label)
+	}
+
+	var labels []string
+	for i := 0; i < s.count; i++ {
+		labels = append(labels, strings.ReplaceAll(label, "{n}", fmt.Sprintf(s.format, s.start+i)))
+	}
+	return labels, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// jsonLabel is a label read with -jsonl, overriding the options from the flags.
+type jsonLabel struct {
+	Text string `json:"text"`
+	// Number of times to print the label, defaults to 1.
+	Copies int    `json:"copies"`
+	Align  string `json:"align"`
+	// Font size in points.
+	Size float64 `json:"size"`
+	// regular, bold, italic or bold-italic.
+	Style string `json:"style"`
+	// Contents of a QR code, printed with the text beside it.
+	QR string `json:"qr"`
+}
+
+// jsonl renders labels read as one JSON object per line.
+func jsonl(b etiquette.Bounds, labels io.Reader, now time.Time, logger *slog.Logger, opts etiquette.TextOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var imgs []*monochrome.Image
+
+	dec := json.NewDecoder(labels)
+	dec.DisallowUnknownFields()
+	for i := 0; ; i++ {
+		var label jsonLabel
+		err := dec.Decode(&label)
+		switch {
+		case errors.Is(err, io.EOF):
+			return imgs, nil
+		case err != nil:
+			return nil, fmt.Errorf("label %d: %w", i, err)
+		}
+
+		label.Text = expandTime(label.Text, now)
+
+		img, err := label.render(r, b, opts)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", i, err)
+		}
+
+		copies := label.Copies
+		switch {
+		case copies == 0:
+			copies = 1
+		case copies < 0:
+			return nil, fmt.Errorf("label %d: invalid number of copies %d", i, copies)
+		}
+
+		for c := 0; c < copies; c++ {
+			imgs = append(imgs, img)
+		}
+	}
+}
+
+func (l jsonLabel) render(r *etiquette.Renderer, b etiquette.Bounds, opts etiquette.TextOpts) (*monochrome.Image, error) {
+	if l.Align != "" {
+		align, err := etiquette.ParseAlign(l.Align)
+		if err != nil {
+			return nil, err
+		}
+		opts.Align = align
+	}
+
+	if l.Size != 0 {
+		opts.Size = l.Size
+	}
+
+	if l.Style != "" {
+		style, err := etiquette.ParseStyle(l.Style)
+		if err != nil {
+			return nil, err
+		}
+		opts.Style = style
+	}
+
+	if l.QR != "" {
+		return qr(r, b, l.QR, l.Text, opts)
+	}
+
+	return r.Text(b, l.Text, opts)
+}
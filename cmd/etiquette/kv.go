@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// kv renders labels read as key=value lines, with blank lines between labels.
+func kv(b etiquette.Bounds, labels io.Reader, now time.Time, logger *slog.Logger, opts etiquette.TextOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		imgs []*monochrome.Image
+		kvs  []etiquette.KeyValue
+	)
+
+	flush := func() error {
+		if len(kvs) == 0 {
+			return nil
+		}
+
+		img, err := r.KeyValues(b, kvs, opts)
+		if err != nil {
+			return fmt.Errorf("label %d: %w", len(imgs), err)
+		}
+
+		imgs = append(imgs, img)
+		kvs = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(labels)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("label %d: expected key=value, got %q", len(imgs), line)
+		}
+
+		kvs = append(kvs, etiquette.KeyValue{
+			Key:   strings.TrimSpace(key) + ":",
+			Value: expandTime(strings.TrimSpace(value), now),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return imgs, nil
+}
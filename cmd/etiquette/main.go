@@ -2,49 +2,432 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
-	"image/png"
 	"io"
+	"log/slog"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"golang.org/x/image/font/gofont/goregular"
-	"golang.org/x/image/font/opentype"
+	"golang.org/x/sys/unix"
 
 	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/media"
 	"go.afab.re/etiquette/monochrome"
 	"go.afab.re/etiquette/pt700"
+	"go.afab.re/etiquette/pwg"
+	"go.afab.re/etiquette/usblp"
 )
 
-func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, `%s [options] /dev/usb/lpN
+// usage of the command, with %[1]s the name of the binary.
+const usage = `%[1]s [options] [/dev/usb/lpN|serial:XXXX]
+
+Print each line from stdin as a text label on a Brother PT-700 printer connected as /dev/usb/lpN,
+or with USB serial number XXXX.
+Printers with a serial port are given as serial:/dev/ttyXXX?baud=9600&flow=none, flow=rtscts enables hardware flow control.
+If no printer is given, the only connected PT-700 is used.
+{date}, {time}, and {date:2006-01-02} with a Go time layout in labels are replaced with the current time.
+
+%[1]s [options] qr wifi -ssid SSID -pass PASSWORD [/dev/usb/lpN|serial:XXXX]
+
+%[1]s [options] qr contact -name NAME -phone PHONE -email EMAIL [/dev/usb/lpN|serial:XXXX]
+
+%[1]s [options] qr asset -url URL -range FIRST..LAST [/dev/usb/lpN|serial:XXXX]
+
+Print a QR code to join a WiFi network, with contact details, or numbered asset tags linking to each asset,
+see %[1]s qr wifi -h, %[1]s qr contact -h and %[1]s qr asset -h.
+
+%[1]s [options] calibrate [-set N] [/dev/usb/lpN|serial:XXXX]
+
+Print a ladder of pin offsets, for printers that print slightly off-center, see %[1]s calibrate -h.
+
+%[1]s [options] test-page [/dev/usb/lpN|serial:XXXX]
+
+Print a calibration pattern, to check the alignment and density of the printer and tape:
+rules, pin index marks, a mm ruler and checkerboards.
 
-Print each line from stdin as a text label on a Brother PT-700 printer connected as /dev/usb/lpN.
+%[1]s doctor
 
-`, os.Args[0])
+Check everything needed to print, and show what's wrong. Include the output in bug reports.
+
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
 		flag.PrintDefaults()
 	}
 
 	var (
-		status  = flag.Bool("status", false, "Show printer status only, don't print anything.")
-		img     = flag.Bool("img", false, "Print an image (PNG/GIF/JPEG) from stdin instead of text.")
-		preview = flag.String("preview", "", "Preview the print as a PNG image written to filename.")
+		list     = flag.Bool("list", false, "List connected printers, don't print anything.")
+		status   = flag.Bool("status", false, "Show printer status only, don't print anything.")
+		estimate = flag.Bool("estimate", false, "Show the length of tape the labels would use, don't print anything.")
+		cassette = flag.Float64("cassette", 0, "Record that a new cassette with this length of tape in mm was installed, don't print anything.")
+		dryRun   = flag.Bool("dry-run", false, "Render and check the labels fit the tape, don't print anything. The printer isn't opened: the tape is -tape, or the one last recorded with -cassette.")
+		tape     = flag.Float64("tape", 0, "Width of the tape in mm, instead of asking the printer. Only supported with -dry-run, -estimate, -encode, -save-project, -preview or -preview-term, the printer isn't needed.")
+		projFile = flag.String("project", "", "Print the labels saved in a project file with -save-project, instead of labels from stdin. Flags override the saved ones.")
+		saveProj = flag.String("save-project", "", "Save the labels from stdin and the flags to a project file with a preview, to print them again with -project. Don't print anything.")
+		rawFile  = flag.String("raw-file", "", "Print a job saved with -encode, or by Brother's software, instead of labels from stdin.")
+		encode   = flag.String("encode", "", "Write the print job to filename instead of printing, to print it later with cat filename > /dev/usb/lpN.")
+		img      = flag.Bool("img", false, "Print an image (PNG/GIF/JPEG), or PWG raster or URF pages, from stdin instead of text.")
+		nul      = flag.Bool("0", false, "Labels from stdin are separated by NUL characters instead of newlines.")
+		raw      = flag.Bool("raw", false, "Print all of stdin as one label, newlines start new lines in the label.")
+		jsonl    = flag.Bool("jsonl", false, `Read one JSON object per line from stdin instead of text: {"text": "...", "copies": 2, "align": "start", "size": 10, "style": "bold"}.`)
+		code     = flag.String("barcode", "", "Print each line from stdin as a barcode of this type: code128, datamatrix, qr, gs1-128 or gs1-datamatrix. GS1 barcodes are written as (01)09501101530003(17)261231(10)AB12.")
+		module   = flag.Float64("module", 0, "Width of the narrowest bar of linear barcodes in mm. Defaults to 2 dots.")
+		seq      = flag.String("sequence", "", "Print each label several times, replacing {n} in it with increasing numbers: start=1 count=50 format=%03d. Ranges like {001..050} are always expanded.")
+		cable    = flag.Float64("cable-flag", 0, "Print each label twice, with a gap to fold it around a cable of this diameter in mm as a flag readable from both sides.")
+		wrap     = flag.Float64("cable-wrap", 0, "Print each label across the tape, repeated to wrap around a cable of this diameter in mm.")
+		csvCols  = flag.String("csv", "", "Read CSV, or the first sheet of an .xlsx spreadsheet, with a header row from stdin, and print a label for each row with these comma separated columns: the first one large, and the others smaller under it.")
+		kv       = flag.Bool("kv", false, "Read key=value lines from stdin, and print the keys and values in two columns. Blank lines separate labels.")
+		zplIn    = flag.Bool("zpl", false, "Read ZPL from stdin instead of text, and print its labels. Only a subset of ZPL is supported: text, Code 128 barcodes and graphic fields.")
+		escposIn = flag.Bool("escpos", false, "Read ESC/POS from stdin instead of text, and print a label for each receipt, up to each cut. Only text and raster images are supported.")
+		preview  = flag.String("preview", "", "Preview the print as a PNG image written to filename, or stdout if it is -. Several labels are written to a directory if filename ends with /, or as an animated GIF if it ends with .gif.")
+		tapeCol  = flag.String("tape-color", "", "Color of the tape for -preview, for example white or yellow. Defaults to what the printer reports, if it does.")
+		textCol  = flag.String("text-color", "", "Color of the text for -preview, for example black or red. Defaults to what the printer reports, if it does.")
+		corners  = flag.Float64("preview-corners", 0, "Round the corners of labels in -preview with this radius in mm, so previews of die-cut labels look like them.")
+		termPrev = flag.Bool("preview-term", false, "Preview the labels in the terminal, don't print anything.")
+		length   = flag.Float64("length", 0, "Minimum length of each label in mm.")
+		size     = flag.Float64("size", 0, "Font size in points. Defaults to the biggest size that fits the tape.")
+		margin   = flag.Float64("margin", 0, "Blank space before and after text in mm. Defaults to 1mm.")
+		bold     = flag.Bool("bold", false, "Print text in bold.")
+		italic   = flag.Bool("italic", false, "Print text in italics.")
+		under    = flag.Bool("underline", false, "Underline text.")
+		strike   = flag.Bool("strikethrough", false, "Strike through text.")
+		box      = flag.Bool("box", false, "Draw a box around each line of text.")
+		spacing  = flag.Float64("letter-spacing", 0, "Extra space between letters in mm, can be negative.")
+		hinting  = flag.String("hinting", "full", "How text is snapped to pixels: full, vertical or none. Full hinting distorts some fonts at small sizes.")
+		supersmp = flag.Int("supersample", 0, "Render text at 2 to 4 times the resolution of the printer and scale it down, to keep small text legible.")
+		maxLen   = flag.Float64("max-length", 0, "Maximum length of text labels in mm. Longer text is condensed, and then made smaller.")
+		condense = flag.Float64("condense", 10, "Maximum percentage text can be condensed by to fit -max-length before it is made smaller.")
+		translit = flag.Bool("transliterate", false, "Replace characters the font doesn't have with ASCII approximations (é → e) instead of failing.")
+		iconName = flag.String("icon", "none", "Print this pictogram before the text of each label: "+strings.Join(etiquette.IconNames(), ", ")+".")
+		iconFile = flag.String("icon-file", "", "Print this image, for example a logo, before the text of each label, scaled to the height of the tape.")
+		align    = flag.String("align", "center", "Where to put the contents of labels longer than them: start, center or end.")
+		trim     = flag.Bool("trim", false, "Crop white borders from images before printing them.")
+		fit      = flag.Bool("fit", false, "Scale images to the width of the tape.")
+		filter   = flag.String("filter", "auto", "How to scale images with -fit: nearest, box, catmull-rom, or auto to use box when shrinking and nearest when enlarging.")
+		sharpen  = flag.Float64("sharpen", 0, "Sharpen images before converting them to black and white, to keep thin lines and small text. 1 doubles the contrast of edges.")
+		speckle  = flag.Int("despeckle", 0, "Remove black specks, and fill in white pinholes, smaller than this many dots from images.")
+		dilate   = flag.Int("dilate", 0, "Thicken black areas of images by this many dots, so thin lines survive printing. Negative values thin them instead.")
+		levels   = flag.String("levels", "", "Adjust images before converting them to black and white, instead of picking the threshold automatically: gamma=1.5 brightness=0.1 contrast=0.2.")
+		frame    = flag.Float64("frame", 0, "Draw a frame of this thickness in mm around each label.")
+		radius   = flag.Float64("frame-radius", 0, "Radius of the frame corners in mm.")
+		unique   = flag.String("unique", "", "Check for labels identical to the one before them, from messy CSV exports for example: warn to only warn about them, or drop to not print them.")
+		split    = flag.Bool("split", false, "Split labels longer than the printer supports into several labels, marking where they continue.")
+		overlap  = flag.Float64("split-overlap", 0, "Length in mm repeated from the end of each split label at the start of the next one.")
+		nUp      = flag.Int("n-up", 1, "Print this many labels side by side across the tape, with dotted lines to cut them apart, to use less of wide tapes.")
+		plan     = flag.Bool("plan", false, "Put labels shorter than the minimum length the printer can print together, separated by dotted lines to cut them apart, to use less tape. Shows how much tape and how many cuts are saved.")
+		planLen  = flag.Float64("plan-length", 0, "Put labels together with -plan on pages up to this long in mm, to save cuts too.")
+		reorder  = flag.Bool("reorder", false, "Print labels in a different order with -plan, if that fits them on fewer pages.")
+		flip     = flag.Bool("flip", false, "Turn the contents of labels upside down, to stick them on the other way around, for example on cables running the other direction.")
+		verbose  = flag.Bool("v", false, "Log printer status changes and timings.")
+		debug    = flag.Bool("vv", false, "Log everything, including all the data sent to and received from the printer.")
+		trace    = flag.String("trace", "", "Record all the data sent to and received from the printer to filename.")
+		wait     = flag.Bool("wait", false, "Wait for other etiquette processes to finish using the printer.")
+		readTime = flag.Duration("status-timeout", 0, "How long to wait for the printer to answer status requests and start printing. Printing long labels is allowed more time. Defaults to 10s.")
+		timeout  = flag.Duration("write-timeout", 0, "How long to wait for the printer to accept data before giving up. Defaults to 10s.")
 	)
 	flag.Parse()
 
-	if flag.NArg() != 1 {
+	var labels io.Reader = os.Stdin
+	// Image of -icon-file. Projects save the image itself, -icon-file overrides it.
+	var iconData []byte
+	if *projFile != "" {
+		p, err := loadProject(*projFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+
+		// Saved flags first, so the command line overrides them.
+		flag.CommandLine.Parse(append(p.Args, os.Args[1:]...))
+		labels = bytes.NewReader(p.labels())
+		iconData = p.Icon
+	}
+
+	if *list {
+		if err := listPrinters(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+		return
+	}
+
+	args := flag.Args()
+
+	var qr *qrLabel
+	var testPage bool
+	var calibrate *calibration
+	switch {
+	case len(args) > 0 && args[0] == "qr":
+		var err error
+		qr, args, err = parseQR(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(-1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	case len(args) > 0 && args[0] == "test-page":
+		var err error
+		args, err = parseTestPage(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(-1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+		testPage = true
+	case len(args) > 0 && args[0] == "doctor":
+		var err error
+		args, err = parseDoctor(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(-1)
+		}
+		if err == nil && len(args) != 0 {
+			err = fmt.Errorf("unexpected arguments %q", args)
+		}
+		if err == nil {
+			err = runDoctor(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+		return
+	case len(args) > 0 && args[0] == "gen-docs":
+		if err := genDocs(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+		return
+	case len(args) > 0 && args[0] == "calibrate":
+		var err error
+		calibrate, args, err = parseCalibrate(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(-1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	if len(args) > 1 {
 		flag.Usage()
 		os.Exit(-1)
 	}
 
-	if err := print(flag.Arg(0), os.Stdin, flags{
-		status:  *status,
-		img:     *img,
-		preview: *preview,
+	if *iconFile != "" {
+		var err error
+		iconData, err = os.ReadFile(*iconFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	var save *project
+	if *saveProj != "" {
+		if qr != nil || testPage || calibrate != nil {
+			fmt.Fprintf(os.Stderr, "Error: -save-project only saves labels from stdin\n")
+			os.Exit(-1)
+		}
+
+		raw, err := io.ReadAll(labels)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+		save = newProject(*saveProj, raw, iconData)
+		labels = bytes.NewReader(raw)
+	}
+
+	var printerName string
+	if len(args) == 1 {
+		printerName = args[0]
+	}
+
+	var logger *slog.Logger
+	switch {
+	case *debug:
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	case *verbose:
+		logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	}
+
+	alignment, err := etiquette.ParseAlign(*align)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	hint, err := etiquette.ParseHinting(*hinting)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	if *supersmp < 0 || *supersmp > 4 {
+		fmt.Fprintf(os.Stderr, "Error: -supersample must be between 0 and 4\n")
+		os.Exit(-1)
+	}
+
+	icon, err := etiquette.ParseIcon(*iconName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	var iconImg image.Image
+	if iconData != nil {
+		iconImg, _, err = image.Decode(bytes.NewReader(iconData))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: -icon-file: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	if *corners < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -preview-corners can't be negative\n")
+		os.Exit(-1)
+	}
+
+	colors := tapeLook{corners: *corners}
+	if *tapeCol != "" {
+		colors.color, err = pt700.ParseTapeColor(*tapeCol)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+	if *textCol != "" {
+		colors.text, err = pt700.ParseTextColor(*textCol)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	scaleFilter, err := etiquette.ParseFilter(*filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	imgLevels, err := parseLevels(*levels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(-1)
+	}
+
+	var numbers *sequence
+	if *seq != "" {
+		numbers, err = parseSequence(*seq)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(-1)
+		}
+	}
+
+	var style etiquette.Style
+	if *bold {
+		style |= etiquette.StyleBold
+	}
+	if *italic {
+		style |= etiquette.StyleItalic
+	}
+
+	var deco etiquette.Decoration
+	if *under {
+		deco |= etiquette.DecorationUnderline
+	}
+	if *strike {
+		deco |= etiquette.DecorationStrikethrough
+	}
+	if *box {
+		deco |= etiquette.DecorationBox
+	}
+
+	if err := print(printerName, labels, flags{
+		status:   *status,
+		estimate: *estimate,
+		cassette: *cassette,
+		dryRun:   *dryRun,
+		tape:     *tape,
+		img:      *img,
+		jsonl:    *jsonl,
+		kv:       *kv,
+		zpl:      *zplIn,
+		escpos:   *escposIn,
+		csv:      *csvCols,
+		cable:    *cable,
+		wrap:     *wrap,
+		seq:      numbers,
+		qr:       qr,
+		testPage: testPage,
+		calib:    calibrate,
+		barcode:  *code,
+		module:   *module,
+		nul:      *nul,
+		raw:      *raw,
+		encode:   *encode,
+		save:     save,
+		rawFile:  *rawFile,
+		preview:  *preview,
+		termPrev: *termPrev,
+		colors:   colors,
+		length:   *length,
+		size:     *size,
+		margin:   *margin,
+		style:    style,
+		deco:     deco,
+		spacing:  *spacing,
+		hinting:  hint,
+		supersmp: *supersmp,
+		maxLen:   *maxLen,
+		condense: *condense / 100,
+		align:    alignment,
+		icon:     icon,
+		iconImg:  iconImg,
+		translit: *translit,
+		trim:     *trim,
+		fit:      *fit,
+		filter:   scaleFilter,
+		sharpen:  *sharpen,
+		levels:   imgLevels,
+		speckle:  *speckle,
+		dilate:   *dilate,
+		unique:   *unique,
+		split:    *split,
+		overlap:  *overlap,
+		nUp:      *nUp,
+		plan:     *plan,
+		planLen:  *planLen,
+		reorder:  *reorder,
+		flip:     *flip,
+		logger:   logger,
+		trace:    *trace,
+		wait:     *wait,
+		timeout:  *timeout,
+		statusTO: *readTime,
+		frame: etiquette.Frame{
+			Thickness: *frame,
+			Radius:    *radius,
+		},
 	}); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(-1)
@@ -52,13 +435,127 @@ Print each line from stdin as a text label on a Brother PT-700 printer connected
 }
 
 type flags struct {
-	status  bool
-	img     bool
-	preview string
+	status   bool
+	estimate bool
+	cassette float64
+	dryRun   bool
+	tape     float64
+	img      bool
+	jsonl    bool
+	kv       bool
+	zpl      bool
+	escpos   bool
+	csv      string
+	cable    float64
+	wrap     float64
+	seq      *sequence
+	qr       *qrLabel
+	testPage bool
+	calib    *calibration
+	barcode  string
+	module   float64
+	nul      bool
+	raw      bool
+	encode   string
+	save     *project
+	rawFile  string
+	preview  string
+	termPrev bool
+	colors   tapeLook
+	length   float64
+	size     float64
+	margin   float64
+	style    etiquette.Style
+	deco     etiquette.Decoration
+	spacing  float64
+	hinting  etiquette.Hinting
+	supersmp int
+	maxLen   float64
+	condense float64
+	align    etiquette.Align
+	icon     etiquette.Icon
+	iconImg  image.Image
+	translit bool
+	trim     bool
+	fit      bool
+	filter   etiquette.Filter
+	sharpen  float64
+	levels   etiquette.Levels
+	speckle  int
+	dilate   int
+	unique   string
+	split    bool
+	overlap  float64
+	nUp      int
+	plan     bool
+	planLen  float64
+	reorder  bool
+	flip     bool
+	logger   *slog.Logger
+	trace    string
+	wait     bool
+	timeout  time.Duration
+	statusTO time.Duration
+	frame    etiquette.Frame
 }
 
-func print(printerPath string, labels io.Reader, flags flags) error {
-	printer, err := pt700.Open(printerPath)
+func print(printerName string, labels io.Reader, flags flags) error {
+	// Asking the printer for its status writes to it, so dry runs never open it.
+	if flags.tape != 0 || flags.dryRun {
+		if !flags.dryRun && !flags.estimate && flags.encode == "" && flags.save == nil && flags.preview == "" && !flags.termPrev {
+			return fmt.Errorf("-tape can only be used with -dry-run, -estimate, -encode, -save-project, -preview or -preview-term")
+		}
+		if flags.rawFile != "" {
+			return errors.New("-raw-file can't be used with -tape or -dry-run")
+		}
+
+		var width pt700.MediaWidth
+		var err error
+		if flags.tape != 0 {
+			width, err = tapeWidth(flags.tape)
+		} else {
+			width, err = recordedWidth(printerName)
+		}
+		if err != nil {
+			return err
+		}
+
+		imgs, err := render(width, labels, flags)
+		if err != nil {
+			return err
+		}
+
+		_, err = report(width, imgs, flags)
+		return err
+	}
+
+	openOpts := pt700.OpenOpts{
+		Logger:       flags.logger,
+		Wait:         flags.wait,
+		WriteTimeout: flags.timeout,
+	}
+	if flags.trace != "" {
+		trace, err := os.Create(flags.trace)
+		if err != nil {
+			return err
+		}
+		defer trace.Close()
+
+		openOpts.Trace = trace
+	}
+
+	printerName, path, err := findPrinter(printerName)
+	if err != nil {
+		return err
+	}
+
+	if flags.calib != nil && flags.calib.set != nil {
+		return media.Update(printerName, func(m *media.Media) {
+			m.PinOffset = *flags.calib.set
+		})
+	}
+
+	printer, err := pt700.Open(path, openOpts)
 	if err != nil {
 		return err
 	}
@@ -68,8 +565,37 @@ func print(printerPath string, labels io.Reader, flags flags) error {
 	if err != nil {
 		return err
 	}
+
+	if flags.cassette != 0 {
+		return media.Update(printerName, func(m *media.Media) {
+			m.Width = status.MediaWidth
+			m.Cassette = flags.cassette
+			m.Used = 0
+		})
+	}
+
+	// Tracking tape is best effort, labels can be printed without it.
+	tapes, err := media.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: can't estimate the tape left: %v\n", err)
+	}
+	remaining, remainingOk := tapes[printerName].Remaining(status.MediaWidth)
+	// Only record the tape used once the length of the cassette is known, with -cassette.
+	recordTape := func(update func(*media.Media)) {
+		if !remainingOk {
+			return
+		}
+		if err := media.Update(printerName, update); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: can't record the tape used: %v\n", err)
+		}
+	}
+
 	if flags.status {
 		fmt.Printf("%+v\n", status)
+		fmt.Printf("%s tape\n", status.Media())
+		if remainingOk {
+			fmt.Printf("~%.0fmm of tape remaining\n", remaining)
+		}
 		return nil
 	}
 
@@ -77,48 +603,504 @@ func print(printerPath string, labels io.Reader, flags flags) error {
 		return err
 	}
 
-	dx, err := status.MediaWidth.Dx()
+	if flags.colors.color == pt700.TapeNone {
+		flags.colors.color = status.TapeColor
+	}
+	if flags.colors.text == pt700.TextNone {
+		flags.colors.text = status.TextColor
+	}
+
+	opts := pt700.PrintOpts{
+		Timeouts: pt700.Timeouts{Status: flags.statusTO},
+	}
+	// The calibration ladder is drawn shifted itself.
+	if flags.calib == nil {
+		opts.PinOffset = tapes[printerName].PinOffset
+	}
+	if isTerminal(os.Stderr) {
+		opts.Progress = func(page, totalPages int, phase string) {
+			fmt.Fprintf(os.Stderr, "\r\033[KLabel %d/%d: %s", page+1, totalPages, phase)
+			if page == totalPages-1 && phase == pt700.ProgressPrinted {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+
+	if flags.rawFile != "" {
+		job, err := os.ReadFile(flags.rawFile)
+		if err != nil {
+			return err
+		}
+
+		res, err := printer.PrintRaw(opts, job)
+		logResult(flags.logger, res)
+		if err != nil && opts.Progress != nil {
+			fmt.Fprintln(os.Stderr)
+		}
+		recordTape(func(m *media.Media) { m.Used += res.TapeUsed })
+		return err
+	}
+
+	imgs, err := render(status.MediaWidth, labels, flags)
 	if err != nil {
 		return err
 	}
-	bounds := etiquette.Bounds{
-		Dx:    dx,
-		MinDy: status.MediaWidth.MinDy(),
+
+	if done, err := report(status.MediaWidth, imgs, flags); done {
+		return err
 	}
 
-	var imgs []*monochrome.Image
-	if flags.img {
-		imgs, err = img(bounds, labels)
-	} else {
-		imgs, err = text(bounds, status.MediaWidth.DPI(), labels)
+	length := pt700.JobLength(imgs...)
+	if remainingOk && remaining < length {
+		fmt.Fprintf(os.Stderr, "Warning: job needs %.0fmm of tape but only ~%.0fmm remaining\n", length, remaining)
 	}
+
+	// Pages left to print, and the tape used on the current cassette.
+	pending, used := imgs, 0.0
+	for {
+		res, err := printer.Print(opts, pending...)
+		used += res.TapeUsed
+		logResult(flags.logger, res)
+		if err == nil {
+			break
+		}
+
+		// Don't leave the error on the same line as the progress.
+		if opts.Progress != nil {
+			fmt.Fprintln(os.Stderr)
+		}
+
+		// Offer to load a new cassette if the printer ran out of tape.
+		var printErr *pt700.PrintError
+		retry := errors.As(err, &printErr) && outOfTape(err) &&
+			confirm(fmt.Sprintf("Ran out of tape, %d of %d labels printed. Load a new cassette and press Enter to print the rest", len(imgs)-len(pending)+printErr.Printed, len(imgs)))
+		if !retry {
+			// The cassette is still loaded, with the tape used until it failed.
+			recordTape(func(m *media.Media) { m.Used += used })
+			return err
+		}
+
+		// The new cassette only has the pages printed since it was loaded.
+		recordTape(func(m *media.Media) { m.Used = 0 })
+		pending, used = pending[printErr.Printed:], 0
+	}
+
+	recordTape(func(m *media.Media) { m.Used += used })
+	return nil
+}
+
+func logResult(logger *slog.Logger, res pt700.PrintResult) {
+	if logger != nil {
+		logger.Info("print result", "pages", res.Pages, "lines", res.Lines, "bytes", res.Bytes,
+			"phases", res.Phases, "duration", res.Duration, "tape_mm", res.TapeUsed)
+	}
+}
+
+// outOfTape reports if err is because the printer ran out of tape.
+func outOfTape(err error) bool {
+	var err1 pt700.Error1
+	var err2 pt700.Error2
+	return (errors.As(err, &err1) && err1&pt700.Err1NoMedia != 0) ||
+		(errors.As(err, &err2) && err2&pt700.Err2ReplaceMedia != 0)
+}
+
+// confirm asks the user to press Enter in the terminal, stdin has the labels.
+// It returns false if there's no terminal.
+func confirm(prompt string) bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil || !isTerminal(tty) {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(os.Stderr, "%s, or Ctrl-C to stop: ", prompt)
+	_, err = bufio.NewReader(tty).ReadString('\n')
+	return err == nil
+}
+
+// parseTestPage parses the arguments of the test-page command, and returns the remaining arguments.
+func parseTestPage(args []string) ([]string, error) {
+	fs := newCommand("test-page", "[/dev/usb/lpN|serial:XXXX]",
+		"Print a calibration pattern, to check the alignment and density of the printer and tape:\nrules, pin index marks, a mm ruler and checkerboards.")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+// findPrinter returns the path of the printer called name,
+// or of the only connected printer if name is empty.
+// The name returned identifies the printer across runs.
+func findPrinter(name string) (string, string, error) {
+	if name != "" {
+		path, err := usblp.Find(name)
+		if err != nil {
+			return "", "", err
+		}
+		return usblp.Name(path), path, nil
+	}
+
+	descs, warnings, err := pt700.Discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	switch len(descs) {
+	case 0:
+		// It could be one of the devices we couldn't read.
+		// Or a newer model with a product ID we don't know yet, it can still be picked by path or serial.
+		return "", "", errors.Join(append([]error{errors.New("no PT-700 printer connected, printers that aren't recognized can still be used by passing their path or serial from -list")}, warnings...)...)
+	case 1:
+		name := descs[0].Path
+		if descs[0].Serial != "" {
+			name = "serial:" + descs[0].Serial
+		}
+		return name, descs[0].Path, nil
+	default:
+		return "", "", fmt.Errorf("%d PT-700 printers connected, pick one with -list", len(descs))
+	}
+}
+
+func listPrinters() error {
+	devices, warnings, err := usblp.Connected()
 	if err != nil {
 		return err
 	}
 
-	if flags.preview != "" {
-		if len(imgs) != 1 {
-			return fmt.Errorf("preview only supported with a single label")
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", warning)
+	}
+
+	for _, device := range devices {
+		fmt.Printf("%s\t%v\n", device.Path, device.DeviceInfo)
+	}
+
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	_, err := unix.IoctlGetTermios(int(f.Fd()), unix.TCGETS)
+	return err == nil
+}
+
+// recordedWidth returns the width of the tape last recorded with -cassette for the printer called name,
+// without opening it.
+func recordedWidth(name string) (pt700.MediaWidth, error) {
+	name, _, err := findPrinter(name)
+	if err != nil {
+		return 0, err
+	}
+
+	tapes, err := media.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	width := tapes[name].Width
+	if width == 0 {
+		return 0, fmt.Errorf("no tape recorded for %s with -cassette, pass its width with -tape", name)
+	}
+	return width, nil
+}
+
+// tapeWidth converts a tape width in mm to a MediaWidth.
+func tapeWidth(mm float64) (pt700.MediaWidth, error) {
+	// 3.5mm is the only width that isn't a whole number of mm.
+	if mm != math.Trunc(mm) && mm != 3.5 {
+		return 0, fmt.Errorf("%vmm tape: unsupported width", mm)
+	}
+
+	width := pt700.MediaWidth(mm)
+	if mm == 3.5 {
+		width = pt700.Width3_5
+	}
+
+	if _, err := width.Dx(); err != nil {
+		return 0, fmt.Errorf("%vmm tape: %w", mm, err)
+	}
+	return width, nil
+}
+
+// render the labels for the given tape width.
+func render(width pt700.MediaWidth, labels io.Reader, flags flags) ([]*monochrome.Image, error) {
+	mediaBounds, err := etiquette.MediaBounds(width)
+	if err != nil {
+		return nil, err
+	}
+
+	// Planned labels are only padded to the minimum length once they're put together.
+	tapeBounds := mediaBounds
+	if flags.plan {
+		tapeBounds.MinDy = 0
+	}
+
+	// Labels are rendered as narrow as they are printed side by side.
+	bounds, err := tapeBounds.Gang(flags.nUp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same time for all the labels in the job.
+	now := time.Now()
+
+	textOpts := etiquette.TextOpts{
+		Size:   flags.size,
+		Margin: flags.margin,
+		Length: flags.length,
+		Align:  flags.align,
+		Style:  flags.style,
+		Frame:  flags.frame,
+
+		Decoration:    flags.deco,
+		LetterSpacing: flags.spacing,
+		Hinting:       flags.hinting,
+		Supersample:   flags.supersmp,
+		MaxLength:     flags.maxLen,
+		Condense:      flags.condense,
+		Transliterate: flags.translit,
+	}
+
+	imageOpts := etiquette.ImageOpts{
+		Length:    flags.length,
+		Align:     flags.align,
+		Trim:      flags.trim,
+		Levels:    flags.levels,
+		Fit:       flags.fit,
+		Filter:    flags.filter,
+		Sharpen:   flags.sharpen,
+		Despeckle: flags.speckle,
+		Dilate:    flags.dilate,
+		Frame:     flags.frame,
+	}
+
+	var imgs []*monochrome.Image
+	switch {
+	case flags.img:
+		imgs, err = img(bounds, labels, imageOpts)
+	case flags.zpl:
+		imgs, err = zplLabels(bounds, labels, flags.logger, imageOpts)
+	case flags.escpos:
+		imgs, err = escposLabels(bounds, labels, flags.logger, imageOpts)
+	case flags.jsonl:
+		imgs, err = jsonl(bounds, labels, now, flags.logger, textOpts)
+	case flags.kv:
+		imgs, err = kv(bounds, labels, now, flags.logger, textOpts)
+	case flags.csv != "":
+		imgs, err = csvLabels(bounds, labels, flags.csv, now, flags.logger, textOpts)
+	case flags.testPage:
+		var img *monochrome.Image
+		img, err = etiquette.TestPage(bounds)
+		imgs = []*monochrome.Image{img}
+	case flags.calib != nil:
+		var r *etiquette.Renderer
+		r, err = client.DefaultRenderer(flags.logger)
+		if err != nil {
+			return nil, err
+		}
+
+		var img *monochrome.Image
+		img, err = flags.calib.ladder(r, bounds)
+		imgs = []*monochrome.Image{img}
+	case flags.qr != nil:
+		imgs, err = flags.qr.render(bounds, flags.logger, textOpts)
+	case flags.barcode != "":
+		imgs, err = barcodes(bounds, flags.barcode, labels, etiquette.BarcodeOpts{
+			Module: flags.module,
+			Length: flags.length,
+			Align:  flags.align,
+			Frame:  flags.frame,
+		})
+	default:
+		split := bufio.ScanLines
+		switch {
+		case flags.nul:
+			split = scanNUL
+		case flags.raw:
+			split = scanAll
 		}
 
-		preview, err := os.Create(flags.preview)
+		layout := (*etiquette.Renderer).Text
+		switch {
+		case flags.cable != 0:
+			layout = func(r *etiquette.Renderer, b etiquette.Bounds, text string, opts etiquette.TextOpts) (*monochrome.Image, error) {
+				return r.CableFlag(b, text, flags.cable, opts)
+			}
+		case flags.wrap != 0:
+			layout = func(r *etiquette.Renderer, b etiquette.Bounds, text string, opts etiquette.TextOpts) (*monochrome.Image, error) {
+				return r.CableWrap(b, text, flags.wrap, opts)
+			}
+		case flags.iconImg != nil:
+			layout = func(r *etiquette.Renderer, b etiquette.Bounds, text string, opts etiquette.TextOpts) (*monochrome.Image, error) {
+				return r.WithImage(b, flags.iconImg, text, opts)
+			}
+		case flags.icon != etiquette.IconNone:
+			layout = func(r *etiquette.Renderer, b etiquette.Bounds, text string, opts etiquette.TextOpts) (*monochrome.Image, error) {
+				return r.WithIcon(b, flags.icon, text, opts)
+			}
+		}
+
+		imgs, err = text(bounds, labels, split, flags.seq, now, flags.logger, layout, textOpts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch flags.unique {
+	case "":
+	case "warn", "drop":
+		imgs = duplicates(imgs, flags.unique == "drop")
+	default:
+		return nil, fmt.Errorf("unknown -unique %q, expected warn or drop", flags.unique)
+	}
+
+	if flags.split {
+		var split []*monochrome.Image
+		for _, img := range imgs {
+			pages, err := etiquette.Split(bounds, img, etiquette.SplitOpts{
+				Overlap: flags.overlap,
+				Marker:  true,
+			})
+			if err != nil {
+				return nil, err
+			}
+			split = append(split, pages...)
+		}
+		imgs = split
+	}
+
+	imgs, err = etiquette.Gang(tapeBounds, flags.nUp, imgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if flags.plan {
+		plan, err := etiquette.PlanJob(mediaBounds, imgs, etiquette.PlanOpts{
+			MaxLength: flags.planLen,
+			Reorder:   flags.reorder,
+		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		return png.Encode(preview, imgs[0])
+		fmt.Fprintf(os.Stderr, "%d labels on %d pages: %.1fmm of tape and %d cuts, instead of %.1fmm and %d cuts on a page each, or %.1fmm printing them one at a time.\n",
+			len(imgs), len(plan.Pages), plan.Length, plan.Cuts(), plan.Unmerged, len(imgs), plan.Separate)
+		if flags.logger != nil {
+			for p, labels := range plan.Labels {
+				flags.logger.Debug("planned page", "page", p, "labels", labels)
+			}
+		}
+
+		imgs = plan.Pages
+	}
+
+	if flags.flip {
+		for i, img := range imgs {
+			imgs[i] = img.Rotate180()
+		}
 	}
 
-	return printer.Print(imgs...)
+	return imgs, nil
 }
 
-func img(b etiquette.Bounds, labels io.Reader) ([]*monochrome.Image, error) {
-	img, _, err := image.Decode(labels)
+// duplicates warns about labels identical to the one before them, and drops them if drop is set.
+func duplicates(imgs []*monochrome.Image, drop bool) []*monochrome.Image {
+	var unique []*monochrome.Image
+	for i, img := range imgs {
+		if i == 0 || !img.Equal(imgs[i-1]) {
+			unique = append(unique, img)
+			continue
+		}
+
+		if drop {
+			fmt.Fprintf(os.Stderr, "Warning: label %d is the same as label %d, not printing it\n", i, i-1)
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: label %d is the same as label %d\n", i, i-1)
+			unique = append(unique, img)
+		}
+	}
+	return unique
+}
+
+// report handles the flags that replace printing the labels.
+// done is true if the labels shouldn't be printed.
+func report(width pt700.MediaWidth, imgs []*monochrome.Image, flags flags) (done bool, err error) {
+	// Show what will be printed, the logger would escape the newlines.
+	if flags.logger != nil && flags.logger.Enabled(context.Background(), slog.LevelDebug) {
+		for i, img := range imgs {
+			fmt.Fprintf(os.Stderr, "label %d:\n", i)
+			img.Dump(os.Stderr)
+		}
+	}
+
+	switch {
+	case flags.preview != "":
+		colors := flags.colors
+		colors.width = width
+		return true, preview(flags.preview, imgs, colors)
+
+	case flags.termPrev:
+		return true, previewTerm(os.Stdout, imgs)
+
+	case flags.save != nil:
+		colors := flags.colors
+		colors.width = width
+		return true, flags.save.save(imgs, colors)
+
+	case flags.encode != "":
+		job, err := pt700.Encode(width, pt700.PrintOpts{}, imgs...)
+		if err != nil {
+			return true, err
+		}
+		return true, os.WriteFile(flags.encode, job, 0o644)
+
+	case flags.estimate:
+		fmt.Printf("%d labels, %.1fmm of tape\n", len(imgs), pt700.JobLength(imgs...))
+		return true, nil
+
+	case flags.dryRun:
+		bounds, err := etiquette.MediaBounds(width)
+		if err != nil {
+			return true, err
+		}
+
+		if err := etiquette.Validate(bounds, imgs...); err != nil {
+			return true, err
+		}
+
+		fmt.Printf("%d labels OK for %v tape, %.1fmm of tape\n", len(imgs), width, pt700.JobLength(imgs...))
+		return true, nil
+
+	default:
+		return false, nil
+	}
+}
+
+func img(b etiquette.Bounds, labels io.Reader, opts etiquette.ImageOpts) ([]*monochrome.Image, error) {
+	// PWG raster and URF streams from print dialogs have a label per page.
+	r := bufio.NewReader(labels)
+	if prefix, _ := r.Peek(8); pwg.IsRaster(prefix) {
+		pages, err := pwg.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var monos []*monochrome.Image
+		for i, page := range pages {
+			mono, err := etiquette.Raster(b, page, opts)
+			if err != nil {
+				return nil, fmt.Errorf("page %d: %w", i, err)
+			}
+			monos = append(monos, mono)
+		}
+		return monos, nil
+	}
+
+	img, _, err := image.Decode(r)
 	if err != nil {
 		return nil, err
 	}
 
-	mono, err := etiquette.Image(b, img)
+	mono, err := etiquette.Image(b, img, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -126,8 +1108,47 @@ func img(b etiquette.Bounds, labels io.Reader) ([]*monochrome.Image, error) {
 	return []*monochrome.Image{mono}, nil
 }
 
-func text(b etiquette.Bounds, dpi int, labels io.Reader) ([]*monochrome.Image, error) {
-	ft, err := opentype.Parse(goregular.TTF)
+// parseLevels parses key=value image levels, separated by spaces or commas.
+func parseLevels(s string) (etiquette.Levels, error) {
+	var levels etiquette.Levels
+	for _, field := range strings.FieldsFunc(s, func(c rune) bool { return c == ' ' || c == ',' }) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return etiquette.Levels{}, fmt.Errorf("levels: expected key=value, got %q", field)
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return etiquette.Levels{}, fmt.Errorf("levels: %s: %w", key, err)
+		}
+
+		switch key {
+		case "gamma":
+			if v <= 0 {
+				return etiquette.Levels{}, fmt.Errorf("levels: gamma must be positive")
+			}
+			levels.Gamma = v
+		case "brightness":
+			levels.Brightness = v
+		case "contrast":
+			levels.Contrast = v
+		default:
+			return etiquette.Levels{}, fmt.Errorf("levels: unknown key %q, expected gamma, brightness or contrast", key)
+		}
+	}
+
+	return levels, nil
+}
+
+// maxLabelText is the longest text of a label that is read.
+// Much more than bufio.Scanner's default of 64KiB, as -raw reads all of the input as one label.
+const maxLabelText = 16 << 20
+
+// layout renders the text of a label.
+type layout func(r *etiquette.Renderer, b etiquette.Bounds, text string, opts etiquette.TextOpts) (*monochrome.Image, error)
+
+func text(b etiquette.Bounds, labels io.Reader, split bufio.SplitFunc, seq *sequence, now time.Time, logger *slog.Logger, layout layout, opts etiquette.TextOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
 	if err != nil {
 		return nil, err
 	}
@@ -135,17 +1156,51 @@ func text(b etiquette.Bounds, dpi int, labels io.Reader) ([]*monochrome.Image, e
 	var imgs []*monochrome.Image
 
 	scanner := bufio.NewScanner(labels)
+	scanner.Buffer(nil, maxLabelText)
+	scanner.Split(split)
 	for scanner.Scan() {
-		img, err := etiquette.Text(b, scanner.Text(), etiquette.TextOpts{
-			Font: ft,
-			DPI:  dpi,
-		})
+		texts, err := expand(scanner.Text(), seq, now)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("label %d: %w", len(imgs), err)
 		}
 
-		imgs = append(imgs, img)
+		for _, text := range texts {
+			img, err := layout(r, b, text, opts)
+			if err != nil {
+				return nil, fmt.Errorf("label %d: %w", len(imgs), err)
+			}
+
+			imgs = append(imgs, img)
+		}
 	}
 
-	return imgs, nil
+	return imgs, scanner.Err()
+}
+
+// scanNUL is bufio.ScanLines, but for NUL delimited labels.
+func scanNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	// Request more data.
+	return 0, nil, nil
+}
+
+// scanAll returns all of the input as one label, without a trailing newline.
+func scanAll(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if !atEOF {
+		// Request more data.
+		return 0, nil, nil
+	}
+
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	return len(data), bytes.TrimSuffix(data, []byte("\n")), nil
 }
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"go.afab.re/etiquette/pt700"
+)
+
+func TestTapeWidth(t *testing.T) {
+	for _, test := range []struct {
+		mm    float64
+		width pt700.MediaWidth
+		fail  bool
+	}{
+		{mm: 3.5, width: pt700.Width3_5},
+		{mm: 6, width: pt700.Width6},
+		{mm: 12, width: pt700.Width12},
+		{mm: 24, width: pt700.Width24},
+		{mm: 12.7, fail: true},
+		{mm: 24.9, fail: true},
+		{mm: 3, fail: true},
+		{mm: 36, fail: true},
+	} {
+		width, err := tapeWidth(test.mm)
+		if (err != nil) != test.fail {
+			t.Errorf("%vmm: got error %v, want error %v", test.mm, err, test.fail)
+			continue
+		}
+		if width != test.width {
+			t.Errorf("%vmm: got %v, want %v", test.mm, width, test.width)
+		}
+	}
+}
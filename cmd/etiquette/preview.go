@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/pt700"
+)
+
+// tapeLook is the tape labels are printed on, to make previews look like it.
+type tapeLook struct {
+	width pt700.MediaWidth
+	// If either is unknown, previews are black on white and only show the print area.
+	color pt700.TapeColor
+	text  pt700.TextColor
+	// corners is the radius of the corners of die-cut labels in mm, or 0 for continuous tape.
+	// Printers don't report die-cut labels, it's set with -preview-corners.
+	corners float64
+}
+
+// paletted converts a label to how it looks printed on the tape:
+// in its colors, with the blank edges of the tape either side of the print area,
+// and with rounded corners if it's a die-cut label.
+func (t tapeLook) paletted(img *monochrome.Image) *image.Paletted {
+	b := img.Bounds()
+	if t.color == pt700.TapeNone || t.text == pt700.TextNone {
+		dst := image.NewPaletted(b.Sub(b.Min), t.palette(monochrome.Model()))
+		draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+		t.round(dst)
+		return dst
+	}
+
+	mm := float64(t.width)
+	if t.width == pt700.Width3_5 {
+		mm = 3.5
+	}
+	edge := max(0, (int(math.Round(mm*float64(t.width.DPI())/25.4))-b.Dx())/2)
+
+	// Same indices as monochrome images: tape, then text.
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx()+2*edge, b.Dy()), t.palette(color.Palette{t.color.RGBA(), t.text.RGBA()}))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.SetColorIndex(edge+x, y, img.ColorIndexAt(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	t.round(dst)
+	return dst
+}
+
+// transparent is the index of the transparent color of previews, cut off the corners of die-cut labels.
+const transparent = 2
+
+// palette adds the transparent color to the tape and text colors, if t has rounded corners.
+func (t tapeLook) palette(p color.Palette) color.Palette {
+	if t.corners <= 0 {
+		return p
+	}
+	return append(p, color.Transparent)
+}
+
+// round cuts the corners of a die-cut label off dst.
+func (t tapeLook) round(dst *image.Paletted) {
+	if t.corners <= 0 {
+		return
+	}
+
+	b := dst.Bounds()
+	r := min(t.corners*float64(t.width.DPI())/25.4, float64(b.Dx())/2, float64(b.Dy())/2)
+	for y := 0; y < int(math.Ceil(r)); y++ {
+		for x := 0; x < int(math.Ceil(r)); x++ {
+			// Distance from the center of the pixel to the center of the corner.
+			dx, dy := r-(float64(x)+0.5), r-(float64(y)+0.5)
+			if dx*dx+dy*dy <= r*r {
+				continue
+			}
+			dst.SetColorIndex(b.Min.X+x, b.Min.Y+y, transparent)
+			dst.SetColorIndex(b.Max.X-1-x, b.Min.Y+y, transparent)
+			dst.SetColorIndex(b.Min.X+x, b.Max.Y-1-y, transparent)
+			dst.SetColorIndex(b.Max.X-1-x, b.Max.Y-1-y, transparent)
+		}
+	}
+}
+
+// preview writes labels as a PNG to path, or stdout if it is "-".
+// Several labels can be written as numbered PNGs to a directory if path ends with a /,
+// or as the frames of an animated GIF if path ends with .gif.
+func preview(path string, labels []*monochrome.Image, t tapeLook) error {
+	var imgs []*image.Paletted
+	for _, label := range labels {
+		imgs = append(imgs, t.paletted(label))
+	}
+
+	switch {
+	case strings.HasSuffix(path, "/"):
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return err
+		}
+
+		for i, img := range imgs {
+			if err := writeFile(filepath.Join(path, fmt.Sprintf("label-%03d.png", i+1)), func(w io.Writer) error {
+				return png.Encode(w, img)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case strings.HasSuffix(path, ".gif"):
+		return writeFile(path, func(w io.Writer) error {
+			return gif.EncodeAll(w, frames(imgs))
+		})
+
+	case len(imgs) != 1:
+		return fmt.Errorf("preview of %d labels needs a directory ending with / or a .gif file", len(imgs))
+
+	case path == "-":
+		return png.Encode(os.Stdout, imgs[0])
+
+	default:
+		return writeFile(path, func(w io.Writer) error {
+			return png.Encode(w, imgs[0])
+		})
+	}
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// frames converts labels to GIF frames shown one second each.
+// Frames are all as big as the longest label, shorter labels are padded with the tape color.
+func frames(imgs []*image.Paletted) *gif.GIF {
+	var size image.Point
+	for _, img := range imgs {
+		size.X = max(size.X, img.Bounds().Dx())
+		size.Y = max(size.Y, img.Bounds().Dy())
+	}
+
+	anim := &gif.GIF{
+		Config: image.Config{
+			ColorModel: imgs[0].Palette,
+			Width:      size.X,
+			Height:     size.Y,
+		},
+	}
+
+	for _, img := range imgs {
+		// The first color of the palette is the tape.
+		frame := image.NewPaletted(image.Rectangle{Max: size}, img.Palette)
+		draw.Draw(frame, img.Bounds(), img, image.Point{}, draw.Src)
+
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 100)
+	}
+
+	return anim
+}
+
+// readable rotates a label so it reads left to right, with the start of the label on the left.
+func readable(img *monochrome.Image) *image.Gray {
+	return monochrome.Gray(img.Rotate90())
+}
+
+// previewTerm draws labels in the terminal, with the kitty graphics protocol if the terminal supports it,
+// and with Unicode half blocks otherwise.
+func previewTerm(w io.Writer, imgs []*monochrome.Image) error {
+	out := bufio.NewWriter(w)
+
+	for i, img := range imgs {
+		if i != 0 {
+			fmt.Fprintln(out)
+		}
+
+		var err error
+		if isKitty() {
+			err = kitty(out, readable(img))
+		} else {
+			err = halfBlocks(out, readable(img), termColumns(os.Stdout))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+func isKitty() bool {
+	return os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("TERM") == "xterm-kitty"
+}
+
+// termColumns returns the width of the terminal f is, or 0 if it isn't one.
+func termColumns(f *os.File) int {
+	size, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(size.Col)
+}
+
+// kitty draws img as a PNG using the kitty graphics protocol.
+func kitty(w io.Writer, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	data := base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	// Escape codes are limited to 4096 bytes of data.
+	const chunk = 4096
+	for i := 0; i < len(data); i += chunk {
+		end := min(i+chunk, len(data))
+
+		more := 0
+		if end != len(data) {
+			more = 1
+		}
+
+		control := fmt.Sprintf("m=%d", more)
+		if i == 0 {
+			control = "a=T,f=100," + control
+		}
+
+		if _, err := fmt.Fprintf(w, "\x1b_G%s;%s\x1b\\", control, data[i:end]); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// halfBlocks draws img with two pixels per character, scaling it down to fit in columns if it's not 0.
+// Black pixels are drawn with the foreground color of the terminal.
+func halfBlocks(w io.Writer, img *image.Gray, columns int) error {
+	b := img.Bounds()
+
+	// Each character is a square of scale pixels, a character cell is twice as tall as it is wide.
+	scale := 1
+	if columns > 0 && b.Dx() > columns {
+		scale = (b.Dx() + columns - 1) / columns
+	}
+
+	// black reports if any pixel in the scale x scale square at x, y is black.
+	black := func(x, y int) bool {
+		for dy := 0; dy < scale; dy++ {
+			for dx := 0; dx < scale; dx++ {
+				p := image.Pt(x+dx, y+dy)
+				if p.In(b) && img.GrayAt(p.X, p.Y).Y < 0x80 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	blocks := [2][2]string{
+		{" ", "▄"},
+		{"▀", "█"},
+	}
+
+	for y := b.Min.Y; y < b.Max.Y; y += 2 * scale {
+		for x := b.Min.X; x < b.Max.X; x += scale {
+			top, bottom := 0, 0
+			if black(x, y) {
+				top = 1
+			}
+			if black(x, y+scale) {
+				bottom = 1
+			}
+			if _, err := io.WriteString(w, blocks[top][bottom]); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"image/png"
+	"os"
+	"unicode/utf8"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// project is a label saved with -save-project, to print it again with -project.
+type project struct {
+	// Args are the flags the labels were rendered with, the command line overrides them.
+	Args []string `json:"args,omitempty"`
+	// Labels read from stdin if they are text, otherwise Data.
+	Labels string `json:"labels,omitempty"`
+	Data   []byte `json:"data,omitempty"`
+	// Icon is the image of -icon-file, so the project doesn't depend on the file.
+	Icon []byte `json:"icon,omitempty"`
+	// Previews are PNGs of each label as they were rendered when the project was saved.
+	Previews [][]byte `json:"previews,omitempty"`
+
+	// Where to save the project.
+	path string
+}
+
+// Flags saved in projects: how the labels are read from stdin and rendered.
+// Flags that pick what to do with the labels, the printer, or logging aren't saved.
+// The image of -icon-file is saved in Icon instead of its path.
+var projectFlags = map[string]bool{
+	// Input.
+	"img":      true,
+	"0":        true,
+	"raw":      true,
+	"jsonl":    true,
+	"barcode":  true,
+	"sequence": true,
+	"csv":      true,
+	"kv":       true,
+	"zpl":      true,
+	"escpos":   true,
+	"unique":   true,
+
+	// Rendering.
+	"module":         true,
+	"cable-flag":     true,
+	"cable-wrap":     true,
+	"length":         true,
+	"size":           true,
+	"margin":         true,
+	"bold":           true,
+	"italic":         true,
+	"underline":      true,
+	"strikethrough":  true,
+	"box":            true,
+	"letter-spacing": true,
+	"hinting":        true,
+	"supersample":    true,
+	"max-length":     true,
+	"condense":       true,
+	"transliterate":  true,
+	"icon":           true,
+	"align":          true,
+	"trim":           true,
+	"fit":            true,
+	"filter":         true,
+	"sharpen":        true,
+	"despeckle":      true,
+	"dilate":         true,
+	"levels":         true,
+	"frame":          true,
+	"frame-radius":   true,
+	"split":          true,
+	"split-overlap":  true,
+	"n-up":           true,
+	"plan":           true,
+	"plan-length":    true,
+	"reorder":        true,
+	"flip":           true,
+}
+
+// newProject records the flags set on the command line, the labels, and the image of -icon-file, to save them to path.
+func newProject(path string, labels, icon []byte) *project {
+	p := project{path: path, Icon: icon}
+	flag.Visit(func(f *flag.Flag) {
+		if projectFlags[f.Name] {
+			p.Args = append(p.Args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+
+	if utf8.Valid(labels) {
+		p.Labels = string(labels)
+	} else {
+		p.Data = labels
+	}
+
+	return &p
+}
+
+func loadProject(path string) (*project, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p project
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// labels returns the saved labels, to use instead of stdin.
+func (p *project) labels() []byte {
+	if p.Data != nil {
+		return p.Data
+	}
+	return []byte(p.Labels)
+}
+
+// save p, with previews of the rendered labels.
+func (p *project) save(imgs []*monochrome.Image, t tapeLook) error {
+	p.Previews = nil
+	for _, img := range imgs {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, t.paletted(img)); err != nil {
+			return err
+		}
+		p.Previews = append(p.Previews, buf.Bytes())
+	}
+
+	raw, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, append(raw, '\n'), 0o644)
+}
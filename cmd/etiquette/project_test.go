@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectIcon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.json")
+	icon := []byte("\x89PNG not really")
+
+	if err := newProject(path, []byte("Label"), icon).save(nil, tapeLook{}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := loadProject(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(p.labels()) != "Label" {
+		t.Errorf("got labels %q, want %q", p.labels(), "Label")
+	}
+	if !bytes.Equal(p.Icon, icon) {
+		t.Errorf("got icon %q, want %q", p.Icon, icon)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/barcode"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// qrLabel is a QR code label built by the qr command.
+type qrLabel struct {
+	payload string
+	// Printed beside the QR code, if set.
+	caption string
+	// If set, a label is printed for each number, replacing {n} in the payload and caption with it.
+	numbers []string
+}
+
+// parseQR parses the arguments of the qr command, and returns the remaining arguments.
+func parseQR(args []string) (*qrLabel, []string, error) {
+	if len(args) == 0 {
+		return nil, nil, fmt.Errorf("expected the type of QR code: wifi, contact or asset")
+	}
+
+	switch args[0] {
+	case "wifi":
+		fs := newCommand("qr wifi", "-ssid SSID -pass PASSWORD [qr wifi options] [/dev/usb/lpN|serial:XXXX]",
+			"Print a QR code to join a WiFi network.")
+
+		var (
+			ssid     = fs.String("ssid", "", "Name of the WiFi network.")
+			pass     = fs.String("pass", "", "Password of the WiFi network.")
+			security = fs.String("security", "", "WPA, WEP or nopass. Defaults to WPA, or nopass if there's no password.")
+			hidden   = fs.Bool("hidden", false, "The network doesn't broadcast its SSID.")
+			caption  = fs.Bool("caption", true, "Print the SSID beside the QR code.")
+		)
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, nil, err
+		}
+
+		payload, err := barcode.WiFi{
+			SSID:     *ssid,
+			Password: *pass,
+			Security: *security,
+			Hidden:   *hidden,
+		}.Payload()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		label := &qrLabel{payload: payload}
+		if *caption {
+			label.caption = *ssid
+		}
+		return label, fs.Args(), nil
+
+	case "contact":
+		fs := newCommand("qr contact", "-name NAME -phone PHONE -email EMAIL [qr contact options] [/dev/usb/lpN|serial:XXXX]",
+			"Print a QR code with contact details.")
+
+		var (
+			name    = fs.String("name", "", "Name of the contact.")
+			phone   = fs.String("phone", "", "Phone number of the contact.")
+			email   = fs.String("email", "", "Email address of the contact.")
+			vcard   = fs.Bool("vcard", false, "Encode the contact as a vCard, which more phones understand, instead of a smaller MECARD.")
+			caption = fs.Bool("caption", true, "Print the contact details beside the QR code.")
+		)
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, nil, err
+		}
+
+		contact := barcode.Contact{
+			Name:  *name,
+			Phone: *phone,
+			Email: *email,
+		}
+
+		encode := contact.MECARD
+		if *vcard {
+			encode = contact.VCard
+		}
+		payload, err := encode()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		label := &qrLabel{payload: payload}
+		if *caption {
+			// One line per detail.
+			var lines []string
+			for _, line := range []string{*name, *phone, *email} {
+				if line != "" {
+					lines = append(lines, line)
+				}
+			}
+			label.caption = strings.Join(lines, "\n")
+		}
+		return label, fs.Args(), nil
+
+	case "asset":
+		fs := newCommand("qr asset", "-url URL -range FIRST..LAST [qr asset options] [/dev/usb/lpN|serial:XXXX]",
+			"Print numbered asset tags, with a QR code linking to each asset beside its ID.")
+
+		var (
+			url     = fs.String("url", "", "URL of each asset, with {n} replaced by its number: https://inv.example/a/{n}.")
+			id      = fs.String("id", "ASSET-{n}", "ID of each asset printed beside the QR code, with {n} replaced by its number.")
+			numbers = fs.String("range", "", "Numbers of the assets: 00123..00150. Numbers are padded with zeros to the width of the range if either end starts with a 0.")
+		)
+		if err := fs.Parse(args[1:]); err != nil {
+			return nil, nil, err
+		}
+
+		if !strings.Contains(*url, "{n}") {
+			return nil, nil, fmt.Errorf("-url must have {n} to number the assets, got %q", *url)
+		}
+		if !strings.Contains(*id, "{n}") {
+			return nil, nil, fmt.Errorf("-id must have {n} to number the assets, got %q", *id)
+		}
+		if !rangePattern.MatchString("{" + *numbers + "}") {
+			return nil, nil, fmt.Errorf("-range must be like 00123..00150, got %q", *numbers)
+		}
+		expanded, err := expandRanges("{" + *numbers + "}")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &qrLabel{payload: *url, caption: *id, numbers: expanded}, fs.Args(), nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown type of QR code %q, expected wifi, contact or asset", args[0])
+	}
+}
+
+func (l qrLabel) render(b etiquette.Bounds, logger *slog.Logger, opts etiquette.TextOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.numbers == nil {
+		img, err := qr(r, b, l.payload, l.caption, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []*monochrome.Image{img}, nil
+	}
+
+	var imgs []*monochrome.Image
+	for _, n := range l.numbers {
+		img, err := qr(r, b, strings.ReplaceAll(l.payload, "{n}", n), strings.ReplaceAll(l.caption, "{n}", n), opts)
+		if err != nil {
+			return nil, fmt.Errorf("asset %s: %w", n, err)
+		}
+		imgs = append(imgs, img)
+	}
+	return imgs, nil
+}
+
+// qr renders a QR code, with an optional caption beside it.
+func qr(r *etiquette.Renderer, b etiquette.Bounds, payload, caption string, opts etiquette.TextOpts) (*monochrome.Image, error) {
+	code, err := barcode.QR([]byte(payload), barcode.QRLevelM)
+	if err != nil {
+		return nil, err
+	}
+
+	if caption == "" {
+		return etiquette.Barcode(b, code, etiquette.BarcodeOpts{
+			Length: opts.Length,
+			Align:  opts.Align,
+			Frame:  opts.Frame,
+		})
+	}
+	return r.Captioned(b, code, caption, opts)
+}
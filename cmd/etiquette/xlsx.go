@@ -0,0 +1,172 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxMagic starts .xlsx files, which are zip archives.
+var xlsxMagic = []byte("PK\x03\x04")
+
+// xlsxRows reads the cells of the first sheet of an .xlsx spreadsheet as text.
+// Empty cells are empty strings, and rows are as long as their last non empty cell.
+func xlsxRows(raw []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, err := xlsxFirstSheet(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared struct {
+		Strings []xlsxText `xml:"si"`
+	}
+	if err := xlsxDecode(archive, "xl/sharedStrings.xml", &shared); err != nil && !errors.Is(err, errXLSXMissing) {
+		return nil, err
+	}
+
+	var data struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xlsxDecode(archive, sheet, &data); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, r := range data.Rows {
+		var row []string
+		for _, c := range r.Cells {
+			col := len(row)
+			if c.Ref != "" {
+				col, err = xlsxColumn(c.Ref)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			var text string
+			switch c.Type {
+			case "s":
+				i, err := strconv.Atoi(c.Value)
+				if err != nil || i < 0 || i >= len(shared.Strings) {
+					return nil, fmt.Errorf("cell %s: invalid shared string %q", c.Ref, c.Value)
+				}
+				text = shared.Strings[i].String()
+			case "inlineStr":
+				text = c.Inline.String()
+			default:
+				text = c.Value
+			}
+
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = text
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// xlsxText is rich text, made up of runs of text.
+type xlsxText struct {
+	Text string   `xml:"t"`
+	Runs []string `xml:"r>t"`
+}
+
+func (t xlsxText) String() string {
+	return t.Text + strings.Join(t.Runs, "")
+}
+
+// xlsxFirstSheet returns the path of the first sheet in the archive.
+func xlsxFirstSheet(archive *zip.Reader) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xlsxDecode(archive, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("no sheets")
+	}
+
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xlsxDecode(archive, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Rels {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+
+		// Targets are relative to xl/, unless they're absolute.
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	return "", fmt.Errorf("no relationship for sheet %q", workbook.Sheets[0].ID)
+}
+
+var errXLSXMissing = errors.New("missing file")
+
+// xlsxDecode decodes the XML file name in the archive into v.
+func xlsxDecode(archive *zip.Reader, name string, v any) error {
+	f, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, errXLSXMissing)
+	}
+	defer f.Close()
+
+	if err := xml.NewDecoder(f).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Number of columns of a sheet, up to XFD.
+const xlsxMaxColumns = 16384
+
+// xlsxColumn returns the 0 indexed column of a cell reference like AB12.
+func xlsxColumn(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A') + 1
+		// Rows are as long as their last column, don't make huge ones.
+		if col > xlsxMaxColumns {
+			return 0, fmt.Errorf("cell reference %q is past the last column XFD", ref)
+		}
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestXLSXColumn(t *testing.T) {
+	for _, test := range []struct {
+		ref  string
+		col  int
+		fail bool
+	}{
+		{ref: "A1", col: 0},
+		{ref: "Z9", col: 25},
+		{ref: "AA10", col: 26},
+		{ref: "AB12", col: 27},
+		{ref: "XFD1048576", col: 16383},
+		{ref: "XFE1", fail: true},
+		{ref: "AAAAAAAAAAAAAAAA1", fail: true},
+		{ref: "12", fail: true},
+		{ref: "", fail: true},
+	} {
+		col, err := xlsxColumn(test.ref)
+		if (err != nil) != test.fail {
+			t.Errorf("%q: got error %v, want error %v", test.ref, err, test.fail)
+			continue
+		}
+		if col != test.col {
+			t.Errorf("%q: got column %d, want %d", test.ref, col, test.col)
+		}
+	}
+}
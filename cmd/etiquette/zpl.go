@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.afab.re/etiquette"
+	"go.afab.re/etiquette/client"
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/zpl"
+)
+
+// zplLabels renders labels read as ZPL.
+func zplLabels(b etiquette.Bounds, labels io.Reader, logger *slog.Logger, opts etiquette.ImageOpts) ([]*monochrome.Image, error) {
+	r, err := client.DefaultRenderer(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := zpl.Parse(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	var imgs []*monochrome.Image
+	for i, label := range parsed {
+		if len(label.Unsupported) != 0 {
+			fmt.Fprintf(os.Stderr, "Warning: label %d: ignoring unsupported ZPL commands %s\n", i, strings.Join(label.Unsupported, " "))
+		}
+
+		img, err := r.ZPL(b, label, opts)
+		if err != nil {
+			return nil, fmt.Errorf("label %d: %w", i, err)
+		}
+
+		for n := 0; n < label.Copies; n++ {
+			imgs = append(imgs, img)
+		}
+	}
+
+	return imgs, nil
+}
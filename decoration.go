@@ -0,0 +1,115 @@
+package etiquette
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/font"
+)
+
+// Decoration of text, drawn on each line.
+// Decorations can be combined: DecorationUnderline | DecorationBox.
+type Decoration int
+
+const (
+	DecorationUnderline Decoration = 1 << iota
+	DecorationStrikethrough
+	// DecorationBox draws a box around text.
+	DecorationBox
+)
+
+// decorations are the positions of decorations for a font face, relative to the baseline.
+// Positive values are below it.
+type decorations struct {
+	Decoration
+
+	// Top of the underline.
+	underline int
+	// Middle of the strikethrough.
+	strikethrough int
+	// Thickness of all the lines.
+	thickness int
+	// Top and bottom of the box.
+	top, bottom int
+}
+
+// decorations returns the positions of deco for a font, using the metrics the font suggests when
+// it has them.
+func (r *Renderer) decorations(deco Decoration, size float64, dpi int, style Style, face font.Face) decorations {
+	if deco == 0 {
+		return decorations{}
+	}
+
+	m := face.Metrics()
+
+	d := decorations{
+		Decoration: deco,
+		underline:  m.Descent.Round() / 2,
+		thickness:  max(1, m.Height.Round()/20),
+		top:        -m.Ascent.Ceil(),
+		bottom:     m.Descent.Ceil(),
+	}
+
+	f, _ := r.fontFor(style)
+	if post := f.PostTable(); post != nil && post.UnderlineThickness > 0 {
+		// Font units to pixels.
+		scale := size * float64(dpi) / 72 / float64(f.UnitsPerEm())
+
+		d.underline = int(math.Round(-float64(post.UnderlinePosition) * scale))
+		d.thickness = max(1, int(math.Round(float64(post.UnderlineThickness)*scale)))
+	}
+
+	// Lowercase letters are struck through their middle.
+	xHeight := m.XHeight.Round()
+	if xHeight == 0 {
+		xHeight = m.Ascent.Round() / 2
+	}
+	d.strikethrough = -xHeight / 2
+
+	return d
+}
+
+// padding is the space needed on each side of text to draw the decorations.
+func (d decorations) padding() int {
+	if d.Decoration&DecorationBox == 0 {
+		return 0
+	}
+	// Leave a gap as thick as the box lines between the box and the text.
+	return 2 * d.thickness
+}
+
+// draw the decorations for a line of text from x0 to x1 with a baseline at y.
+func (d decorations) draw(dst draw.Image, x0, x1, y int) {
+	line := func(r image.Rectangle) {
+		// Keep lines entirely in the image, instead of cutting them off.
+		bounds := dst.Bounds()
+		if r.Min.Y < bounds.Min.Y {
+			r = r.Add(image.Pt(0, bounds.Min.Y-r.Min.Y))
+		}
+		if r.Max.Y > bounds.Max.Y {
+			r = r.Sub(image.Pt(0, r.Max.Y-bounds.Max.Y))
+		}
+
+		draw.Draw(dst, r, &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	}
+
+	if d.Decoration&DecorationUnderline != 0 {
+		line(image.Rect(x0, y+d.underline, x1, y+d.underline+d.thickness))
+	}
+
+	if d.Decoration&DecorationStrikethrough != 0 {
+		top := y + d.strikethrough - d.thickness/2
+		line(image.Rect(x0, top, x1, top+d.thickness))
+	}
+
+	if d.Decoration&DecorationBox != 0 {
+		box := image.Rect(x0-d.padding(), y+d.top, x1+d.padding(), y+d.bottom).Intersect(dst.Bounds())
+
+		line(image.Rect(box.Min.X, box.Min.Y, box.Max.X, box.Min.Y+d.thickness))
+		line(image.Rect(box.Min.X, box.Max.Y-d.thickness, box.Max.X, box.Max.Y))
+		line(image.Rect(box.Min.X, box.Min.Y, box.Min.X+d.thickness, box.Max.Y))
+		line(image.Rect(box.Max.X-d.thickness, box.Min.Y, box.Max.X, box.Max.Y))
+	}
+}
@@ -0,0 +1,101 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"go.afab.re/etiquette/escpos"
+	"go.afab.re/etiquette/monochrome"
+)
+
+// ESCPOS renders a receipt parsed from ESC/POS as an image suitable for printing.
+// The receipt is drawn as wide as its widest line, like a receipt printer would,
+// then rotated if it's landscape, and shrunk if it's wider than the tape.
+// Text is rendered with the fonts of r, and returns the same errors as Text.
+func (r *Renderer) ESCPOS(b Bounds, receipt escpos.Receipt, opts ImageOpts) (*monochrome.Image, error) {
+	type line struct {
+		size   image.Point
+		ascent int
+		face   font.Face
+		text   string
+		raster *monochrome.Image
+		align  escpos.Align
+	}
+
+	var lines []line
+	var canvas image.Point
+	for i, l := range receipt.Lines {
+		ln := line{
+			raster: l.Raster,
+			align:  l.Align,
+		}
+
+		if l.Raster != nil {
+			ln.size = l.Raster.Bounds().Size()
+		} else {
+			style := StyleRegular
+			if l.Bold {
+				style = StyleBold
+			}
+
+			text, err := r.check(l.Text, TextOpts{Style: style})
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i, err)
+			}
+
+			// Line heights are the size of the em square.
+			face, err := r.face(float64(escpos.LineHeight*max(1, l.Scale))*72/escpos.DPI, escpos.DPI, style, HintingFull)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i, err)
+			}
+
+			ln.face, ln.text = face, text
+			ln.ascent = face.Metrics().Ascent.Ceil()
+			ln.size = image.Pt(font.MeasureString(face, text).Ceil(), linePitch(face))
+		}
+
+		lines = append(lines, ln)
+		canvas.X = max(canvas.X, ln.size.X)
+		canvas.Y += ln.size.Y
+	}
+
+	if canvas.X == 0 || canvas.Y == 0 {
+		return nil, fmt.Errorf("empty receipt")
+	}
+
+	dst := image.NewGray(image.Rectangle{Max: canvas})
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+
+	var y int
+	for _, ln := range lines {
+		var x int
+		switch ln.align {
+		case escpos.AlignCenter:
+			x = (canvas.X - ln.size.X) / 2
+		case escpos.AlignRight:
+			x = canvas.X - ln.size.X
+		}
+
+		if ln.raster != nil {
+			rect := image.Rectangle{image.Pt(x, y), image.Pt(x, y).Add(ln.size)}
+			draw.DrawMask(dst, rect, image.Black, image.Point{}, blackMask{ln.raster}, ln.raster.Bounds().Min, draw.Over)
+		} else {
+			d := font.Drawer{
+				Dst:  dst,
+				Src:  image.Black,
+				Face: ln.face,
+				Dot:  fixed.P(x, y+ln.ascent),
+			}
+			d.DrawString(ln.text)
+		}
+
+		y += ln.size.Y
+	}
+
+	return fitPage(b, dst, image.Pt(escpos.DPI, escpos.DPI), false, opts)
+}
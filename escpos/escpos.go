@@ -0,0 +1,463 @@
+// Package escpos parses a subset of ESC/POS, the commands of receipt printers,
+// so point of sale software can print labels.
+//
+// Supported commands are:
+//   - Text, in code page 437 or UTF-8, and LF to end lines.
+//   - ESC @, to reset the print modes.
+//   - ESC E, ESC ! and GS !, for bold and taller text.
+//   - ESC a, to align lines.
+//   - ESC d, to feed lines.
+//   - GS v 0, raster images.
+//   - GS V, to cut the paper, which ends a receipt.
+//
+// Other common commands, like those selecting code pages or opening cash drawers, are ignored,
+// and listed in the Unsupported commands of a receipt.
+package escpos
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// DPI is the resolution of ESC/POS raster images, that of most receipt printers.
+const DPI = 203
+
+// LineHeight is the height of lines of the default font, in dots.
+const LineHeight = 24
+
+// Receipt is what's printed between cuts.
+type Receipt struct {
+	Lines []Line
+
+	// Unsupported are the commands that were ignored, like ESC t, in the order they first appear.
+	Unsupported []string
+}
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Line is a line of text, or a raster image if Raster isn't nil.
+type Line struct {
+	Text string
+	Bold bool
+	// Scale is how many times taller than LineHeight the text is.
+	Scale int
+
+	Raster *monochrome.Image
+
+	Align Align
+}
+
+// Largest raster image decoded, in bytes, so a corrupt command can't use up all the memory.
+const maxRaster = 1 << 24
+
+// parser is the state of the receipt being parsed.
+type parser struct {
+	r       *bufio.Reader
+	receipt Receipt
+	ignored map[string]bool
+
+	text  []byte
+	bold  bool
+	scale int
+	align Align
+}
+
+// Parse parses the receipts of an ESC/POS stream.
+// Blank lines at the start and end of receipts are dropped, they're only used to feed the paper.
+func Parse(r io.Reader) ([]Receipt, error) {
+	p := &parser{
+		r:       bufio.NewReader(r),
+		ignored: make(map[string]bool),
+		scale:   1,
+	}
+
+	var receipts []Receipt
+	for {
+		cut, err := p.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("receipt %d: %w", len(receipts), err)
+		}
+
+		if cut {
+			if receipt, ok := p.end(); ok {
+				receipts = append(receipts, receipt)
+			}
+		}
+	}
+
+	if len(p.text) != 0 {
+		p.line()
+	}
+	if receipt, ok := p.end(); ok {
+		receipts = append(receipts, receipt)
+	}
+	return receipts, nil
+}
+
+// end returns the current receipt without its leading and trailing blank lines, and starts a new one.
+// It returns false if the receipt is blank.
+func (p *parser) end() (Receipt, bool) {
+	receipt := p.receipt
+	p.receipt = Receipt{}
+	p.ignored = make(map[string]bool)
+
+	blank := func(l Line) bool {
+		return l.Raster == nil && strings.TrimSpace(l.Text) == ""
+	}
+	for len(receipt.Lines) != 0 && blank(receipt.Lines[0]) {
+		receipt.Lines = receipt.Lines[1:]
+	}
+	for len(receipt.Lines) != 0 && blank(receipt.Lines[len(receipt.Lines)-1]) {
+		receipt.Lines = receipt.Lines[:len(receipt.Lines)-1]
+	}
+
+	return receipt, len(receipt.Lines) != 0
+}
+
+// line ends the current line of text.
+func (p *parser) line() {
+	p.receipt.Lines = append(p.receipt.Lines, Line{
+		Text:  decode(p.text),
+		Bold:  p.bold,
+		Scale: p.scale,
+		Align: p.align,
+	})
+	p.text = p.text[:0]
+}
+
+// decode decodes text as UTF-8 if it's valid, otherwise as code page 437, the default of receipt printers.
+func decode(text []byte) string {
+	if utf8.Valid(text) {
+		return string(text)
+	}
+
+	s, err := charmap.CodePage437.NewDecoder().Bytes(text)
+	if err != nil {
+		return string(text)
+	}
+	return string(s)
+}
+
+func (p *parser) ignore(cmd string) {
+	if !p.ignored[cmd] {
+		p.ignored[cmd] = true
+		p.receipt.Unsupported = append(p.receipt.Unsupported, cmd)
+	}
+}
+
+// Control characters.
+const (
+	lf  = 0x0A
+	cr  = 0x0D
+	esc = 0x1B
+	gs  = 0x1D
+	dle = 0x10
+	fs  = 0x1C
+)
+
+// Parameter bytes of commands that are ignored, by the byte after ESC, GS or FS.
+var (
+	escParams = map[byte]int{
+		' ': 1, '-': 1, '2': 0, '3': 1, 'J': 1, 'M': 1, 'R': 1, 't': 1, 'G': 1, 'V': 1, '{': 1,
+		'p': 3, 'c': 2, '=': 1, 'U': 1, 'r': 1,
+	}
+	gsParams = map[byte]int{
+		'B': 1, 'H': 1, 'f': 1, 'h': 1, 'w': 1, 'L': 2, 'W': 2, 'P': 2, 'a': 1, 'b': 1, 'r': 1,
+	}
+	// Kanji mode commands. Stored images have lengths that can't be skipped reliably.
+	fsParams = map[byte]int{
+		'&': 0, '.': 0, '!': 1, '-': 1, 'C': 1, 'S': 2, 'W': 1,
+	}
+)
+
+// next parses the next character or command, and reports whether it cuts the paper.
+func (p *parser) next() (bool, error) {
+	c, err := p.r.ReadByte()
+	if err != nil {
+		return false, err
+	}
+
+	switch c {
+	case lf:
+		p.line()
+	case cr:
+	case esc:
+		return false, p.esc()
+	case gs:
+		return p.gs()
+	case dle:
+		// Real time status requests: DLE EOT n, DLE ENQ n, DLE DC4 fn m t.
+		cmd, err := p.bytes(2)
+		if err != nil {
+			return false, err
+		}
+		if cmd[0] == 0x14 {
+			if _, err := p.bytes(2); err != nil {
+				return false, err
+			}
+		}
+		p.ignore(fmt.Sprintf("DLE %#x", cmd[0]))
+	case fs:
+		cmd, err := p.param()
+		if err != nil {
+			return false, err
+		}
+		n, ok := fsParams[cmd]
+		if !ok {
+			return false, fmt.Errorf("unsupported command FS %q", cmd)
+		}
+		if _, err := p.bytes(n); err != nil {
+			return false, err
+		}
+		p.ignore(fmt.Sprintf("FS %c", cmd))
+	case '\t':
+		p.text = append(p.text, ' ')
+	default:
+		if c >= ' ' {
+			p.text = append(p.text, c)
+		}
+	}
+	return false, nil
+}
+
+func (p *parser) esc() error {
+	cmd, err := p.r.ReadByte()
+	if err != nil {
+		return unexpected(err)
+	}
+
+	switch cmd {
+	case '@':
+		p.bold, p.scale, p.align = false, 1, AlignLeft
+
+	case 'E':
+		n, err := p.param()
+		if err != nil {
+			return err
+		}
+		p.bold = n&1 != 0
+
+	case '!':
+		// Bold, double height and double width bits.
+		n, err := p.param()
+		if err != nil {
+			return err
+		}
+		p.bold = n&0x08 != 0
+		p.scale = 1
+		if n&0x10 != 0 {
+			p.scale = 2
+		}
+
+	case 'a':
+		n, err := p.param()
+		if err != nil {
+			return err
+		}
+		switch n {
+		case 1, '1':
+			p.align = AlignCenter
+		case 2, '2':
+			p.align = AlignRight
+		default:
+			p.align = AlignLeft
+		}
+
+	case 'd':
+		n, err := p.param()
+		if err != nil {
+			return err
+		}
+		// Feeding ends the current line.
+		lines := int(n)
+		if len(p.text) != 0 {
+			p.line()
+			lines--
+		}
+		for i := 0; i < lines; i++ {
+			p.line()
+		}
+
+	default:
+		n, ok := escParams[cmd]
+		if !ok {
+			return fmt.Errorf("unsupported command ESC %q", cmd)
+		}
+		if _, err := p.bytes(n); err != nil {
+			return err
+		}
+		p.ignore(fmt.Sprintf("ESC %c", cmd))
+	}
+
+	return nil
+}
+
+func (p *parser) gs() (bool, error) {
+	cmd, err := p.r.ReadByte()
+	if err != nil {
+		return false, unexpected(err)
+	}
+
+	switch cmd {
+	case '!':
+		// Width in the high nibble, height in the low nibble.
+		n, err := p.param()
+		if err != nil {
+			return false, err
+		}
+		p.scale = int(n&0x07) + 1
+
+	case 'v':
+		return false, p.raster()
+
+	case 'V':
+		// Cut, then feed by n with function B.
+		m, err := p.param()
+		if err != nil {
+			return false, err
+		}
+		if m >= 65 {
+			if _, err := p.param(); err != nil {
+				return false, err
+			}
+		}
+		if len(p.text) != 0 {
+			p.line()
+		}
+		return true, nil
+
+	case '(':
+		// Extended commands: GS ( fn pL pH followed by pL+pH*256 bytes, like QR codes.
+		header, err := p.bytes(3)
+		if err != nil {
+			return false, err
+		}
+		if _, err := p.bytes(int(header[1]) + int(header[2])<<8); err != nil {
+			return false, err
+		}
+		p.ignore(fmt.Sprintf("GS ( %c", header[0]))
+
+	case 'k':
+		// Barcodes: NUL terminated data for m <= 6, or a length n then the data.
+		m, err := p.param()
+		if err != nil {
+			return false, err
+		}
+		if m <= 6 {
+			if _, err := p.r.ReadBytes(0); err != nil {
+				return false, unexpected(err)
+			}
+		} else {
+			n, err := p.param()
+			if err != nil {
+				return false, err
+			}
+			if _, err := p.bytes(int(n)); err != nil {
+				return false, err
+			}
+		}
+		p.ignore("GS k")
+
+	default:
+		n, ok := gsParams[cmd]
+		if !ok {
+			return false, fmt.Errorf("unsupported command GS %q", cmd)
+		}
+		if _, err := p.bytes(n); err != nil {
+			return false, err
+		}
+		p.ignore(fmt.Sprintf("GS %c", cmd))
+	}
+
+	return false, nil
+}
+
+// raster parses GS v 0 m xL xH yL yH, followed by rows of x bytes, y rows, 1 bits black.
+// m doubles the width if its bit 0 is set, and the height if its bit 1 is set.
+func (p *parser) raster() error {
+	header, err := p.bytes(6)
+	if err != nil {
+		return err
+	}
+	if header[0] != '0' {
+		return fmt.Errorf("unsupported command GS v %q", header[0])
+	}
+
+	mode := header[1]
+	if mode >= '0' {
+		mode -= '0'
+	}
+	rowBytes := int(header[2]) + int(header[3])<<8
+	rows := int(header[4]) + int(header[5])<<8
+	if rowBytes == 0 || rows == 0 || rowBytes*rows > maxRaster {
+		return fmt.Errorf("invalid %dx%d bytes raster image", rowBytes, rows)
+	}
+
+	scaleX, scaleY := 1, 1
+	if mode&1 != 0 {
+		scaleX = 2
+	}
+	if mode&2 != 0 {
+		scaleY = 2
+	}
+
+	img := monochrome.New(image.Rect(0, 0, rowBytes*8*scaleX, rows*scaleY))
+	for y := 0; y < rows; y++ {
+		row, err := p.bytes(rowBytes)
+		if err != nil {
+			return err
+		}
+		for x := 0; x < rowBytes*8; x++ {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				img.FillRect(image.Rect(x*scaleX, y*scaleY, (x+1)*scaleX, (y+1)*scaleY), true)
+			}
+		}
+	}
+
+	// Images start on a new line.
+	if len(p.text) != 0 {
+		p.line()
+	}
+	p.receipt.Lines = append(p.receipt.Lines, Line{
+		Raster: img,
+		Align:  p.align,
+	})
+	return nil
+}
+
+func (p *parser) param() (byte, error) {
+	c, err := p.r.ReadByte()
+	return c, unexpected(err)
+}
+
+func (p *parser) bytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(p.r, b)
+	return b, unexpected(err)
+}
+
+// unexpected returns io.ErrUnexpectedEOF for io.EOF, as commands are truncated.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -1,12 +1,21 @@
 package etiquette
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 
 	"go.afab.re/etiquette/monochrome"
 )
@@ -16,105 +25,579 @@ type Bounds struct {
 	Dx int
 	// Dy is the minimum height of the image, in pixels.
 	MinDy int
+	// MaxDy is the maximum height of the image, in pixels.
+	// If 0, there is no maximum.
+	MaxDy int
+	// DPI is the resolution of the printer, in dots (pixels) per inch.
+	// It must be set, lengths in mm and font sizes depend on it: MediaBounds sets it for the tape.
+	// Functions taking Bounds return an error if it isn't.
+	DPI int
+}
+
+// check returns an error if b can't be rendered to.
+func (b Bounds) check() error {
+	if b.DPI <= 0 {
+		return fmt.Errorf("invalid bounds resolution %d DPI, Bounds.DPI must be set", b.DPI)
+	}
+	return nil
+}
+
+const mmPerInch = 25.4
+
+// MMToPx converts a length in millimeters to the nearest number of pixels.
+// b.DPI must be set.
+func (b Bounds) MMToPx(mm float64) int {
+	return int(math.Round(mm * float64(b.DPI) / mmPerInch))
+}
+
+// PxToMM converts a number of pixels to a length in millimeters.
+// b.DPI must be set.
+func (b Bounds) PxToMM(px int) float64 {
+	return float64(px) * mmPerInch / float64(b.DPI)
 }
 
 type TextOpts struct {
-	DPI  int
+	// Font is the font used by the package level Text.
+	//
+	// Deprecated: use a Renderer for the font instead.
 	Font *opentype.Font
+	// DPI is the resolution used by the package level Text if Bounds.DPI isn't set.
+	//
+	// Deprecated: set Bounds.DPI instead.
+	DPI int
+
+	// Size is the font size in points.
+	// If 0, the biggest size that fits the tape is used.
+	Size float64
+	// Margin is the blank space before and after the text, in mm.
+	// If 0, defaults to 1mm.
+	Margin float64
+	// Length is the minimum length of the label, in mm.
+	// Labels are never shorter than Bounds.MinDy.
+	Length float64
+	// Align is where the text goes if the label is longer than it.
+	Align Align
+	// Style is drawn with the font added for it with Renderer.AddFont, or synthesized if there isn't one.
+	Style Style
+	// Decoration is drawn on each line of text.
+	Decoration Decoration
+
+	// LetterSpacing is extra space added between letters, in mm. It can be negative.
+	// Kerning from the font is always applied.
+	LetterSpacing float64
+	// MaxLength is the maximum length of the label, in mm.
+	// Text that is too long is condensed by up to Condense, and then drawn with a smaller font if
+	// needed (unless Size is set).
+	// If 0, there is no maximum.
+	MaxLength float64
+	// Condense is how much text can be squeezed to fit MaxLength, from 0 (not at all) to 1.
+	Condense float64
+
+	// Hinting is how glyphs are snapped to pixels.
+	// Full hinting distorts some fonts at small sizes.
+	Hinting Hinting
+	// Supersample renders text at this many times the resolution of the printer,
+	// and then scales it down, which keeps small text more legible.
+	// If 0 or 1, text is rendered at the resolution of the printer.
+	Supersample int
+
+	// Threshold converts the rendered text, and anything drawn with it, to monochrome.
+	// If nil, the threshold is picked automatically.
+	Threshold Thresholder
+
+	// Transliterate replaces characters the font doesn't have with ASCII approximations (é → e).
+	// Text with characters the font doesn't have is otherwise an error.
+	Transliterate bool
+
+	// Frame is drawn around the text.
+	Frame Frame
 }
 
-// Text renders text as an image suitable for printing.
+// Renderer renders text with a font, and optionally fonts for other styles of it.
+// It caches font faces, so one Renderer should be reused to render several labels.
+// It is not safe for concurrent use.
+type Renderer struct {
+	// Logger logs the font size and time taken to render each label at debug level.
+	// If nil, nothing is logged.
+	Logger *slog.Logger
+
+	fonts map[Style]*opentype.Font
+	buf   sfnt.Buffer
+
+	// At most maxFaces, sizes tried to fit text and ZPL or ESC/POS text heights can be anything.
+	faces map[faceKey]font.Face
+	// Biggest font size that fits a height.
+	fits map[fitKey]float64
+}
+
+// maxFaces is the most font faces a Renderer caches.
+const maxFaces = 64
+
+type faceKey struct {
+	size    float64
+	dpi     int
+	style   Style
+	hinting Hinting
+}
+
+type fitKey struct {
+	height px
+	dpi    int
+	style  Style
+}
+
+// NewRenderer returns a Renderer for a regular font.
+func NewRenderer(f *opentype.Font) *Renderer {
+	return &Renderer{
+		fonts: map[Style]*opentype.Font{StyleRegular: f},
+		faces: make(map[faceKey]font.Face),
+		fits:  make(map[fitKey]float64),
+	}
+}
+
+// Text renders text with opts.Font, like Renderer.Text.
+//
+// Deprecated: use NewRenderer(font).Text, which caches font faces across labels.
 func Text(b Bounds, text string, opts TextOpts) (*monochrome.Image, error) {
-	// We're going to rotate the label to print it landscape, it's height needs to match
-	// the width of the printer.
-	height := px(b.Dx)
+	if opts.Font == nil {
+		return nil, errors.New("TextOpts.Font must be set")
+	}
+	if b.DPI == 0 {
+		b.DPI = opts.DPI
+	}
 
-	face, err := face(height, opts)
+	return NewRenderer(opts.Font).Text(b, text, opts)
+}
+
+// Text renders text as an image suitable for printing.
+// Newlines in text start new lines. Right to left text, like Hebrew, is drawn right to left,
+// and Arabic letters are joined.
+// A MissingGlyphsError is returned if the font doesn't have some of the characters,
+// and a ShapingError if the text is in a script that can't be rendered correctly.
+func (r *Renderer) Text(b Bounds, text string, opts TextOpts) (*monochrome.Image, error) {
+	start := time.Now()
+
+	text, err := r.check(text, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	dst := image.NewGray(bounds(height, face, text))
-	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		rows = append(rows, []string{line})
+	}
 
-	d := font.Drawer{
-		Dst:  dst,
-		Src:  image.Black,
-		Face: face,
+	return r.rows(b, rows, opts, start)
+}
+
+// textGray renders text like Text in grayscale, without padding it to the bounds,
+// to draw it with other parts of a label before it is converted to monochrome.
+func (r *Renderer) textGray(b Bounds, text string, opts TextOpts) (*image.Gray, error) {
+	start := time.Now()
+
+	text, err := r.check(text, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		rows = append(rows, []string{line})
+	}
+
+	return r.rowsGray(b, rows, opts, start)
+}
+
+// KeyValue is a pair rendered by KeyValues.
+type KeyValue struct {
+	Key, Value string
+}
+
+// KeyValues renders pairs of keys and values as an image suitable for printing,
+// with one pair per line and the keys and values in two aligned columns.
+// It returns the same errors as Text.
+func (r *Renderer) KeyValues(b Bounds, kvs []KeyValue, opts TextOpts) (*monochrome.Image, error) {
+	start := time.Now()
+
+	if len(kvs) == 0 {
+		return nil, fmt.Errorf("no keys and values")
 	}
-	d.DrawString(text)
 
-	return Image(b, landscape(dst))
+	var rows [][]string
+	for _, kv := range kvs {
+		var row []string
+		for _, text := range []string{kv.Key, kv.Value} {
+			if strings.Contains(text, "\n") {
+				return nil, fmt.Errorf("newline in %q", text)
+			}
+
+			text, err := r.check(text, opts)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, text)
+		}
+		rows = append(rows, row)
+	}
+
+	return r.rows(b, rows, opts, start)
 }
 
-// Rotate image 90° clockwise.
-func landscape(img *image.Gray) *image.Gray {
-	dst := image.NewGray(image.Rect(
-		img.Bounds().Min.Y, img.Bounds().Min.X,
-		img.Bounds().Max.Y, img.Bounds().Max.X,
-	))
+// check the font can render text, transliterating it if needed,
+// and returns it in the order it's drawn.
+func (r *Renderer) check(text string, opts TextOpts) (string, error) {
+	text = shapeArabic(text)
+	if err := checkShaping(text); err != nil {
+		return "", err
+	}
+
+	missing, err := r.missing(text, opts.Style)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) != 0 && opts.Transliterate {
+		text = transliterate(text, missing)
 
-	// Use bounds of each img independently in case Min was not (0, 0).
-	for x := 0; x < dst.Bounds().Dx(); x++ {
-		for y := 0; y < dst.Bounds().Dy(); y++ {
-			dst.Set(
-				dst.Bounds().Min.X+x,
-				dst.Bounds().Min.Y+y,
-				img.At(
-					img.Bounds().Max.X-1-y,
-					img.Bounds().Min.Y+x,
-				),
-			)
+		missing, err = r.missing(text, opts.Style)
+		if err != nil {
+			return "", err
 		}
 	}
+	if len(missing) != 0 {
+		return "", MissingGlyphsError{Runes: missing}
+	}
 
-	return dst
+	return visual(text), nil
+}
+
+// rows renders rows of text, with the cells of each row in columns.
+func (r *Renderer) rows(b Bounds, rows [][]string, opts TextOpts, start time.Time) (*monochrome.Image, error) {
+	gray, err := r.rowsGray(b, rows, opts, start)
+	if err != nil {
+		return nil, err
+	}
+
+	return Image(b, gray, opts.imageOpts())
+}
+
+// imageOpts returns the options to turn the whole label into an image.
+func (o TextOpts) imageOpts() ImageOpts {
+	return ImageOpts{
+		Length:    o.Length,
+		Align:     o.Align,
+		Frame:     o.Frame,
+		Threshold: o.Threshold,
+	}
+}
+
+// partOpts returns the options to render part of a label, that is then padded and framed with the rest of it.
+func (o TextOpts) partOpts() TextOpts {
+	o.Length = 0
+	o.Frame = Frame{}
+	return o
+}
+
+// rowsGray renders rows of text across the tape in grayscale, without padding it to the bounds.
+func (r *Renderer) rowsGray(b Bounds, rows [][]string, opts TextOpts, start time.Time) (*image.Gray, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	// We're going to rotate the label to print it landscape, it's height needs to match
+	// the width of the printer (minus any frame).
+	height := px(b.Dx - 2*opts.Frame.size(b))
+
+	margin := opts.Margin
+	if margin == 0 {
+		margin = defaultMargin
+	}
+	marginPx := b.MMToPx(margin)
+
+	scale := max(opts.Supersample, 1)
+	spacing := fixed.Int26_6(math.Round(opts.LetterSpacing * float64(b.DPI*scale) / mmPerInch * 64))
+
+	// Each row gets the same share of the height.
+	size, err := r.fit(height/px(len(rows)), b.DPI, opts.Size, opts.Style)
+	if err != nil {
+		return nil, err
+	}
+
+	render := func(size float64) (*image.Gray, error) {
+		face, err := r.face(size, b.DPI*scale, opts.Style, opts.Hinting)
+		if err != nil {
+			return nil, err
+		}
+
+		deco := r.decorations(opts.Decoration, size, b.DPI*scale, opts.Style, face)
+		dst := drawRows(height*px(scale), marginPx*scale, face, rows, spacing, deco)
+		if scale == 1 {
+			return dst, nil
+		}
+		return resize(dst, max(1, dst.Bounds().Dx()/scale), int(height), FilterBox), nil
+	}
+
+	dst, err := render(size)
+	if err != nil {
+		return nil, err
+	}
+
+	// Condense the text, and then shrink the font if that's not enough.
+	maxDx := b.MMToPx(opts.MaxLength) - 2*opts.Frame.size(b)
+	for opts.MaxLength != 0 && dst.Bounds().Dx() > maxDx {
+		if float64(dst.Bounds().Dx())*(1-opts.Condense) <= float64(maxDx) {
+			dst = condense(dst, maxDx)
+			break
+		}
+
+		if opts.Size != 0 || size <= 1 {
+			return nil, fmt.Errorf("text doesn't fit in %vmm", opts.MaxLength)
+		}
+
+		size--
+		dst, err = render(size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gray := monochrome.Rotate270Gray(dst)
+	if r.Logger != nil {
+		r.Logger.Debug("rendered text", "rows", rows, "height", height, "dy", gray.Bounds().Dy(), "duration", time.Since(start))
+	}
+
+	return gray, nil
 }
 
 type px int
 
-// Find the biggest font for a given height.
-func face(height px, opts TextOpts) (font.Face, error) {
-	var best font.Face
+// Default margin before and after text, in mm.
+const defaultMargin = 1
+
+func (r *Renderer) face(size float64, dpi int, style Style, hinting Hinting) (font.Face, error) {
+	key := faceKey{size, dpi, style, hinting}
+	if face, ok := r.faces[key]; ok {
+		return face, nil
+	}
+
+	f, synth := r.fontFor(style)
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     float64(dpi),
+		Hinting: hinting.font(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	face = newSynthFace(face, synth, size, dpi)
+
+	if len(r.faces) >= maxFaces {
+		clear(r.faces)
+	}
+	r.faces[key] = face
+	return face, nil
+}
+
+// Find the biggest font size for a given height, or check the requested size fits.
+func (r *Renderer) fit(height px, dpi int, size float64, style Style) (float64, error) {
+	if size != 0 {
+		face, err := r.face(size, dpi, style, HintingFull)
+		if err != nil {
+			return 0, err
+		}
+
+		if face.Metrics().Height.Ceil() > int(height) {
+			return 0, fmt.Errorf("font size %vpt is too big for %dpx high label", size, height)
+		}
+
+		return size, nil
+	}
+
+	key := fitKey{height, dpi, style}
+	if size, ok := r.fits[key]; ok {
+		return size, nil
+	}
+
+	// Synthesized styles have the same metrics.
+	f, _ := r.fontFor(style)
+
+	var best float64
 
 	for i := float64(1); ; i++ {
-		face, err := opentype.NewFace(opts.Font, &opentype.FaceOptions{
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{
 			Size:    i,
-			DPI:     float64(opts.DPI),
+			DPI:     float64(dpi),
 			Hinting: font.HintingFull,
 		})
 		if err != nil {
-			return nil, err
+			return 0, err
 		}
 
 		if face.Metrics().Height.Ceil() > int(height) {
-			return best, nil
+			break
 		}
 
-		best = face
+		best = i
+	}
+
+	if best == 0 {
+		return 0, fmt.Errorf("no font size fits %dpx high label", height)
 	}
+
+	r.fits[key] = best
+	return best, nil
 }
 
-func bounds(height px, face font.Face, text string) image.Rectangle {
-	m := face.Metrics()
+// linePitch is the distance between the baselines of lines.
+func linePitch(face font.Face) int {
+	return face.Metrics().Height.Ceil()
+}
+
+// drawRows draws rows of text black on white.
+// A single column of text is centered on the longest line, otherwise each column is aligned to its start.
+func drawRows(height px, margin int, face font.Face, rows [][]string, spacing fixed.Int26_6, deco decorations) *image.Gray {
+	dst := image.NewGray(bounds(height, max(margin, deco.padding()), face, rows, spacing, deco))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
 
-	margin := 7
+	d := font.Drawer{
+		Dst:  dst,
+		Src:  image.Black,
+		Face: face,
+	}
+
+	widths := columnWidths(face, rows, spacing)
+	for i, row := range rows {
+		y := i * linePitch(face)
+
+		var x int
+		for j, cell := range row {
+			cBounds := boundString(face, cell, spacing)
+			cWidth := cBounds.Max.X.Ceil() - cBounds.Min.X.Floor()
+
+			cx := x
+			if len(widths) == 1 {
+				cx += (widths[j] - cWidth) / 2
+			}
+
+			d.Dot = fixed.P(cx-cBounds.Min.X.Floor(), y)
+			drawString(&d, cell, spacing)
+
+			if cell != "" {
+				deco.draw(dst, cx, cx+cWidth, y)
+			}
+
+			x += widths[j] + columnGap(face, deco)
+		}
+	}
+
+	return dst
+}
+
+// drawString is font.Drawer.DrawString, with extra spacing between letters.
+func drawString(d *font.Drawer, s string, spacing fixed.Int26_6) {
+	prevC := rune(-1)
+	for _, c := range s {
+		if prevC >= 0 {
+			d.Dot.X += d.Face.Kern(prevC, c) + spacing
+		}
+
+		dr, mask, maskp, advance, _ := d.Face.Glyph(d.Dot, c)
+		if !dr.Empty() {
+			draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
+		}
+
+		d.Dot.X += advance
+		prevC = c
+	}
+}
+
+// boundString is font.BoundString, with extra spacing between letters.
+func boundString(face font.Face, s string, spacing fixed.Int26_6) fixed.Rectangle26_6 {
+	b, _ := font.BoundString(face, s)
+
+	if n := utf8.RuneCountInString(s); n > 1 {
+		b.Max.X += spacing * fixed.Int26_6(n-1)
+	}
+
+	return b
+}
+
+// columnWidths is the width of the longest cell in each column.
+func columnWidths(face font.Face, rows [][]string, spacing fixed.Int26_6) []int {
+	var widths []int
+	for _, row := range rows {
+		for j, cell := range row {
+			if j == len(widths) {
+				widths = append(widths, 0)
+			}
+
+			cBounds := boundString(face, cell, spacing)
+			widths[j] = max(widths[j], cBounds.Max.X.Ceil()-cBounds.Min.X.Floor())
+		}
+	}
+	return widths
+}
+
+// columnGap is the space between columns.
+func columnGap(face font.Face, deco decorations) int {
+	return face.Metrics().Height.Ceil()/2 + 2*deco.padding()
+}
+
+// rowsWidth is the width of rows of text in columns.
+func rowsWidth(face font.Face, rows [][]string, spacing fixed.Int26_6, deco decorations) int {
+	var width int
+	for j, w := range columnWidths(face, rows, spacing) {
+		if j > 0 {
+			width += columnGap(face, deco)
+		}
+		width += w
+	}
+	return width
+}
+
+// condense scales img horizontally to dx pixels wide, averaging the pixels squeezed together.
+func condense(img *image.Gray, dx int) *image.Gray {
+	src := img.Bounds()
+	dst := image.NewGray(image.Rect(src.Min.X, src.Min.Y, src.Min.X+dx, src.Max.Y))
+
+	scale := float64(src.Dx()) / float64(dx)
+	for x := 0; x < dx; x++ {
+		// Source pixels covered by this one.
+		x0, x1 := float64(x)*scale, float64(x+1)*scale
+
+		for y := src.Min.Y; y < src.Max.Y; y++ {
+			var sum, weight float64
+			for sx := int(x0); sx < int(math.Ceil(x1)) && sx < src.Dx(); sx++ {
+				w := math.Min(x1, float64(sx+1)) - math.Max(x0, float64(sx))
+				sum += w * float64(img.GrayAt(src.Min.X+sx, y).Y)
+				weight += w
+			}
+
+			dst.SetGray(dst.Rect.Min.X+x, y, color.Gray{uint8(math.Round(sum / weight))})
+		}
+	}
+
+	return dst
+}
+
+// bounds of the image to draw rows in, with the first baseline at y = 0 and rows starting at x = 0.
+func bounds(height px, margin int, face font.Face, rows [][]string, spacing fixed.Int26_6, deco decorations) image.Rectangle {
+	m := face.Metrics()
 
 	// Margin to center the font vertically.
 	// (not the specific text - otherwise different labels will end up aligned differently).
 	yMin := -m.Ascent.Ceil()
-	yMax := m.Descent.Ceil()
+	yMax := (len(rows)-1)*linePitch(face) + m.Descent.Ceil()
 
 	yMargin := int(height) - (yMax - yMin)
 
 	// If the margin isn't a multiple of two, (arbitrarily) give the extra space to yMax.
-	yMax += (yMargin + 1) / 2
+	// The margin can be negative if the font's ascent and descent are bigger than its height.
 	yMin -= yMargin / 2
+	yMax = yMin + int(height)
 
 	// Combine font based vertical bounds, and text based horizontal bounds.
-	tBounds, _ := font.BoundString(face, text)
 	return image.Rect(
-		tBounds.Min.X.Floor()-margin, yMin,
-		tBounds.Max.X.Ceil()+margin, yMax,
+		-margin, yMin,
+		rowsWidth(face, rows, spacing, deco)+margin, yMax,
 	)
 }
@@ -0,0 +1,291 @@
+package etiquette
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+
+	"go.afab.re/etiquette/barcode"
+	"go.afab.re/etiquette/internal/imagetest"
+	"go.afab.re/etiquette/pt700"
+	"go.afab.re/etiquette/zpl"
+)
+
+func testRenderer(t testing.TB) *Renderer {
+	t.Helper()
+
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRenderer(f)
+}
+
+func testBounds(t testing.TB, width pt700.MediaWidth) Bounds {
+	t.Helper()
+
+	dx, err := width.Dx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Bounds{
+		Dx:    dx,
+		MinDy: width.MinDy(),
+		MaxDy: width.MaxDy(),
+		DPI:   width.DPI(),
+	}
+}
+
+func TestTextGolden(t *testing.T) {
+	r := testRenderer(t)
+
+	for _, test := range []struct {
+		name  string
+		width pt700.MediaWidth
+		text  string
+		opts  TextOpts
+	}{
+		{"fit", pt700.Width12, "Hello, World!", TextOpts{}},
+		{"lines", pt700.Width24, "Line one\nLine two", TextOpts{}},
+		{"size", pt700.Width12, "Small", TextOpts{Size: 12}},
+		{"underline", pt700.Width12, "Underlined", TextOpts{Decoration: DecorationUnderline}},
+		{"length", pt700.Width9, "Centered", TextOpts{Length: 60, Align: AlignCenter}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			img, err := r.Text(testBounds(t, test.width), test.text, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Glyph rasterization can differ a little between architectures.
+			imagetest.CompareGolden(t, img, filepath.Join("testdata", "text-"+test.name+".png"), imagetest.Tolerance(8))
+		})
+	}
+}
+
+func TestImageGolden(t *testing.T) {
+	// A gradient, with a dark circle in the middle.
+	src := image.NewGray(image.Rect(0, 0, 120, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 120; x++ {
+			v := uint8(y * 255 / 200)
+			if dx, dy := x-60, y-100; dx*dx+dy*dy < 40*40 {
+				v /= 4
+			}
+			src.SetGray(x, y, color.Gray{v})
+		}
+	}
+
+	for _, test := range []struct {
+		name  string
+		width pt700.MediaWidth
+		opts  ImageOpts
+	}{
+		{"threshold", pt700.Width24, ImageOpts{}},
+		{"fit", pt700.Width12, ImageOpts{Fit: true}},
+		{"frame", pt700.Width24, ImageOpts{Fit: true, Frame: Frame{Thickness: 0.5}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			img, err := Image(testBounds(t, test.width), src, test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			imagetest.CompareGolden(t, img, filepath.Join("testdata", "image-"+test.name+".png"))
+		})
+	}
+}
+
+func TestBarcodeGolden(t *testing.T) {
+	b := testBounds(t, pt700.Width12)
+
+	qr, err := barcode.QR([]byte("etiquette"), barcode.QRLevelM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := Barcode(b, qr, BarcodeOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagetest.CompareGolden(t, img, filepath.Join("testdata", "barcode-qr.png"))
+
+	code128, err := barcode.Code128("etiquette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err = Barcode(b, code128, BarcodeOpts{Module: 0.3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	imagetest.CompareGolden(t, img, filepath.Join("testdata", "barcode-code128.png"))
+}
+
+func TestBoundsDPI(t *testing.T) {
+	r := testRenderer(t)
+	b := Bounds{Dx: 70, MinDy: 100}
+
+	if _, err := r.Text(b, "no DPI", TextOpts{}); err == nil {
+		t.Errorf("Text: expected an error without DPI")
+	}
+	if _, err := Image(b, image.NewGray(image.Rect(0, 0, 10, 10)), ImageOpts{}); err == nil {
+		t.Errorf("Image: expected an error without DPI")
+	}
+}
+
+func TestTextWithoutRenderer(t *testing.T) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := testBounds(t, pt700.Width12)
+
+	want, err := NewRenderer(f).Text(b, "Hello", TextOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DPI can still be given in TextOpts.
+	dpi := b.DPI
+	b.DPI = 0
+	got, err := Text(b, "Hello", TextOpts{Font: f, DPI: dpi})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, n := imagetest.Diff(got, want); n != 0 || got.Bounds() != want.Bounds() {
+		t.Errorf("Text and Renderer.Text differ by %d pixels", n)
+	}
+
+	if _, err := Text(b, "Hello", TextOpts{DPI: dpi}); err == nil {
+		t.Errorf("expected an error without a font")
+	}
+}
+
+func TestFaceCache(t *testing.T) {
+	r := testRenderer(t)
+	b := testBounds(t, pt700.Width24)
+
+	for size := 1.0; size <= 2*maxFaces; size++ {
+		if _, err := r.Text(b, "Hello", TextOpts{Size: size / 4}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(r.faces) > maxFaces {
+		t.Errorf("got %d cached faces, want at most %d", len(r.faces), maxFaces)
+	}
+}
+
+func BenchmarkText(b *testing.B) {
+	f, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		b.Fatal(err)
+	}
+	bounds := testBounds(b, pt700.Width24)
+
+	for _, bench := range []struct {
+		name string
+		opts TextOpts
+		// Fitting is cached by the Renderer, use a new one each time to measure it.
+		fresh bool
+	}{
+		{"fit", TextOpts{}, true},
+		{"fit cached", TextOpts{}, false},
+		{"size", TextOpts{Size: 20}, false},
+		{"condense", TextOpts{MaxLength: 30, Condense: 0.5}, false},
+		{"supersample", TextOpts{Supersample: 4}, false},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			r := NewRenderer(f)
+			for i := 0; i < b.N; i++ {
+				if bench.fresh {
+					r = NewRenderer(f)
+				}
+				if _, err := r.Text(bounds, "Hello, World!\nSecond line", bench.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkImage(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 1200, 800))
+	for y := 0; y < 800; y++ {
+		for x := 0; x < 1200; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 0xFF})
+		}
+	}
+	bounds := testBounds(b, pt700.Width24)
+
+	for _, bench := range []struct {
+		name string
+		opts ImageOpts
+	}{
+		{"fit", ImageOpts{Fit: true}},
+		{"fit sharpen", ImageOpts{Fit: true, Sharpen: 1}},
+		{"fit despeckle", ImageOpts{Fit: true, Despeckle: 4}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := Image(bounds, src, bench.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestZPLTooLarge(t *testing.T) {
+	r := testRenderer(t)
+	b := testBounds(t, pt700.Width12)
+
+	for _, test := range []struct {
+		name string
+		zpl  string
+	}{
+		{"length", "^XA^PW100^LL32000^FO0,0^FDA^FS^XZ"},
+		{"origin", "^XA^PW100^LL100^LH30000,0^FO30000,0^FDA^FS^XZ"},
+		{"text", "^XA^FO0,0^A0N,20000^FDA^FS^XZ"},
+		{"barcode", "^XA^BY32000^FO0,0^BCN,10^FD123456^FS^XZ"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			labels, err := zpl.Parse(strings.NewReader(test.zpl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.ZPL(b, labels[0], ImageOpts{}); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestIcons(t *testing.T) {
+	for _, name := range IconNames() {
+		icon, err := ParseIcon(name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if icon.String() != name {
+			t.Errorf("%s: parsed as %s", name, icon)
+		}
+
+		img := icon.draw(64)
+		var black int
+		for _, p := range img.Pix {
+			if p < 0x80 {
+				black++
+			}
+		}
+		if black == 0 {
+			t.Errorf("%s: nothing drawn", name)
+		}
+	}
+
+	if _, err := ParseIcon("ghs-flammable"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,85 @@
+package etiquette
+
+import (
+	"image"
+	"math"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Frame is a border drawn around the contents of a label.
+type Frame struct {
+	// Thickness of the border, in mm.
+	// If 0, no frame is drawn.
+	Thickness float64
+	// Radius of the corners, in mm.
+	// If 0, the corners are square.
+	Radius float64
+	// Inset is the blank space between the edge of the label and the frame,
+	// and between the frame and the contents, in mm.
+	// If 0, defaults to 0.5mm.
+	Inset float64
+}
+
+// Default inset around frames, in mm.
+const defaultFrameInset = 0.5
+
+func (f Frame) inset() float64 {
+	if f.Inset == 0 {
+		return defaultFrameInset
+	}
+	return f.Inset
+}
+
+// size returns the space taken up by the frame on each side of the contents, in pixels.
+func (f Frame) size(b Bounds) int {
+	if f.Thickness == 0 {
+		return 0
+	}
+
+	return 2*b.MMToPx(f.inset()) + b.MMToPx(f.Thickness)
+}
+
+// inner returns the bounds available to the contents of the frame.
+func (f Frame) inner(b Bounds, minDy int) (Bounds, int) {
+	size := f.size(b)
+
+	b.Dx -= 2 * size
+	return b, minDy - 2*size
+}
+
+// draw the frame onto img, which should already be padded to the full bounds of the label.
+func (f Frame) draw(b Bounds, img *monochrome.Image) {
+	if f.Thickness == 0 {
+		return
+	}
+
+	inset := b.MMToPx(f.inset())
+	thickness := b.MMToPx(f.Thickness)
+	radius := b.MMToPx(f.Radius)
+
+	outer := img.Bounds().Inset(inset)
+	inner := outer.Inset(thickness)
+
+	for x := outer.Min.X; x < outer.Max.X; x++ {
+		for y := outer.Min.Y; y < outer.Max.Y; y++ {
+			if inRoundedRect(outer, radius, x, y) && !inRoundedRect(inner, max(radius-thickness, 0), x, y) {
+				img.SetBlack(x, y, true)
+			}
+		}
+	}
+}
+
+// inRoundedRect checks if the center of pixel (x, y) is inside r with corners of radius.
+func inRoundedRect(r image.Rectangle, radius int, x, y int) bool {
+	if r.Empty() || !(image.Point{x, y}).In(r) {
+		return false
+	}
+
+	// Distance from the closest point of r inset by radius.
+	px, py := float64(x)+0.5, float64(y)+0.5
+	cx := math.Max(float64(r.Min.X+radius), math.Min(px, float64(r.Max.X-radius)))
+	cy := math.Max(float64(r.Min.Y+radius), math.Min(py, float64(r.Max.Y-radius)))
+
+	return math.Hypot(px-cx, py-cy) <= float64(radius)
+}
@@ -0,0 +1,93 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Space between labels printed on the same page, with a dotted line to cut along in the middle, in mm.
+const cutGap = 1
+
+// Gang returns the bounds of each of n labels printed side by side across the tape of b,
+// to print several short labels on wide tape with less tape. Render the labels with them, and pack them with Gang.
+func (b Bounds) Gang(n int) (Bounds, error) {
+	if err := b.check(); err != nil {
+		return Bounds{}, err
+	}
+
+	if n < 1 {
+		return Bounds{}, fmt.Errorf("invalid number of labels across the tape %d", n)
+	}
+	if n == 1 {
+		return b, nil
+	}
+
+	dx := (b.Dx - (n-1)*b.MMToPx(cutGap)) / n
+	if dx <= 0 {
+		return Bounds{}, fmt.Errorf("%d labels don't fit across the %dpx tape", n, b.Dx)
+	}
+
+	return Bounds{
+		Dx:    dx,
+		MaxDy: b.MaxDy,
+		DPI:   b.DPI,
+	}, nil
+}
+
+// Gang packs imgs rendered with the bounds from b.Gang(n), n at a time side by side across the tape of b.
+// Each one starts at the start of the label, and dotted lines between them show where to cut them apart.
+func Gang(b Bounds, n int, imgs []*monochrome.Image) ([]*monochrome.Image, error) {
+	lane, err := b.Gang(n)
+	if err != nil || n == 1 {
+		return imgs, err
+	}
+	gap := b.MMToPx(cutGap)
+
+	var ganged []*monochrome.Image
+	for i := 0; i < len(imgs); i += n {
+		group := imgs[i:min(i+n, len(imgs))]
+
+		dy := b.MinDy
+		for j, img := range group {
+			if img.Bounds().Dx() != lane.Dx {
+				return nil, fmt.Errorf("label %d: expected %dpx wide image but got %dpx", i+j, lane.Dx, img.Bounds().Dx())
+			}
+			dy = max(dy, img.Bounds().Dy())
+		}
+
+		dst := monochrome.New(image.Rect(0, 0, b.Dx, dy))
+		for j, img := range group {
+			// Labels start at Max.Y.
+			x := j * (lane.Dx + gap)
+			r := image.Rect(x, dy-img.Bounds().Dy(), x+lane.Dx, dy)
+			monochrome.Draw(dst, r, img, img.Bounds().Min, draw.Src)
+
+			if j != len(group)-1 {
+				drawCutLine(dst, image.Rect(x+lane.Dx, 0, x+lane.Dx+gap, dy))
+			}
+		}
+
+		ganged = append(ganged, dst)
+	}
+
+	return ganged, nil
+}
+
+// drawCutLine draws a dotted line along the middle of the gap r between two labels.
+func drawCutLine(img *monochrome.Image, r image.Rectangle) {
+	if r.Dx() < r.Dy() {
+		x := r.Min.X + r.Dx()/2
+		for y := r.Min.Y; y < r.Max.Y; y += 4 {
+			img.FillRect(image.Rect(x, y, x+1, min(y+2, r.Max.Y)), true)
+		}
+		return
+	}
+
+	y := r.Min.Y + r.Dy()/2
+	for x := r.Min.X; x < r.Max.X; x += 4 {
+		img.FillRect(image.Rect(x, y, min(x+2, r.Max.X), y+1), true)
+	}
+}
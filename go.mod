@@ -7,4 +7,4 @@ require (
 	golang.org/x/sys v0.16.0
 )
 
-require golang.org/x/text v0.14.0 // indirect
+require golang.org/x/text v0.14.0
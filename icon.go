@@ -0,0 +1,343 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+
+	"golang.org/x/image/vector"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Icon is a pictogram printed beside text, as tall as the tape.
+type Icon int
+
+const (
+	IconNone Icon = iota
+	IconArrowUp
+	IconArrowDown
+	IconArrowLeft
+	IconArrowRight
+	// Triangle with an exclamation mark, ISO 7010 W001.
+	IconWarning
+	// Triangle with a lightning bolt, ISO 7010 W012.
+	IconElectrical
+	// Diamond with an exclamation mark, GHS07 for irritants and other health hazards.
+	IconGHSExclamation
+	// Exploding bomb, GHS01 for explosives.
+	IconGHSExplosive
+	// Flame, GHS02 for flammables.
+	IconGHSFlammable
+	// Flame over a circle, GHS03 for oxidizers.
+	IconGHSOxidizing
+	// Gas cylinder, GHS04 for gases under pressure.
+	IconGHSCompressedGas
+	// Liquid dripping on a surface and a hand, GHS05 for corrosives.
+	IconGHSCorrosive
+	// Skull and crossbones, GHS06 for acute toxicity.
+	IconGHSToxic
+	// Person with a star on their chest, GHS08 for serious health hazards.
+	IconGHSHealthHazard
+	// Dead tree and fish, GHS09 for environmental hazards.
+	IconGHSEnvironment
+)
+
+// path is a closed polygon, in a 1x1 square with y increasing downwards.
+// Holes are cut out of the shapes they are inside of.
+type path struct {
+	points []vec
+	hole   bool
+}
+
+type vec struct{ x, y float32 }
+
+var (
+	arrowRight = []vec{{0.1, 0.4}, {0.55, 0.4}, {0.55, 0.2}, {0.9, 0.5}, {0.55, 0.8}, {0.55, 0.6}, {0.1, 0.6}}
+
+	triangle = []path{
+		{points: []vec{{0.5, 0.05}, {0.97, 0.92}, {0.03, 0.92}}},
+		{points: []vec{{0.5, 0.23}, {0.83, 0.84}, {0.17, 0.84}}, hole: true},
+	}
+
+	exclamation = []path{
+		{points: []vec{{0.46, 0.4}, {0.54, 0.4}, {0.54, 0.66}, {0.46, 0.66}}},
+		{points: []vec{{0.46, 0.71}, {0.54, 0.71}, {0.54, 0.79}, {0.46, 0.79}}},
+	}
+
+	// Frame of GHS pictograms, the symbol has to fit inside it.
+	diamond = []path{
+		{points: []vec{{0.5, 0}, {1, 0.5}, {0.5, 1}, {0, 0.5}}},
+		{points: []vec{{0.5, 0.09}, {0.91, 0.5}, {0.5, 0.91}, {0.09, 0.5}}, hole: true},
+	}
+
+	flame = []vec{
+		{0.5, 0.2}, {0.57, 0.3}, {0.62, 0.4}, {0.64, 0.5}, {0.61, 0.58}, {0.55, 0.62}, {0.45, 0.62},
+		{0.39, 0.58}, {0.36, 0.5}, {0.38, 0.41}, {0.43, 0.48}, {0.46, 0.38},
+	}
+)
+
+// ghs returns a GHS pictogram: paths inside the diamond frame.
+func ghs(paths ...path) []path {
+	return append(diamond[:len(diamond):len(diamond)], paths...)
+}
+
+// bar is a rectangle of width w, from a to b.
+func bar(a, b vec, w float32) []vec {
+	l := float32(math.Hypot(float64(b.x-a.x), float64(b.y-a.y)))
+	// Half the width, perpendicular to the bar.
+	n := vec{(a.y - b.y) / l * w / 2, (b.x - a.x) / l * w / 2}
+	return []vec{{a.x + n.x, a.y + n.y}, {b.x + n.x, b.y + n.y}, {b.x - n.x, b.y - n.y}, {a.x - n.x, a.y - n.y}}
+}
+
+// star has points alternating between radius outer and inner around c, starting straight up.
+// A star with inner == outer is a circle.
+func star(c vec, points int, outer, inner float32) []vec {
+	var star []vec
+	for i := 0; i < 2*points; i++ {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		a := float64(i) * math.Pi / float64(points)
+		star = append(star, vec{c.x + r*float32(math.Sin(a)), c.y - r*float32(math.Cos(a))})
+	}
+	return star
+}
+
+func circle(c vec, r float32) []vec {
+	return star(c, 12, r, r)
+}
+
+// scale points around c.
+func scale(points []vec, c vec, f float32) []vec {
+	scaled := make([]vec, len(points))
+	for i, p := range points {
+		scaled[i] = vec{c.x + (p.x-c.x)*f, c.y + (p.y-c.y)*f}
+	}
+	return scaled
+}
+
+var icons = map[Icon]struct {
+	name  string
+	paths []path
+}{
+	IconArrowUp:    {"arrow-up", []path{{points: rotate(arrowRight, 3)}}},
+	IconArrowDown:  {"arrow-down", []path{{points: rotate(arrowRight, 1)}}},
+	IconArrowLeft:  {"arrow-left", []path{{points: rotate(arrowRight, 2)}}},
+	IconArrowRight: {"arrow-right", []path{{points: arrowRight}}},
+	IconWarning:    {"warning", append(triangle[:len(triangle):len(triangle)], exclamation...)},
+	IconElectrical: {"electrical", append(triangle[:len(triangle):len(triangle)], path{points: []vec{
+		{0.55, 0.36}, {0.42, 0.6}, {0.5, 0.6}, {0.44, 0.8}, {0.6, 0.53}, {0.52, 0.53}, {0.6, 0.36},
+	}})},
+	IconGHSExclamation: {"ghs-exclamation", ghs(
+		path{points: []vec{{0.45, 0.25}, {0.55, 0.25}, {0.55, 0.6}, {0.45, 0.6}}},
+		path{points: []vec{{0.45, 0.66}, {0.55, 0.66}, {0.55, 0.76}, {0.45, 0.76}}},
+	)},
+	IconGHSExplosive: {"ghs-explosive", ghs(
+		path{points: star(vec{0.5, 0.56}, 9, 0.2, 0.1)},
+		path{points: circle(vec{0.5, 0.56}, 0.06), hole: true},
+		// Flying fragments.
+		path{points: bar(vec{0.33, 0.33}, vec{0.38, 0.38}, 0.04)},
+		path{points: bar(vec{0.5, 0.24}, vec{0.5, 0.29}, 0.04)},
+		path{points: bar(vec{0.67, 0.33}, vec{0.62, 0.38}, 0.04)},
+	)},
+	IconGHSFlammable: {"ghs-flammable", ghs(
+		path{points: flame},
+		path{points: []vec{{0.3, 0.67}, {0.7, 0.67}, {0.7, 0.73}, {0.3, 0.73}}},
+	)},
+	IconGHSOxidizing: {"ghs-oxidizing", ghs(
+		path{points: scale(flame, vec{0.5, 0.2}, 0.7)},
+		path{points: circle(vec{0.5, 0.62}, 0.13)},
+		path{points: circle(vec{0.5, 0.62}, 0.08), hole: true},
+	)},
+	IconGHSCompressedGas: {"ghs-compressed-gas", ghs(
+		// Lying down, with rounded ends and the valve on the right.
+		path{points: bar(vec{0.32, 0.55}, vec{0.62, 0.55}, 0.2)},
+		path{points: circle(vec{0.32, 0.55}, 0.1)},
+		path{points: circle(vec{0.62, 0.55}, 0.1)},
+		path{points: bar(vec{0.7, 0.55}, vec{0.78, 0.55}, 0.07)},
+		path{points: bar(vec{0.78, 0.48}, vec{0.78, 0.62}, 0.04)},
+	)},
+	IconGHSCorrosive: {"ghs-corrosive", ghs(
+		// Two tipped test tubes, dripping.
+		path{points: bar(vec{0.35, 0.3}, vec{0.46, 0.37}, 0.06)},
+		path{points: bar(vec{0.54, 0.37}, vec{0.65, 0.3}, 0.06)},
+		path{points: circle(vec{0.42, 0.44}, 0.025)},
+		path{points: circle(vec{0.58, 0.44}, 0.025)},
+		// Eaten away surface, and hand.
+		path{points: []vec{{0.26, 0.63}, {0.38, 0.63}, {0.4, 0.58}, {0.46, 0.58}, {0.48, 0.63}, {0.48, 0.7}, {0.3, 0.7}}},
+		path{points: []vec{{0.52, 0.63}, {0.56, 0.56}, {0.62, 0.56}, {0.64, 0.62}, {0.78, 0.62}, {0.7, 0.7}, {0.52, 0.7}}},
+	)},
+	IconGHSToxic: {"ghs-toxic", ghs(
+		path{points: circle(vec{0.5, 0.38}, 0.13)},
+		path{points: []vec{{0.43, 0.45}, {0.57, 0.45}, {0.57, 0.55}, {0.43, 0.55}}},
+		path{points: circle(vec{0.45, 0.38}, 0.035), hole: true},
+		path{points: circle(vec{0.55, 0.38}, 0.035), hole: true},
+		// Crossbones.
+		path{points: bar(vec{0.3, 0.56}, vec{0.7, 0.72}, 0.06)},
+		path{points: bar(vec{0.3, 0.72}, vec{0.7, 0.56}, 0.06)},
+	)},
+	IconGHSHealthHazard: {"ghs-health-hazard", ghs(
+		path{points: circle(vec{0.5, 0.27}, 0.07)},
+		path{points: []vec{{0.38, 0.38}, {0.62, 0.38}, {0.68, 0.46}, {0.66, 0.72}, {0.34, 0.72}, {0.32, 0.46}}},
+		path{points: star(vec{0.5, 0.56}, 8, 0.11, 0.05), hole: true},
+	)},
+	IconGHSEnvironment: {"ghs-environment", ghs(
+		// Dead tree.
+		path{points: bar(vec{0.38, 0.28}, vec{0.38, 0.7}, 0.05)},
+		path{points: bar(vec{0.38, 0.46}, vec{0.28, 0.37}, 0.04)},
+		path{points: bar(vec{0.38, 0.4}, vec{0.48, 0.31}, 0.04)},
+		// Fish.
+		path{points: circle(vec{0.55, 0.59}, 0.08)},
+		path{points: []vec{{0.61, 0.59}, {0.71, 0.52}, {0.71, 0.66}}},
+		// Ground.
+		path{points: bar(vec{0.31, 0.7}, vec{0.69, 0.7}, 0.04)},
+	)},
+}
+
+// rotate points clockwise by quarter turns around the center of the square.
+func rotate(points []vec, quarters int) []vec {
+	rotated := make([]vec, len(points))
+	for i, p := range points {
+		for q := 0; q < quarters; q++ {
+			p = vec{1 - p.y, p.x}
+		}
+		rotated[i] = p
+	}
+	return rotated
+}
+
+func (i Icon) String() string {
+	if i == IconNone {
+		return "none"
+	}
+	if icon, ok := icons[i]; ok {
+		return icon.name
+	}
+	return fmt.Sprintf("Icon(%d)", int(i))
+}
+
+// ParseIcon parses the String() of an Icon.
+func ParseIcon(s string) (Icon, error) {
+	if s == IconNone.String() {
+		return IconNone, nil
+	}
+	for i, icon := range icons {
+		if s == icon.name {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown icon %q, expected one of %v", s, IconNames())
+}
+
+// IconNames returns the names of all the icons, sorted.
+func IconNames() []string {
+	var names []string
+	for _, icon := range icons {
+		names = append(names, icon.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// draw the icon as a size by size image, upright like text before it is rotated to print.
+func (i Icon) draw(size int) *image.Gray {
+	r := vector.NewRasterizer(size, size)
+	s := float32(size)
+	for _, p := range icons[i].paths {
+		points := p.points
+		// The rasterizer adds up the winding of paths, so holes go the other way.
+		if p.hole != (area(points) < 0) {
+			points = reversed(points)
+		}
+
+		r.MoveTo(points[0].x*s, points[0].y*s)
+		for _, pt := range points[1:] {
+			r.LineTo(pt.x*s, pt.y*s)
+		}
+		r.ClosePath()
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	r.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+
+	// Black on white.
+	dst := image.NewGray(mask.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.DrawMask(dst, dst.Bounds(), image.Black, image.Point{}, mask, image.Point{}, draw.Over)
+	return dst
+}
+
+// area returns the signed area of a polygon, positive if it is clockwise with y increasing downwards.
+func area(points []vec) float32 {
+	var a float32
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		a += p.x*q.y - q.x*p.y
+	}
+	return a / 2
+}
+
+func reversed(points []vec) []vec {
+	r := make([]vec, len(points))
+	for i, p := range points {
+		r[len(points)-1-i] = p
+	}
+	return r
+}
+
+// WithIcon renders text like Text, with icon before it.
+// opts apply to the whole label.
+func (r *Renderer) WithIcon(b Bounds, icon Icon, text string, opts TextOpts) (*monochrome.Image, error) {
+	if icon == IconNone {
+		return r.Text(b, text, opts)
+	}
+	if _, ok := icons[icon]; !ok {
+		return nil, fmt.Errorf("unknown icon %v", icon)
+	}
+
+	dx := b.Dx - 2*opts.Frame.size(b)
+	return r.beside(b, monochrome.Rotate270Gray(icon.draw(dx)), text, opts)
+}
+
+// WithImage renders text like Text, with img before it, upright like the text and scaled to the height of the tape.
+// opts apply to the whole label.
+func (r *Renderer) WithImage(b Bounds, img image.Image, text string, opts TextOpts) (*monochrome.Image, error) {
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("empty image")
+	}
+
+	gray := monochrome.Rotate270Gray(monochrome.Gray(img))
+	dx := b.Dx - 2*opts.Frame.size(b)
+	dy := max(1, int(math.Round(float64(gray.Bounds().Dy())*float64(dx)/float64(gray.Bounds().Dx()))))
+	return r.beside(b, resize(gray, dx, dy, FilterAuto), text, opts)
+}
+
+// beside renders text like Text, with img as wide as the inside of the frame before it.
+// They're converted to monochrome together.
+func (r *Renderer) beside(b Bounds, img *image.Gray, text string, opts TextOpts) (*monochrome.Image, error) {
+	// Render each part to fit inside the frame, without any minimum length.
+	inner := Bounds{
+		Dx:  b.Dx - 2*opts.Frame.size(b),
+		DPI: b.DPI,
+	}
+
+	// Same margin before the image as the text has.
+	margin := opts.Margin
+	if margin == 0 {
+		margin = defaultMargin
+	}
+	blank := image.NewGray(image.Rect(0, 0, inner.Dx, b.MMToPx(margin)))
+	draw.Draw(blank, blank.Bounds(), image.White, image.Point{}, draw.Src)
+
+	textImg, err := r.textGray(inner, text, opts.partOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	return Image(b, joinGray(blank, img, textImg), opts.imageOpts())
+}
@@ -4,34 +4,284 @@ import (
 	"fmt"
 	"image"
 	"image/draw"
+	"math"
 
 	"go.afab.re/etiquette/monochrome"
 )
 
+// Align is where the contents of a label go along its length,
+// if the label is longer than them.
+type Align int
+
+const (
+	AlignCenter Align = iota
+	// Start of the label, the end that is printed first.
+	AlignStart
+	// End of the label.
+	AlignEnd
+)
+
+func (a Align) String() string {
+	switch a {
+	case AlignCenter:
+		return "center"
+	case AlignStart:
+		return "start"
+	case AlignEnd:
+		return "end"
+	default:
+		return fmt.Sprintf("Align(%d)", int(a))
+	}
+}
+
+// ParseAlign parses the String() of an Align.
+func ParseAlign(s string) (Align, error) {
+	for _, a := range []Align{AlignCenter, AlignStart, AlignEnd} {
+		if s == a.String() {
+			return a, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown alignment %q", s)
+}
+
+// Thresholder converts a grayscale image to monochrome.
+type Thresholder func(gray *image.Gray) *monochrome.Image
+
+type ImageOpts struct {
+	// Length is the minimum length of the label, in mm.
+	// Labels are never shorter than Bounds.MinDy.
+	Length float64
+	// Align is where the image goes if the label is longer than it.
+	Align Align
+
+	// Trim crops white borders from the image before padding it,
+	// so padding in the source image doesn't waste tape.
+	Trim bool
+	// TrimThreshold is the gray level (0-255) from which pixels are considered white when trimming.
+	// If 0, only pure white pixels are trimmed.
+	TrimThreshold uint8
+
+	// Fit scales the image so it spans the width of the tape.
+	Fit bool
+	// Filter is used to scale the image.
+	Filter Filter
+
+	// Sharpen is the amount of unsharp masking applied before the image is converted to monochrome,
+	// to keep thin lines and small text in downscaled images. 1 doubles the contrast of edges.
+	// If 0, the image isn't sharpened.
+	Sharpen float64
+	// Levels adjusts the image before it's converted to monochrome.
+	Levels Levels
+	// Threshold converts the image to monochrome, once it is sharpened and its levels are adjusted.
+	// If nil, the threshold is picked automatically with Otsu's method, or is mid gray if Levels are set.
+	Threshold Thresholder
+	// Despeckle removes black specks, and fills in white pinholes, smaller than this many pixels
+	// once the image is monochrome.
+	Despeckle int
+	// Dilate thickens black areas by this many pixels once the image is monochrome,
+	// so thin lines survive printing. If negative, black areas are thinned instead.
+	Dilate int
+
+	// Frame is drawn around the image.
+	Frame Frame
+}
+
 // Image converts an image to one suitable for printing:
 // - Monochrome.
 // - Padded out to bounds.
-func Image(b Bounds, img image.Image) (*monochrome.Image, error) {
-	return pad(b, monochrome.From(img))
+func Image(b Bounds, img image.Image, opts ImageOpts) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	if opts.Trim {
+		threshold := opts.TrimThreshold
+		if threshold == 0 {
+			threshold = 0xFF
+		}
+		img = trim(img, threshold)
+	}
+
+	minDy := max(b.MinDy, b.MMToPx(opts.Length))
+
+	inner, innerMinDy := opts.Frame.inner(b, minDy)
+	if opts.Fit && img.Bounds().Dx() != inner.Dx && !img.Bounds().Empty() {
+		dy := max(1, int(math.Round(float64(img.Bounds().Dy())*float64(inner.Dx)/float64(img.Bounds().Dx()))))
+		img = resize(monochrome.Gray(img), inner.Dx, dy, opts.Filter)
+	}
+
+	contents, err := pad(inner, innerMinDy, opts.Align, toMonochrome(img, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Frame.Thickness == 0 {
+		return contents, nil
+	}
+
+	framed, err := pad(b, minDy, AlignCenter, contents)
+	if err != nil {
+		return nil, err
+	}
+	opts.Frame.draw(b, framed)
+
+	return framed, nil
+}
+
+// toMonochrome converts img to monochrome, despeckles it, and dilates or erodes it.
+func toMonochrome(img image.Image, opts ImageOpts) *monochrome.Image {
+	mono := threshold(img, opts)
+	if opts.Despeckle > 0 {
+		mono = mono.Despeckle(opts.Despeckle)
+	}
+
+	switch {
+	case opts.Dilate > 0:
+		return mono.Dilate(opts.Dilate)
+	case opts.Dilate < 0:
+		return mono.Erode(-opts.Dilate)
+	default:
+		return mono
+	}
 }
 
-func pad(b Bounds, src *monochrome.Image) (*monochrome.Image, error) {
+// threshold converts img to monochrome, sharpening it and adjusting its levels first if needed.
+func threshold(img image.Image, opts ImageOpts) *monochrome.Image {
+	if opts.Sharpen == 0 && opts.Levels == (Levels{}) && opts.Threshold == nil {
+		return monochrome.From(img)
+	}
+
+	gray := monochrome.Gray(img)
+	if opts.Sharpen != 0 {
+		gray = monochrome.Sharpen(gray, opts.Sharpen)
+	}
+	if opts.Levels != (Levels{}) {
+		gray = opts.Levels.apply(gray)
+	}
+
+	switch {
+	case opts.Threshold != nil:
+		return opts.Threshold(gray)
+	case opts.Levels != (Levels{}):
+		return monochrome.Threshold(gray, levelsThreshold)
+	default:
+		return monochrome.Threshold(gray, monochrome.Otsu(gray))
+	}
+}
+
+// Validate checks imgs can be printed with bounds b.
+func Validate(b Bounds, imgs ...*monochrome.Image) error {
+	for i, img := range imgs {
+		if img.Bounds().Dx() != b.Dx {
+			return fmt.Errorf("label %d: expected %dpx wide image but got %dpx", i, b.Dx, img.Bounds().Dx())
+		}
+		if img.Bounds().Dy() < b.MinDy {
+			return fmt.Errorf("label %d: can't print images shorter than %dpx, got %dpx", i, b.MinDy, img.Bounds().Dy())
+		}
+		if b.MaxDy != 0 && img.Bounds().Dy() > b.MaxDy {
+			return fmt.Errorf("label %d: can't print images longer than %dpx, got %dpx", i, b.MaxDy, img.Bounds().Dy())
+		}
+	}
+
+	return nil
+}
+
+func pad(b Bounds, minDy int, align Align, src *monochrome.Image) (*monochrome.Image, error) {
 	if src.Bounds().Dx() > b.Dx {
 		return nil, fmt.Errorf("expected up to %dpx wide image but got %dx", b.Dx, src.Bounds().Dx())
 	}
 	xPadding := b.Dx - src.Bounds().Dx()
 
-	yPadding := b.MinDy - src.Bounds().Dy()
-	if src.Bounds().Dy() >= b.MinDy {
+	yPadding := minDy - src.Bounds().Dy()
+	if src.Bounds().Dy() >= minDy {
 		yPadding = 0
 	}
 
+	// If padding isn't a multiple of two, give it to the top left.
+	minYPadding, maxYPadding := (yPadding+1)/2, yPadding/2
+	switch align {
+	// Labels are printed from Max.Y.
+	case AlignStart:
+		minYPadding, maxYPadding = yPadding, 0
+	case AlignEnd:
+		minYPadding, maxYPadding = 0, yPadding
+	}
+
 	dst := monochrome.New(image.Rectangle{
-		// If padding isn't a multiple of two, give it to the top left.
-		src.Bounds().Min.Sub(image.Pt((xPadding+1)/2, (yPadding+1)/2)),
-		src.Bounds().Max.Add(image.Pt(xPadding/2, yPadding/2)),
+		src.Bounds().Min.Sub(image.Pt((xPadding+1)/2, minYPadding)),
+		src.Bounds().Max.Add(image.Pt(xPadding/2, maxYPadding)),
 	})
-	draw.Draw(dst, src.Bounds(), src, src.Bounds().Min, draw.Src)
+	monochrome.Draw(dst, src.Bounds(), src, src.Bounds().Min, draw.Src)
 
 	return dst, nil
 }
+
+// trim crops the borders of img made up of pixels at least as light as threshold.
+// Transparent pixels are considered white.
+func trim(img image.Image, threshold uint8) image.Image {
+	b := img.Bounds()
+
+	mono, _ := img.(*monochrome.Image)
+	white := func(x, y int) bool {
+		if mono != nil {
+			return !mono.BlackAt(x, y)
+		}
+
+		return monochrome.Luma(img.At(x, y)) >= threshold
+	}
+	whiteRow := func(y int) bool {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !white(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	whiteCol := func(x, minY, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !white(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	minY := b.Min.Y
+	for minY < b.Max.Y && whiteRow(minY) {
+		minY++
+	}
+	// Nothing but white, nothing sensible to trim to.
+	if minY == b.Max.Y {
+		return img
+	}
+
+	maxY := b.Max.Y
+	for whiteRow(maxY - 1) {
+		maxY--
+	}
+
+	minX := b.Min.X
+	for whiteCol(minX, minY, maxY) {
+		minX++
+	}
+
+	maxX := b.Max.X
+	for whiteCol(maxX-1, minY, maxY) {
+		maxX--
+	}
+
+	return subImage(img, image.Rect(minX, minY, maxX, maxY))
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, r, img, r.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,153 @@
+// Package imagetest compares rendered labels to golden images, to catch changes to how they're drawn.
+//
+// Golden images are PNGs, usually in testdata. Run the tests with -update to write them from the current output,
+// and check the new images before committing them.
+// When an image doesn't match, a diff image is written next to the golden image with a .diff.png extension:
+// black pixels that match are gray, missing black pixels are red, and extra black pixels are blue.
+package imagetest
+
+import (
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+var update = flag.Bool("update", false, "Write golden images from the output of the tests, instead of comparing them.")
+
+// Option configures CompareGolden.
+type Option func(*options)
+
+type options struct {
+	tolerance int
+}
+
+// Tolerance allows up to pixels pixels to differ from the golden image,
+// for output that depends on floating point rounding.
+func Tolerance(pixels int) Option {
+	return func(o *options) {
+		o.tolerance = pixels
+	}
+}
+
+// CompareGolden fails t if got doesn't match the golden image at goldenPath, or writes it there with -update.
+func CompareGolden(t testing.TB, got *monochrome.Image, goldenPath string, opts ...Option) {
+	t.Helper()
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	diffPath := strings.TrimSuffix(goldenPath, filepath.Ext(goldenPath)) + ".diff.png"
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writePNG(goldenPath, got); err != nil {
+			t.Fatal(err)
+		}
+		removeDiff(t, diffPath)
+		return
+	}
+
+	want, err := readPNG(goldenPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("%s doesn't exist, run the test with -update to create it", goldenPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Bounds().Size() != want.Bounds().Size() {
+		t.Fatalf("%s: got a %v image, want %v", goldenPath, got.Bounds().Size(), want.Bounds().Size())
+	}
+
+	diff, n := Diff(got, want)
+	if n <= o.tolerance {
+		removeDiff(t, diffPath)
+		return
+	}
+
+	if err := writePNG(diffPath, diff); err != nil {
+		t.Fatal(err)
+	}
+	t.Errorf("%s: %d pixels differ, more than %d, see %s", goldenPath, n, o.tolerance, diffPath)
+}
+
+// Diff returns an image of the differences between two images of the same size, and the number of pixels that differ.
+func Diff(got, want image.Image) (*image.RGBA, int) {
+	var (
+		white   = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+		same    = color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}
+		missing = color.RGBA{0xFF, 0x00, 0x00, 0xFF}
+		extra   = color.RGBA{0x00, 0x00, 0xFF, 0xFF}
+	)
+
+	size := got.Bounds().Size()
+	diff := image.NewRGBA(image.Rectangle{Max: size})
+	n := 0
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			g := black(got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y))
+			w := black(want.At(want.Bounds().Min.X+x, want.Bounds().Min.Y+y))
+
+			c := white
+			switch {
+			case g && w:
+				c = same
+			case w:
+				c, n = missing, n+1
+			case g:
+				c, n = extra, n+1
+			}
+			diff.SetRGBA(x, y, c)
+		}
+	}
+	return diff, n
+}
+
+func black(c color.Color) bool {
+	return monochrome.Luma(c) < 0x80
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// removeDiff removes the diff image of a previous failure.
+func removeDiff(t testing.TB, path string) {
+	t.Helper()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+}
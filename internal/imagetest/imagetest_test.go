@@ -0,0 +1,50 @@
+package imagetest
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+func TestDiff(t *testing.T) {
+	got := monochrome.New(image.Rect(0, 0, 3, 1))
+	got.SetBlack(0, 0, true)
+	got.SetBlack(2, 0, true)
+
+	// Bounds don't have to match, only sizes.
+	want := monochrome.New(image.Rect(5, 5, 8, 6))
+	want.SetBlack(5, 5, true)
+	want.SetBlack(6, 5, true)
+
+	diff, n := Diff(got, want)
+	if n != 2 {
+		t.Errorf("got %d differing pixels, want 2", n)
+	}
+	for x, c := range []color.RGBA{
+		{0xC0, 0xC0, 0xC0, 0xFF},
+		{0xFF, 0x00, 0x00, 0xFF},
+		{0x00, 0x00, 0xFF, 0xFF},
+	} {
+		if got := diff.RGBAAt(x, 0); got != c {
+			t.Errorf("pixel %d: got %v, want %v", x, got, c)
+		}
+	}
+}
+
+func TestCompareGolden(t *testing.T) {
+	img := monochrome.New(image.Rect(0, 0, 4, 4))
+	img.SetBlack(1, 2, true)
+
+	path := filepath.Join(t.TempDir(), "golden.png")
+	if err := writePNG(path, img); err != nil {
+		t.Fatal(err)
+	}
+	CompareGolden(t, img, path)
+
+	// One pixel off is within a tolerance of one.
+	img.SetBlack(3, 3, true)
+	CompareGolden(t, img, path, Tolerance(1))
+}
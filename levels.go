@@ -0,0 +1,49 @@
+package etiquette
+
+import (
+	"image"
+	"math"
+)
+
+// Levels adjusts images before they are converted to monochrome,
+// to tune dark photos or faint scans.
+// Adjusting levels replaces the automatic (Otsu) threshold with a fixed mid gray one,
+// so the adjustments control which pixels end up black.
+type Levels struct {
+	// Gamma is applied first, values above 1 lighten shadows and below 1 darken them.
+	// If 0, defaults to 1.
+	Gamma float64
+	// Brightness is added to every pixel, from -1 (black) to 1 (white).
+	Brightness float64
+	// Contrast stretches pixels away from mid gray if positive, or towards it if negative, from -1 to 1.
+	Contrast float64
+}
+
+// Mid gray threshold used with Levels.
+const levelsThreshold = 0x7F
+
+// apply the levels to src.
+func (l Levels) apply(src *image.Gray) *image.Gray {
+	gamma := l.Gamma
+	if gamma == 0 {
+		gamma = 1
+	}
+
+	// Precompute every gray level.
+	var lut [256]uint8
+	for i := range lut {
+		v := math.Pow(float64(i)/0xFF, 1/gamma)
+		v += l.Brightness
+		v = (v-0.5)*(1+l.Contrast)/(1-min(l.Contrast, 0.99)) + 0.5
+		lut[i] = uint8(math.Round(0xFF * min(max(v, 0), 1)))
+	}
+
+	gray := image.NewGray(src.Bounds())
+	for y := src.Bounds().Min.Y; y < src.Bounds().Max.Y; y++ {
+		for x := src.Bounds().Min.X; x < src.Bounds().Max.X; x++ {
+			gray.Pix[gray.PixOffset(x, y)] = lut[src.GrayAt(x, y).Y]
+		}
+	}
+
+	return gray
+}
@@ -0,0 +1,75 @@
+// Package media estimates how much tape is left in printers.
+// Printers can't tell how much tape is left, so the tape used by each print is recorded in a file
+// in the user's configuration directory, shared by every program printing with etiquette.
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.afab.re/etiquette/pt700"
+)
+
+// Media tracks the tape used in a printer across runs, and its calibration.
+type Media struct {
+	// Width of the installed tape, if it changes the cassette was swapped.
+	Width pt700.MediaWidth
+	// Cassette is the length of tape installed, in mm.
+	Cassette float64
+	// Used is the length of tape printed since the cassette was installed, in mm.
+	Used float64
+	// PinOffset is the number of pins to shift printing by, picked with the calibrate command.
+	PinOffset int `json:",omitempty"`
+}
+
+// Remaining returns the estimated length of tape left, in mm.
+// ok is false if it can't be estimated: the length of the cassette wasn't recorded, or it was swapped for tape of another width.
+func (m Media) Remaining(width pt700.MediaWidth) (remaining float64, ok bool) {
+	if m.Cassette == 0 || m.Width != width {
+		return 0, false
+	}
+
+	return max(m.Cassette-m.Used, 0), true
+}
+
+// Path is the file the media of every printer is stored in.
+func Path() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "etiquette", "media.json"), nil
+}
+
+// Load loads the media of every printer, keyed by serial:XXXX, or by printer path for printers without a serial number.
+func Load() (map[string]Media, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	return load(path)
+}
+
+func load(path string) (map[string]Media, error) {
+	m := make(map[string]Media)
+
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return m, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return m, nil
+}
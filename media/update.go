@@ -0,0 +1,85 @@
+//go:build linux
+
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Update loads the media of printer, applies update, and saves it.
+// Concurrent updates from other processes are serialized, so none of them are lost.
+func Update(printer string, update func(*Media)) error {
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// media.json is replaced on every update, so lock a separate file.
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := flock(lock); err != nil {
+		return err
+	}
+
+	all, err := load(path)
+	if err != nil {
+		return err
+	}
+
+	m := all[printer]
+	update(&m)
+	all[printer] = m
+
+	return save(path, all)
+}
+
+// flock takes an exclusive lock on f, released when it's closed.
+func flock(f *os.File) error {
+	for {
+		err := unix.Flock(int(f.Fd()), unix.LOCK_EX)
+		if !errors.Is(err, unix.EINTR) {
+			return err
+		}
+	}
+}
+
+// save writes m to a temporary file, and renames it to path so readers never see a partial file.
+func save(path string, m map[string]Media) error {
+	raw, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
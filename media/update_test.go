@@ -0,0 +1,39 @@
+//go:build linux
+
+package media
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestUpdateConcurrent(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	const updates = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- Update("serial:1234", func(m *Media) { m.Used++ })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used := all["serial:1234"].Used; used != updates {
+		t.Errorf("got %vmm used, want %vmm: updates were lost", used, float64(updates))
+	}
+}
@@ -0,0 +1,73 @@
+package monochrome
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Despeckle returns m without black specks smaller than n pixels,
+// and with white pinholes smaller than n pixels filled in.
+// White areas touching the edges of m are background, and never filled in.
+func (m *Image) Despeckle(n int) *Image {
+	dst := New(m.Bounds())
+	Draw(dst, dst.Bounds(), m, m.Bounds().Min, draw.Src)
+
+	dst.removeComponents(n, 1)
+	dst.removeComponents(n, 0)
+
+	return dst
+}
+
+// removeComponents flips connected areas of pixels of color index smaller than n pixels.
+// Black areas are 8-connected, and white areas 4-connected so they can't leak through diagonal black lines.
+func (m *Image) removeComponents(n int, index uint8) {
+	b := m.Bounds()
+	seen := make([]bool, b.Dx()*b.Dy())
+	at := func(p image.Point) int {
+		return (p.Y-b.Min.Y)*b.Dx() + p.X - b.Min.X
+	}
+
+	neighbours := []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	if index == 1 {
+		neighbours = append(neighbours, image.Point{-1, -1}, image.Point{1, -1}, image.Point{-1, 1}, image.Point{1, 1})
+	}
+
+	var component, stack []image.Point
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			start := image.Pt(x, y)
+			if seen[at(start)] || m.ColorIndexAt(x, y) != index {
+				continue
+			}
+
+			// Flood fill the component.
+			component, stack = component[:0], append(stack[:0], start)
+			seen[at(start)] = true
+			edge := false
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				component = append(component, p)
+
+				for _, d := range neighbours {
+					q := p.Add(d)
+					if !q.In(b) {
+						edge = true
+						continue
+					}
+					if !seen[at(q)] && m.ColorIndexAt(q.X, q.Y) == index {
+						seen[at(q)] = true
+						stack = append(stack, q)
+					}
+				}
+			}
+
+			if len(component) >= n || (index == 0 && edge) {
+				continue
+			}
+			for _, p := range component {
+				m.p.SetColorIndex(p.X, p.Y, 1-index)
+			}
+		}
+	}
+}
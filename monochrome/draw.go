@@ -0,0 +1,60 @@
+package monochrome
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Image can be the destination of the draw package, but that converts every pixel to and from colors.
+var _ draw.Image = &Image{}
+
+// Draw copies src onto dst like draw.Draw, but directly without going through colors.
+// With draw.Over, white pixels of src are transparent: only black pixels are copied.
+func Draw(dst *Image, r image.Rectangle, src *Image, sp image.Point, op draw.Op) {
+	r, sp = clip(dst.Bounds(), r, src.Bounds(), sp)
+
+	for y := 0; y < r.Dy(); y++ {
+		d := dst.p.Pix[dst.p.PixOffset(r.Min.X, r.Min.Y+y):][:r.Dx()]
+		s := src.p.Pix[src.p.PixOffset(sp.X, sp.Y+y):][:r.Dx()]
+
+		if op == draw.Src {
+			copy(d, s)
+			continue
+		}
+		for i := range d {
+			d[i] |= s[i]
+		}
+	}
+}
+
+// DrawMask draws black onto dst where mask is at least half opaque,
+// like draw.DrawMask with a black source and draw.Over.
+func DrawMask(dst *Image, r image.Rectangle, mask image.Image, mp image.Point) {
+	r, mp = clip(dst.Bounds(), r, mask.Bounds(), mp)
+
+	alpha, _ := mask.(*image.Alpha)
+	for y := 0; y < r.Dy(); y++ {
+		d := dst.p.Pix[dst.p.PixOffset(r.Min.X, r.Min.Y+y):][:r.Dx()]
+
+		for x := range d {
+			var a uint32
+			if alpha != nil {
+				a = uint32(alpha.AlphaAt(mp.X+x, mp.Y+y).A) * 0x101
+			} else {
+				_, _, _, a = mask.At(mp.X+x, mp.Y+y).RGBA()
+			}
+
+			if a >= 0x8000 {
+				d[x] = 1
+			}
+		}
+	}
+}
+
+// clip r to dst, and to src placed at sp, like draw.Draw does.
+func clip(dst, r, src image.Rectangle, sp image.Point) (image.Rectangle, image.Point) {
+	orig := r.Min
+	r = r.Intersect(dst)
+	r = r.Intersect(src.Add(orig.Sub(sp)))
+	return r, sp.Add(r.Min.Sub(orig))
+}
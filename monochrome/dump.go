@@ -0,0 +1,46 @@
+package monochrome
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Braille dot of each pixel of a 2x4 cell, indexed by y then x.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Dump draws m as text with braille characters, each one a 2x4 block of pixels.
+// Black pixels are raised dots.
+func (m *Image) Dump(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	b := m.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y += 4 {
+		for x := b.Min.X; x < b.Max.X; x += 2 {
+			c := rune(0x2800)
+			for dy := 0; dy < 4 && y+dy < b.Max.Y; dy++ {
+				for dx := 0; dx < 2 && x+dx < b.Max.X; dx++ {
+					if m.BlackAt(x+dx, y+dy) {
+						c |= brailleDots[dy][dx]
+					}
+				}
+			}
+			out.WriteRune(c)
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+// String is the Dump of m.
+func (m *Image) String() string {
+	var sb strings.Builder
+	_ = m.Dump(&sb)
+	return sb.String()
+}
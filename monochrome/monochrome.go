@@ -57,3 +57,70 @@ func (m *Image) SetBlack(x, y int, isBlack bool) {
 		m.p.SetColorIndex(x, y, 0)
 	}
 }
+
+// SubImage returns the part of m visible through r, sharing pixels with m.
+func (m *Image) SubImage(r image.Rectangle) image.Image {
+	return m.Crop(r)
+}
+
+// Crop is SubImage, but returns an *Image.
+func (m *Image) Crop(r image.Rectangle) *Image {
+	return &Image{
+		p: m.p.SubImage(r).(*image.Paletted),
+	}
+}
+
+// FillRect sets all the pixels of r to black or white.
+func (m *Image) FillRect(r image.Rectangle, isBlack bool) {
+	r = r.Intersect(m.Bounds())
+
+	var index uint8
+	if isBlack {
+		index = 1
+	}
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := m.p.Pix[m.p.PixOffset(r.Min.X, y):][:r.Dx()]
+		for i := range row {
+			row[i] = index
+		}
+	}
+}
+
+// CopyRow copies row srcY of src to row y of m, from the Min.X of both.
+// The row is truncated to the narrowest of the two images.
+func (m *Image) CopyRow(y int, src *Image, srcY int) {
+	dx := min(m.Bounds().Dx(), src.Bounds().Dx())
+	copy(
+		m.p.Pix[m.p.PixOffset(m.Bounds().Min.X, y):][:dx],
+		src.p.Pix[src.p.PixOffset(src.Bounds().Min.X, srcY):][:dx],
+	)
+}
+
+// SetRow sets row y from packed bits, 1 for black, most significant bit first, from Min.X.
+// Bits past the width of m are ignored.
+func (m *Image) SetRow(y int, bits []byte) {
+	row := m.p.Pix[m.p.PixOffset(m.Bounds().Min.X, y):][:m.Bounds().Dx()]
+	for i := range row {
+		if i/8 >= len(bits) {
+			break
+		}
+		row[i] = (bits[i/8] >> (7 - i%8)) & 1
+	}
+}
+
+// Equal reports whether m and o are the same size with the same pixels, even if their bounds are offset.
+func (m *Image) Equal(o *Image) bool {
+	if m.Bounds().Size() != o.Bounds().Size() {
+		return false
+	}
+
+	for y := 0; y < m.Bounds().Dy(); y++ {
+		mRow := m.p.Pix[m.p.PixOffset(m.Bounds().Min.X, m.Bounds().Min.Y+y):][:m.Bounds().Dx()]
+		oRow := o.p.Pix[o.p.PixOffset(o.Bounds().Min.X, o.Bounds().Min.Y+y):][:o.Bounds().Dx()]
+		if string(mRow) != string(oRow) {
+			return false
+		}
+	}
+	return true
+}
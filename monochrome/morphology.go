@@ -0,0 +1,72 @@
+package monochrome
+
+// Dilate returns m with black areas grown by r pixels in every direction,
+// to make thin lines survive printing.
+func (m *Image) Dilate(r int) *Image {
+	return m.grow(r, 1)
+}
+
+// Erode returns m with black areas shrunk by r pixels in every direction.
+// Pixels outside of m are white, so black areas touching the edges shrink too.
+func (m *Image) Erode(r int) *Image {
+	return m.grow(r, 0)
+}
+
+// Open erodes then dilates m, removing black specks smaller than r pixels.
+func (m *Image) Open(r int) *Image {
+	return m.Erode(r).Dilate(r)
+}
+
+// Close dilates then erodes m, filling white holes smaller than r pixels.
+func (m *Image) Close(r int) *Image {
+	return m.Dilate(r).Erode(r)
+}
+
+// grow grows areas of pixels with color index by r pixels, in a square.
+// Squares are separable, so it's done along rows and then along columns.
+func (m *Image) grow(r int, index uint8) *Image {
+	b := m.Bounds()
+
+	rows := New(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		growLine(b.Dx(), r, index,
+			func(i int) uint8 { return m.ColorIndexAt(b.Min.X+i, y) },
+			func(i int, v uint8) { rows.p.SetColorIndex(b.Min.X+i, y, v) },
+		)
+	}
+
+	dst := New(b)
+	for x := b.Min.X; x < b.Max.X; x++ {
+		growLine(b.Dy(), r, index,
+			func(i int) uint8 { return rows.ColorIndexAt(x, b.Min.Y+i) },
+			func(i int, v uint8) { dst.p.SetColorIndex(x, b.Min.Y+i, v) },
+		)
+	}
+
+	return dst
+}
+
+// growLine sets every pixel of a line of n pixels within r of a pixel of color index to it.
+// Pixels outside of the line are white.
+func growLine(n, r int, index uint8, get func(i int) uint8, set func(i int, v uint8)) {
+	// Number of pixels of color index before i.
+	before := make([]int, n+1)
+	for i := 0; i < n; i++ {
+		before[i+1] = before[i]
+		if get(i) == index {
+			before[i+1]++
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		lo, hi := max(i-r, 0), min(i+r+1, n)
+
+		// White pixels past the edges count for erosion.
+		found := before[hi]-before[lo] > 0 || (index == 0 && (i-r < 0 || i+r+1 > n))
+		if found {
+			set(i, index)
+		} else {
+			set(i, 1-index)
+		}
+	}
+}
@@ -3,38 +3,66 @@ package monochrome
 import (
 	"image"
 	"image/color"
-	"image/draw"
+	"runtime"
+	"sync"
 )
 
 // From converts an image to monochrome with Otsu thresholding.
 // https://en.wikipedia.org/wiki/Otsu%27s_method
 func From(img image.Image) *Image {
-	// First get a grayscale image.
-	var gray *image.Gray
-	switch i := img.(type) {
-	case *Image:
-		return i
-	case *image.Gray:
-		gray = i
-	default:
-		// In case the image is transparent, overlay it over a white background to ensure
-		// transparent pixels are converted to white(ish).
-		// Otherwise black transparent pixels (RGBA(0,0,0,0) end up black when converted to image.Gray.
-		gray = image.NewGray(img.Bounds())
-		draw.Draw(gray, gray.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
-		draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Over)
+	if mono, ok := img.(*Image); ok {
+		return mono
 	}
 
-	// Then figure out the threshold and turn it into monochrome
-	threshold := otsuThreshold(gray)
+	gray := Gray(img)
+	return Threshold(gray, otsuThreshold(gray))
+}
+
+// Gray converts an image to grayscale with Luma. Transparent pixels are white.
+func Gray(img image.Image) *image.Gray {
+	if gray, ok := img.(*image.Gray); ok {
+		return gray
+	}
+
+	gray := image.NewGray(img.Bounds())
+	bands(img.Bounds(), func(minY, maxY int) {
+		for y := minY; y < maxY; y++ {
+			for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+				gray.Pix[gray.PixOffset(x, y)] = Luma(img.At(x, y))
+			}
+		}
+	})
+	return gray
+}
 
+// Luma returns the perceptual brightness of c with Rec. 601 weights, the same as color.GrayModel,
+// after overlaying it on a white background.
+// Otherwise black transparent pixels (RGBA(0,0,0,0)) would end up black.
+func Luma(c color.Color) uint8 {
+	// Premultiplied, so overlaying on white is adding the transparency.
+	r, g, b, a := c.RGBA()
+	r, g, b = r+0xFFFF-a, g+0xFFFF-a, b+0xFFFF-a
+
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
+
+// Otsu returns the threshold that best separates the light and dark pixels of img:
+// pixels at most as light as it should be black.
+func Otsu(img image.Image) uint8 {
+	return otsuThreshold(Gray(img))
+}
+
+// Threshold converts a grayscale image to monochrome: pixels at most as light as threshold are black.
+func Threshold(gray *image.Gray, threshold uint8) *Image {
 	mono := New(gray.Bounds())
 
-	for x := gray.Bounds().Min.X; x < gray.Bounds().Max.X; x++ {
-		for y := gray.Bounds().Min.Y; y < gray.Bounds().Max.Y; y++ {
-			mono.SetBlack(x, y, gray.GrayAt(x, y).Y <= threshold)
+	bands(gray.Bounds(), func(minY, maxY int) {
+		for y := minY; y < maxY; y++ {
+			for x := gray.Bounds().Min.X; x < gray.Bounds().Max.X; x++ {
+				mono.SetBlack(x, y, gray.GrayAt(x, y).Y <= threshold)
+			}
 		}
-	}
+	})
 
 	return mono
 }
@@ -86,17 +114,59 @@ func otsuThreshold(i *image.Gray) uint8 {
 }
 
 func intensityHistogram(i *image.Gray) [256]int {
-	var histo [256]int
+	// One histogram per band, summed once they're all done so the result doesn't depend on scheduling.
+	var (
+		mu     sync.Mutex
+		histos [][256]int
+	)
+	bands(i.Bounds(), func(minY, maxY int) {
+		var histo [256]int
+		for y := minY; y < maxY; y++ {
+			for x := i.Bounds().Min.X; x < i.Bounds().Max.X; x++ {
+				histo[i.GrayAt(x, y).Y]++
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		histos = append(histos, histo)
+	})
 
-	for x := i.Bounds().Min.X; x < i.Bounds().Max.X; x++ {
-		for y := i.Bounds().Min.Y; y < i.Bounds().Max.Y; y++ {
-			histo[i.At(x, y).(color.Gray).Y]++
+	var histo [256]int
+	for _, h := range histos {
+		for intensity, pixels := range h {
+			histo[intensity] += pixels
 		}
 	}
 
 	return histo
 }
 
+// Images smaller than this many pixels aren't worth splitting into bands.
+const minParallelPixels = 1 << 16
+
+// bands splits the rows of r into a band per CPU, and calls f for each band in parallel.
+func bands(r image.Rectangle, f func(minY, maxY int)) {
+	n := runtime.GOMAXPROCS(0)
+	if r.Dx()*r.Dy() < minParallelPixels {
+		n = 1
+	}
+	n = max(1, min(n, r.Dy()))
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		minY := r.Min.Y + i*r.Dy()/n
+		maxY := r.Min.Y + (i+1)*r.Dy()/n
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f(minY, maxY)
+		}()
+	}
+	wg.Wait()
+}
+
 func square(a int) int {
 	return a * a
 }
@@ -0,0 +1,115 @@
+package monochrome
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// bimodal returns an image with half its pixels around dark, and half around light.
+func bimodal(r image.Rectangle, dark, light uint8) *image.Gray {
+	img := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := dark
+			if x < (r.Min.X+r.Max.X)/2 {
+				v = light
+			}
+			// A little noise, so the modes aren't a single intensity.
+			img.SetGray(x, y, color.Gray{v + uint8((x+y)%5)})
+		}
+	}
+	return img
+}
+
+func TestOtsu(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		dark, light uint8
+	}{
+		{"black and white", 0, 250},
+		{"grays", 60, 180},
+		{"close", 100, 120},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			img := bimodal(image.Rect(3, 7, 67, 39), test.dark, test.light)
+
+			threshold := Otsu(img)
+			// Noise adds up to 4.
+			if threshold < test.dark+4 || threshold >= test.light {
+				t.Fatalf("got threshold %d, want it between %d and %d", threshold, test.dark+4, test.light)
+			}
+
+			mono := Threshold(img, threshold)
+			if mono.Bounds() != img.Bounds() {
+				t.Fatalf("got bounds %v, want %v", mono.Bounds(), img.Bounds())
+			}
+			for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+				for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+					if want := x >= (img.Bounds().Min.X+img.Bounds().Max.X)/2; mono.BlackAt(x, y) != want {
+						t.Fatalf("(%d, %d): got black %v, want %v", x, y, mono.BlackAt(x, y), want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFrom(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.Black)
+	img.Set(1, 0, color.White)
+	img.Set(2, 0, color.NRGBA{0x20, 0x20, 0x20, 0xFF})
+	// Transparent pixels are white, whatever their color.
+	img.Set(3, 0, color.NRGBA{0, 0, 0, 0})
+
+	mono := From(img)
+	for x, want := range []bool{true, false, true, false} {
+		if got := mono.BlackAt(x, 0); got != want {
+			t.Errorf("pixel %d: got black %v, want %v", x, got, want)
+		}
+	}
+
+	// Monochrome images are returned as is.
+	if From(mono) != mono {
+		t.Errorf("monochrome image was converted")
+	}
+}
+
+// photo returns a photo sized image, with a smooth gradient so every intensity is used.
+func photo() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	for y := 0; y < 1500; y++ {
+		for x := 0; x < 2000; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x + y), 0xFF})
+		}
+	}
+	return img
+}
+
+func BenchmarkFrom(b *testing.B) {
+	img := photo()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		From(img)
+	}
+}
+
+func BenchmarkOtsu(b *testing.B) {
+	gray := Gray(photo())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Otsu(gray)
+	}
+}
+
+func BenchmarkThreshold(b *testing.B) {
+	gray := Gray(photo())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Threshold(gray, 0x80)
+	}
+}
@@ -0,0 +1,115 @@
+package monochrome
+
+import (
+	"image"
+)
+
+// Rotate90 returns m rotated 90° clockwise, with the X and Y of its bounds swapped.
+func (m *Image) Rotate90() *Image {
+	dst := New(transposed(m.Bounds()))
+	rotate90(pixelsOf(m.p), pixelsOf(dst.p))
+	return dst
+}
+
+// Rotate180 returns m turned upside down, with the same bounds.
+func (m *Image) Rotate180() *Image {
+	dst := New(m.Bounds())
+	rotate180(pixelsOf(m.p), pixelsOf(dst.p))
+	return dst
+}
+
+// Rotate270 returns m rotated 90° counter-clockwise, with the X and Y of its bounds swapped.
+func (m *Image) Rotate270() *Image {
+	dst := New(transposed(m.Bounds()))
+	rotate270(pixelsOf(m.p), pixelsOf(dst.p))
+	return dst
+}
+
+// FlipH returns m mirrored left to right.
+func (m *Image) FlipH() *Image {
+	dst := New(m.Bounds())
+	flipH(pixelsOf(m.p), pixelsOf(dst.p))
+	return dst
+}
+
+// FlipV returns m mirrored top to bottom.
+func (m *Image) FlipV() *Image {
+	dst := New(m.Bounds())
+	flipV(pixelsOf(m.p), pixelsOf(dst.p))
+	return dst
+}
+
+// Rotate270Gray is Rotate270 for grayscale images, to rotate them before they're converted to monochrome.
+func Rotate270Gray(gray *image.Gray) *image.Gray {
+	dst := image.NewGray(transposed(gray.Bounds()))
+	rotate270(pixels{gray.Pix, gray.Stride, gray.Rect}, pixels{dst.Pix, dst.Stride, dst.Rect})
+	return dst
+}
+
+// pixels are one byte per pixel, the layout of both image.Paletted and image.Gray.
+type pixels struct {
+	pix    []uint8
+	stride int
+	rect   image.Rectangle
+}
+
+func pixelsOf(p *image.Paletted) pixels {
+	return pixels{p.Pix, p.Stride, p.Rect}
+}
+
+// row returns the pixels of the y-th row from the top, y starting at 0.
+func (p pixels) row(y int) []uint8 {
+	return p.pix[y*p.stride:][:p.rect.Dx()]
+}
+
+func transposed(r image.Rectangle) image.Rectangle {
+	return image.Rect(r.Min.Y, r.Min.X, r.Max.Y, r.Max.X)
+}
+
+// The rotations and flips write each row of dst in turn, reading src by row or by column.
+
+func rotate90(src, dst pixels) {
+	h := src.rect.Dy()
+	for y := 0; y < dst.rect.Dy(); y++ {
+		row := dst.row(y)
+		for x := range row {
+			row[x] = src.pix[(h-1-x)*src.stride+y]
+		}
+	}
+}
+
+func rotate180(src, dst pixels) {
+	h := src.rect.Dy()
+	for y := 0; y < h; y++ {
+		from, row := src.row(h-1-y), dst.row(y)
+		for x := range row {
+			row[x] = from[len(from)-1-x]
+		}
+	}
+}
+
+func rotate270(src, dst pixels) {
+	w := src.rect.Dx()
+	for y := 0; y < dst.rect.Dy(); y++ {
+		row := dst.row(y)
+		for x := range row {
+			row[x] = src.pix[x*src.stride+w-1-y]
+		}
+	}
+}
+
+func flipH(src, dst pixels) {
+	for y := 0; y < src.rect.Dy(); y++ {
+		from, row := src.row(y), dst.row(y)
+		for x := range row {
+			row[x] = from[len(from)-1-x]
+		}
+	}
+}
+
+func flipV(src, dst pixels) {
+	h := src.rect.Dy()
+	for y := 0; y < h; y++ {
+		copy(dst.row(y), src.row(h-1-y))
+	}
+}
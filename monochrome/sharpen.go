@@ -0,0 +1,39 @@
+package monochrome
+
+import (
+	"image"
+	"math"
+)
+
+// Sharpen returns gray with an unsharp mask applied: the difference between each pixel
+// and the average of its 3x3 neighbourhood is boosted by amount.
+func Sharpen(gray *image.Gray, amount float64) *image.Gray {
+	b := gray.Bounds()
+	dst := image.NewGray(b)
+
+	// at clamps to the edges of the image, so they aren't darkened or lightened.
+	at := func(x, y int) int {
+		x = min(max(x, b.Min.X), b.Max.X-1)
+		y = min(max(y, b.Min.Y), b.Max.Y-1)
+		return int(gray.Pix[gray.PixOffset(x, y)])
+	}
+
+	bands(b, func(minY, maxY int) {
+		for y := minY; y < maxY; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				sum := 0
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						sum += at(x+dx, y+dy)
+					}
+				}
+
+				v := float64(at(x, y))
+				v += amount * (v - float64(sum)/9)
+				dst.Pix[dst.PixOffset(x, y)] = uint8(math.Round(min(max(v, 0), 0xFF)))
+			}
+		}
+	})
+
+	return dst
+}
@@ -0,0 +1,134 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"sort"
+
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/pt700"
+)
+
+type PlanOpts struct {
+	// MaxLength is the longest page several labels are put together on, in mm.
+	// If 0, labels are only put together on pages up to the minimum length the printer can print,
+	// which uses the least tape. Longer pages only save cuts.
+	MaxLength float64
+	// Reorder lets labels be printed in a different order, to fit them on fewer pages.
+	Reorder bool
+}
+
+// Plan is how to print labels as one job with as little tape and as few cuts as possible.
+type Plan struct {
+	// Pages to print, each made of one or more labels separated by dotted lines to cut them apart with scissors.
+	Pages []*monochrome.Image
+	// Labels are the indexes of the labels on each page, from the start of the page.
+	Labels [][]int
+
+	// Length is the length of tape the pages use, in mm, including the leader at the start of the job.
+	Length float64
+	// Unmerged is the length of tape printing each label on its own page of one job uses, in mm.
+	Unmerged float64
+	// Separate is the length of tape printing each label as its own job uses, in mm.
+	Separate float64
+}
+
+// Cuts is the number of cuts the printer makes, one after each page.
+func (p Plan) Cuts() int {
+	return len(p.Pages)
+}
+
+// PlanJob puts labels together on pages, to use less tape and fewer cuts printing them.
+// The printer has a leader of blank tape at the start of each job, and pads pages shorter than b.MinDy,
+// so labels should be rendered with a MinDy of 0 and printed as the pages of one job.
+// Labels longer than a page are printed on their own.
+func PlanJob(b Bounds, imgs []*monochrome.Image, opts PlanOpts) (Plan, error) {
+	if err := b.check(); err != nil {
+		return Plan{}, err
+	}
+
+	limit := max(b.MinDy, b.MMToPx(opts.MaxLength))
+	if b.MaxDy != 0 {
+		limit = min(limit, b.MaxDy)
+	}
+	gap := b.MMToPx(cutGap)
+
+	order := make([]int, len(imgs))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.Reorder {
+		// First fit decreasing: longest labels first, each on the first page it fits on.
+		sort.SliceStable(order, func(i, j int) bool {
+			return imgs[order[i]].Bounds().Dy() > imgs[order[j]].Bounds().Dy()
+		})
+	}
+
+	var plan Plan
+	var lengths []int
+	var unmerged []*monochrome.Image
+	for _, i := range order {
+		img := imgs[i]
+		if img.Bounds().Dx() != b.Dx {
+			return Plan{}, fmt.Errorf("label %d: expected %dpx wide image but got %dpx", i, b.Dx, img.Bounds().Dx())
+		}
+		dy := img.Bounds().Dy()
+
+		padded, err := pad(b, b.MinDy, AlignCenter, img)
+		if err != nil {
+			return Plan{}, err
+		}
+		unmerged = append(unmerged, padded)
+		plan.Separate += pt700.JobLength(padded)
+
+		// Without reordering, labels can only go on the last page.
+		first := 0
+		if !opts.Reorder {
+			first = max(0, len(lengths)-1)
+		}
+
+		page := -1
+		for p := first; p < len(lengths); p++ {
+			if lengths[p]+gap+dy <= limit {
+				page = p
+				break
+			}
+		}
+
+		if page == -1 {
+			plan.Labels = append(plan.Labels, []int{i})
+			lengths = append(lengths, dy)
+			continue
+		}
+		plan.Labels[page] = append(plan.Labels[page], i)
+		lengths[page] += gap + dy
+	}
+
+	for p, labels := range plan.Labels {
+		page := monochrome.New(image.Rect(0, 0, b.Dx, lengths[p]))
+
+		// Labels start at Max.Y.
+		y := lengths[p]
+		for j, i := range labels {
+			if j != 0 {
+				y -= gap
+				drawCutLine(page, image.Rect(0, y, b.Dx, y+gap))
+			}
+
+			img := imgs[i]
+			y -= img.Bounds().Dy()
+			monochrome.Draw(page, image.Rect(0, y, b.Dx, y+img.Bounds().Dy()), img, img.Bounds().Min, draw.Src)
+		}
+
+		page, err := pad(b, b.MinDy, AlignCenter, page)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Pages = append(plan.Pages, page)
+	}
+
+	plan.Length = pt700.JobLength(plan.Pages...)
+	plan.Unmerged = pt700.JobLength(unmerged...)
+	return plan, nil
+}
@@ -0,0 +1,52 @@
+package etiquette
+
+import (
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/pt700"
+)
+
+// Printer is a label printer, such as a *pt700.PT700.
+type Printer interface {
+	Status() (pt700.Status, error)
+	Print(opts pt700.PrintOpts, imgs ...*monochrome.Image) (pt700.PrintResult, error)
+}
+
+// MediaBounds returns the Bounds of labels printed on tape of width.
+func MediaBounds(width pt700.MediaWidth) (Bounds, error) {
+	dx, err := width.Dx()
+	if err != nil {
+		return Bounds{}, err
+	}
+
+	return Bounds{
+		Dx:    dx,
+		MinDy: width.MinDy(),
+		MaxDy: width.MaxDy(),
+		DPI:   width.DPI(),
+	}, nil
+}
+
+// PrintImage converts img for the tape loaded in p with Image, and prints it.
+func PrintImage(p Printer, img image.Image, opts ImageOpts, printOpts pt700.PrintOpts) (pt700.PrintResult, error) {
+	status, err := p.Status()
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+	if err := status.Err(); err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	b, err := MediaBounds(status.MediaWidth)
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	mono, err := Image(b, img, opts)
+	if err != nil {
+		return pt700.PrintResult{}, err
+	}
+
+	return p.Print(printOpts, mono)
+}
@@ -0,0 +1,159 @@
+package pt700
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+)
+
+// TapeColor is the color of the tape in the cassette, reported by some models.
+type TapeColor uint8
+
+// From the status information of the Brother PDF.
+const (
+	TapeNone              TapeColor = 0x00
+	TapeWhite             TapeColor = 0x01
+	TapeOther             TapeColor = 0x02
+	TapeClear             TapeColor = 0x03
+	TapeRed               TapeColor = 0x04
+	TapeBlue              TapeColor = 0x05
+	TapeYellow            TapeColor = 0x06
+	TapeGreen             TapeColor = 0x07
+	TapeBlack             TapeColor = 0x08
+	TapeClearWhiteText    TapeColor = 0x09
+	TapeMatteWhite        TapeColor = 0x20
+	TapeMatteClear        TapeColor = 0x21
+	TapeMatteSilver       TapeColor = 0x22
+	TapeSatinGold         TapeColor = 0x23
+	TapeSatinSilver       TapeColor = 0x24
+	TapeFluorescentOrange TapeColor = 0x40
+	TapeFluorescentYellow TapeColor = 0x41
+	TapeHeatShrinkWhite   TapeColor = 0x70
+	TapeFlexibleIDWhite   TapeColor = 0x90
+	TapeFlexibleIDYellow  TapeColor = 0x91
+	TapeCleaning          TapeColor = 0xF0
+	TapeStencil           TapeColor = 0xF1
+	TapeIncompatible      TapeColor = 0xFF
+)
+
+var tapeColors = map[TapeColor]struct {
+	name string
+	rgba color.RGBA
+}{
+	TapeWhite:             {"White", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TapeOther:             {"Other", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TapeClear:             {"Clear", color.RGBA{0xF0, 0xF0, 0xF0, 0xFF}},
+	TapeRed:               {"Red", color.RGBA{0xD0, 0x20, 0x20, 0xFF}},
+	TapeBlue:              {"Blue", color.RGBA{0x20, 0x60, 0xC0, 0xFF}},
+	TapeYellow:            {"Yellow", color.RGBA{0xFF, 0xDD, 0x00, 0xFF}},
+	TapeGreen:             {"Green", color.RGBA{0x30, 0xA0, 0x40, 0xFF}},
+	TapeBlack:             {"Black", color.RGBA{0x10, 0x10, 0x10, 0xFF}},
+	TapeClearWhiteText:    {"ClearWhiteText", color.RGBA{0xF0, 0xF0, 0xF0, 0xFF}},
+	TapeMatteWhite:        {"MatteWhite", color.RGBA{0xF8, 0xF8, 0xF4, 0xFF}},
+	TapeMatteClear:        {"MatteClear", color.RGBA{0xE8, 0xE8, 0xE8, 0xFF}},
+	TapeMatteSilver:       {"MatteSilver", color.RGBA{0xC0, 0xC0, 0xC0, 0xFF}},
+	TapeSatinGold:         {"SatinGold", color.RGBA{0xD4, 0xAF, 0x37, 0xFF}},
+	TapeSatinSilver:       {"SatinSilver", color.RGBA{0xC8, 0xC8, 0xC8, 0xFF}},
+	TapeFluorescentOrange: {"FluorescentOrange", color.RGBA{0xFF, 0x80, 0x20, 0xFF}},
+	TapeFluorescentYellow: {"FluorescentYellow", color.RGBA{0xEE, 0xFF, 0x30, 0xFF}},
+	TapeHeatShrinkWhite:   {"HeatShrinkWhite", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TapeFlexibleIDWhite:   {"FlexibleIDWhite", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TapeFlexibleIDYellow:  {"FlexibleIDYellow", color.RGBA{0xFF, 0xDD, 0x00, 0xFF}},
+	TapeCleaning:          {"Cleaning", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TapeStencil:           {"Stencil", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+}
+
+func (c TapeColor) String() string {
+	switch c {
+	case TapeNone:
+		return "None"
+	case TapeIncompatible:
+		return "Incompatible"
+	}
+	if tc, ok := tapeColors[c]; ok {
+		return tc.name
+	}
+	return fmt.Sprintf("Unknown(0x%x)", uint8(c))
+}
+
+// RGBA approximates the color, for previews. Unknown colors are white.
+func (c TapeColor) RGBA() color.RGBA {
+	if tc, ok := tapeColors[c]; ok {
+		return tc.rgba
+	}
+	return color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+}
+
+// TextColor is the color of the ink ribbon in the cassette, reported by some models.
+type TextColor uint8
+
+// From the status information of the Brother PDF.
+const (
+	TextNone         TextColor = 0x00
+	TextWhite        TextColor = 0x01
+	TextOther        TextColor = 0x02
+	TextRed          TextColor = 0x04
+	TextBlue         TextColor = 0x05
+	TextBlack        TextColor = 0x08
+	TextGold         TextColor = 0x0A
+	TextCleaning     TextColor = 0xF0
+	TextStencil      TextColor = 0xF1
+	TextIncompatible TextColor = 0xFF
+)
+
+var textColors = map[TextColor]struct {
+	name string
+	rgba color.RGBA
+}{
+	TextWhite: {"White", color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}},
+	TextOther: {"Other", color.RGBA{0x00, 0x00, 0x00, 0xFF}},
+	TextRed:   {"Red", color.RGBA{0xD0, 0x20, 0x20, 0xFF}},
+	TextBlue:  {"Blue", color.RGBA{0x20, 0x40, 0xB0, 0xFF}},
+	TextBlack: {"Black", color.RGBA{0x00, 0x00, 0x00, 0xFF}},
+	TextGold:  {"Gold", color.RGBA{0xC8, 0x9B, 0x2C, 0xFF}},
+}
+
+func (c TextColor) String() string {
+	switch c {
+	case TextNone:
+		return "None"
+	case TextCleaning:
+		return "Cleaning"
+	case TextStencil:
+		return "Stencil"
+	case TextIncompatible:
+		return "Incompatible"
+	}
+	if tc, ok := textColors[c]; ok {
+		return tc.name
+	}
+	return fmt.Sprintf("Unknown(0x%x)", uint8(c))
+}
+
+// RGBA approximates the color, for previews. Unknown colors are black.
+func (c TextColor) RGBA() color.RGBA {
+	if tc, ok := textColors[c]; ok {
+		return tc.rgba
+	}
+	return color.RGBA{0x00, 0x00, 0x00, 0xFF}
+}
+
+// ParseTapeColor parses the String() of a TapeColor, ignoring case.
+func ParseTapeColor(s string) (TapeColor, error) {
+	for c := range tapeColors {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown tape color %q", s)
+}
+
+// ParseTextColor parses the String() of a TextColor, ignoring case.
+func ParseTextColor(s string) (TextColor, error) {
+	for c := range textColors {
+		if strings.EqualFold(s, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown text color %q", s)
+}
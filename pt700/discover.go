@@ -0,0 +1,64 @@
+//go:build linux
+
+package pt700
+
+import (
+	"go.afab.re/etiquette/usblp"
+)
+
+// BrotherVendorID is the USB vendor ID of Brother printers.
+const BrotherVendorID = 0x04f9
+
+// Models supported by this package, by USB product ID.
+// They all have the same 128 pin, 180dpi print head and raster commands.
+var models = map[uint16]string{
+	0x2060: "PT-E550W",
+	0x2061: "PT-P700",
+	0x2062: "PT-P750W",
+}
+
+// Desc describes a connected printer supported by this package, without opening it.
+type Desc struct {
+	usblp.Device
+	// Model name of the printer.
+	Model string
+}
+
+// Discover returns the connected printers supported by this package.
+// The printers aren't opened, so they can still be used by other software.
+// Devices that can't be read are skipped and reported in warnings.
+func Discover() (descs []Desc, warnings []error, err error) {
+	devices, warnings, err := usblp.Connected()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, device := range devices {
+		if device.VendorID != BrotherVendorID {
+			continue
+		}
+
+		model, ok := models[device.ProductID]
+		if !ok {
+			continue
+		}
+
+		descs = append(descs, Desc{
+			Device: device,
+			Model:  model,
+		})
+	}
+
+	return descs, warnings, nil
+}
+
+// Open opens the printer.
+func (d Desc) Open(opts OpenOpts) (*PT700, error) {
+	p, err := Open(d.Path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p.model = d.Model
+	return p, nil
+}
@@ -0,0 +1,157 @@
+package pt700
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+var (
+	// Invalidate. Brother docs 2.1.1 sends 100 bytes, so we do too.
+	invalidate = make([]byte, 100)
+	// Initialize. This seems to start the print job.
+	initialize = []byte{0x1B, 0x40}
+)
+
+// Encode returns the bytes Print would send to print imgs on tape of width,
+// without the status requests.
+// Writing them to a printer as is, for example with cat job.bin > /dev/usb/lpN, prints the labels.
+// Only the PinOffset of opts is used.
+func Encode(width MediaWidth, opts PrintOpts, imgs ...*monochrome.Image) ([]byte, error) {
+	if err := checkImgs(width, imgs...); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(invalidate)
+	buf.Write(initialize)
+
+	write := func(b []byte) error {
+		buf.Write(b)
+		return nil
+	}
+	for i, img := range imgs {
+		if err := encodePage(width, pagePosition(i, len(imgs)), img, opts.PinOffset, write); err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodePage writes the commands to print img as a page with write, up to and including the print command.
+func encodePage(width MediaWidth, pos pagePos, img *monochrome.Image, pinOffset int, write func([]byte) error) error {
+	// Control codes (Brother PDF 2.1.2).
+	// Raster mode.
+	if err := write([]byte{0x1B, 0x69, 0x61, 0x01}); err != nil {
+		return fmt.Errorf("enabling raster mode: %w", err)
+	}
+
+	// Print information
+	info := []byte{
+		0x1B, 0x69, 0x7A,
+		// Only validate the media width in case the tape has changed,
+		// the type and length don't really matter,
+		// PrinterRecovery should always be on according to the manual.
+		0x84,
+		0x00,        // Type, we don't request validation.
+		byte(width), // Media width mm.
+		0x00,        // Media length mm, we don't request validation.
+	}
+	// Number of raster lines (height of image).
+	info = binary.LittleEndian.AppendUint32(info, uint32(img.Bounds().Dy()))
+	// Starting page or not.
+	if pos&first != 0 {
+		info = append(info, 0x00)
+	} else {
+		info = append(info, 0x01)
+	}
+	// n10: always 0.
+	info = append(info, 0x00)
+	if err := write(info); err != nil {
+		return fmt.Errorf("print information: %w", err)
+	}
+
+	// Mode settings.
+	if err := write([]byte{
+		0x1B, 0x69, 0x4D,
+		// Enable auto cut.
+		0x40,
+	}); err != nil {
+		return fmt.Errorf("mode settings: %w", err)
+	}
+
+	// Advanced mode settings.
+	if err := write([]byte{
+		0x1B, 0x69, 0x4B,
+		// "Chain-printing" lets the printer print several jobs in a row,
+		// by not feeding out the label and cutting it for the last page.
+		// We print all the labels as pages of one job, so we actually don't want chain-printing.
+		0x08,
+	}); err != nil {
+		return fmt.Errorf("advanced mode settings: %w", err)
+	}
+
+	// Margin.
+	if err := write([]byte{
+		// Manual says min 2mm margins (14 dots) in 2.3.3. But 0 seems to work fine.
+		// Lets the caller deal with margins themselves.
+		0x1B, 0x69, 0x64, 0x00, 0x00,
+	}); err != nil {
+		return fmt.Errorf("margins: %w", err)
+	}
+
+	// Compression.
+	if err := write([]byte{
+		0x4D,
+		// No compression.
+		0x00,
+	}); err != nil {
+		return fmt.Errorf("compression: %w", err)
+	}
+
+	// Raster data.
+	if err := printRaster(width, img, pinOffset, write); err != nil {
+		return fmt.Errorf("raster: %w", err)
+	}
+
+	// Print.
+	printCmd := byte(0x0C)
+	if pos&last != 0 {
+		// Print and feed.
+		printCmd = 0x1A
+	}
+	if err := write([]byte{printCmd}); err != nil {
+		return fmt.Errorf("print: %w", err)
+	}
+
+	return nil
+}
+
+func printRaster(width MediaWidth, img *monochrome.Image, pinOffset int, write func([]byte) error) error {
+	// Print bottom line first.
+	for y := img.Bounds().Max.Y; y > img.Bounds().Min.Y; y-- {
+		if err := rasterLine(width, img, y, pinOffset, write); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rasterLine(width MediaWidth, img *monochrome.Image, y int, pinOffset int, write func([]byte) error) error {
+	// Only the middle pins are used for printing, offset everything.
+	geometry, err := width.Geometry(pins)
+	if err != nil {
+		return err
+	}
+	geometry.Offset = pinOffset
+
+	line := geometry.Line(img, y)
+
+	// Manual says 0x67! But that doesn't work, and the example
+	// in 2.2.3 uses 0x47.
+	return write(append([]byte{0x47, byte(len(line)), byte(len(line) >> 8)}, line...))
+}
@@ -0,0 +1,38 @@
+package pt700
+
+import (
+	"image"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// testLabel returns a label for tape of width, dy lines long, with a diagonal line.
+func testLabel(t testing.TB, width MediaWidth, dy int) *monochrome.Image {
+	t.Helper()
+
+	dx, err := width.Dx()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img := monochrome.New(image.Rect(0, 0, dx, dy))
+	for y := 0; y < dy; y++ {
+		img.SetBlack(y%dx, y, true)
+	}
+	return img
+}
+
+func BenchmarkEncode(b *testing.B) {
+	imgs := []*monochrome.Image{
+		testLabel(b, Width24, 1000),
+		testLabel(b, Width24, 1000),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Encode(Width24, PrintOpts{PinOffset: 2}, imgs...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
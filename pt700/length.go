@@ -0,0 +1,38 @@
+package pt700
+
+import "go.afab.re/etiquette/monochrome"
+
+// leaderLength is the blank tape fed out before the first page of each job, in mm.
+// The print head sits this far behind the cutter.
+const leaderLength = 24.5
+
+// cutLength is the tape fed around each cut between pages, in mm.
+// Margins are set to 0, but the printer still feeds a little to cut each page.
+const cutLength = 1.0
+
+// JobLength estimates the length of tape used to Print() imgs as one job, in mm.
+// This includes the blank leader at the start of the job, and the tape fed to cut each page.
+func JobLength(imgs ...*monochrome.Image) float64 {
+	if len(imgs) == 0 {
+		return 0
+	}
+
+	var lines int
+	for _, img := range imgs {
+		lines += img.Bounds().Dy()
+	}
+
+	return jobLength(len(imgs), lines)
+}
+
+// jobLength is JobLength for pages with lines raster lines in total.
+func jobLength(pages, lines int) float64 {
+	if pages == 0 {
+		return 0
+	}
+	return leaderLength + float64(pages)*cutLength + pxToMM(lines)
+}
+
+func pxToMM(px int) float64 {
+	return float64(px) * 25.4 / dpi
+}
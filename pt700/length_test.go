@@ -0,0 +1,32 @@
+package pt700
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+func TestJobLength(t *testing.T) {
+	label := func(dy int) *monochrome.Image {
+		return monochrome.New(image.Rect(0, 0, 70, dy))
+	}
+
+	for _, test := range []struct {
+		name string
+		imgs []*monochrome.Image
+		want float64
+	}{
+		{"none", nil, 0},
+		// 180 lines is 1 inch.
+		{"one page", []*monochrome.Image{label(180)}, leaderLength + cutLength + 25.4},
+		{"three pages", []*monochrome.Image{label(180), label(360), label(90)}, leaderLength + 3*cutLength + 88.9},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := JobLength(test.imgs...); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("got %vmm, want %vmm", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+//go:build linux
+
+package pt700
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrBusy is returned by Open if another process is using the printer.
+var ErrBusy = errors.New("printer busy")
+
+// lock takes an exclusive lock on the printer, so jobs from different processes don't get interleaved.
+// Only processes that also lock() are kept out.
+func lock(fd int, wait bool) error {
+	cmd := unix.F_SETLK
+	if wait {
+		cmd = unix.F_SETLKW
+	}
+
+	for {
+		lk := unix.Flock_t{
+			Type:   unix.F_WRLCK,
+			Whence: io.SeekStart,
+		}
+
+		err := unix.FcntlFlock(uintptr(fd), cmd, &lk)
+		switch {
+		case errors.Is(err, unix.EINTR):
+			continue
+		case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EACCES):
+			// Find out who has it, best effort as they could have released it since.
+			if err := unix.FcntlFlock(uintptr(fd), unix.F_GETLK, &lk); err == nil && lk.Type != unix.F_UNLCK {
+				return fmt.Errorf("%w (pid %d)", ErrBusy, lk.Pid)
+			}
+			return ErrBusy
+		case err != nil:
+			return fmt.Errorf("lock: %w", err)
+		default:
+			return nil
+		}
+	}
+}
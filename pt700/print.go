@@ -0,0 +1,193 @@
+package pt700
+
+import (
+	"fmt"
+	"time"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+type PrintOpts struct {
+	// Progress is called as each page is sent and printed.
+	// page is 0 indexed, phase is one of the Progress* constants.
+	Progress func(page, totalPages int, phase string)
+
+	// PinOffset shifts printing by this many pins, for printers that print slightly off-center.
+	// Positive offsets move printing towards the last pin of the print head.
+	PinOffset int
+
+	// Timeouts are how long to wait for the printer during each phase of printing a page.
+	Timeouts Timeouts
+}
+
+// Timeouts are how long to wait for the printer to answer.
+type Timeouts struct {
+	// Status is how long to wait for status replies, and for the printer to start printing.
+	// If 0, defaults to 10s.
+	Status time.Duration
+	// Printed is how long to wait for a page to be printed, cut and fed once it started printing,
+	// on top of PerMM for each mm of the page.
+	// If 0, defaults to 10s.
+	Printed time.Duration
+	// PerMM is how long printing each mm of a page can take.
+	// If 0, defaults to 100ms, the printer prints about 30mm per second.
+	PerMM time.Duration
+}
+
+const (
+	defaultStatusTimeout  = 10 * time.Second
+	defaultPrintedTimeout = 10 * time.Second
+	defaultPerMMTimeout   = 100 * time.Millisecond
+)
+
+func (t Timeouts) status() time.Duration {
+	if t.Status == 0 {
+		return defaultStatusTimeout
+	}
+	return t.Status
+}
+
+// printed returns how long to wait for a page of lines raster lines to be printed.
+func (t Timeouts) printed(lines int) time.Duration {
+	printed, perMM := t.Printed, t.PerMM
+	if printed == 0 {
+		printed = defaultPrintedTimeout
+	}
+	if perMM == 0 {
+		perMM = defaultPerMMTimeout
+	}
+	return printed + time.Duration(pxToMM(lines)*float64(perMM))
+}
+
+// Phases of printing a page, reported to PrintOpts.Progress.
+const (
+	ProgressSending  = "sending"
+	ProgressPrinting = "printing"
+	ProgressFeeding  = "feeding"
+	ProgressPrinted  = "printed"
+)
+
+func (o PrintOpts) progress(page, totalPages int, phase string) {
+	if o.Progress != nil {
+		o.Progress(page, totalPages, phase)
+	}
+}
+
+// PrintResult describes what Print sent to the printer, to log or track tape consumption.
+// It covers the pages that were printed even if printing failed part way.
+type PrintResult struct {
+	// Pages is the number of pages completely printed.
+	Pages int
+	// Lines is the number of raster lines of the printed pages.
+	Lines int
+	// Bytes is the number of bytes written to the printer.
+	Bytes int
+	// Phases is the total time spent in each Progress phase, across all pages.
+	Phases map[string]time.Duration
+	// Duration is the wall time of the whole job.
+	Duration time.Duration
+	// TapeUsed is the estimated length of tape used by the printed pages in mm, see JobLength.
+	TapeUsed float64
+}
+
+// PrintError is returned by Print when printing a page fails.
+// The job can be resumed by printing the pages from Printed again, once the problem is fixed.
+type PrintError struct {
+	// Printed is the number of pages that were completely printed.
+	Printed int
+	Err     error
+}
+
+func (e *PrintError) Error() string {
+	return fmt.Sprintf("printing page %d: %v", e.Printed, e.Err)
+}
+
+func (e *PrintError) Unwrap() error {
+	return e.Err
+}
+
+func checkImgs(width MediaWidth, imgs ...*monochrome.Image) error {
+	dx, err := width.Dx()
+	if err != nil {
+		return err
+	}
+	minDy := width.MinDy()
+	maxDy := width.MaxDy()
+
+	for _, img := range imgs {
+		if img.Bounds().Dx() != dx {
+			return fmt.Errorf("printer has %v tape, expected %dpx wide image but got %dx", width, dx, img.Bounds().Dx())
+		}
+		if img.Bounds().Dy() < minDy {
+			return fmt.Errorf("printer can't print images shorter than %dpx, got %dpx", minDy, img.Bounds().Dy())
+		}
+		if img.Bounds().Dy() > maxDy {
+			return fmt.Errorf("printer can't print images longer than %dpx, got %dpx", maxDy, img.Bounds().Dy())
+		}
+	}
+
+	return nil
+}
+
+// Position of page in job.
+// Can be both first and last if it's the only page.
+type pagePos int
+
+const (
+	middle pagePos = 0
+	first  pagePos = 1 << iota
+	last
+)
+
+// pagePosition returns the position of page i of a job of n pages.
+func pagePosition(i, n int) pagePos {
+	var pos pagePos
+	if i == 0 {
+		pos = pos | first
+	}
+	if i == n-1 {
+		pos = pos | last
+	}
+	return pos
+}
+
+// page of a job to print.
+type page struct {
+	// lines is the number of raster lines of the page.
+	lines int
+	// feed is set if the page ends with a print and feed command, which cuts it.
+	feed bool
+	// encode returns the commands to print the page.
+	encode func() ([]byte, error)
+}
+
+// encodedPage is a page encoded by encodeAhead.
+type encodedPage struct {
+	data []byte
+	err  error
+}
+
+// encodeAhead encodes pages in the background, so the next page is ready as soon as the printer is.
+// It stays at most two pages ahead of the pages read from the channel: one queued, and one being encoded.
+// Encoding stops at the first error, or when done is closed.
+func encodeAhead(pages []page, done <-chan struct{}) <-chan encodedPage {
+	encoded := make(chan encodedPage, 1)
+
+	go func() {
+		defer close(encoded)
+
+		for _, pg := range pages {
+			data, err := pg.encode()
+			select {
+			case encoded <- encodedPage{data, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return encoded
+}
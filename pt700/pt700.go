@@ -1,258 +1,382 @@
+//go:build linux
+
 package pt700
 
 import (
-	"encoding/binary"
+	"bytes"
+	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math"
 	"os"
+	"slices"
 	"time"
 
 	"golang.org/x/sys/unix"
 
 	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/usblp"
 )
 
 // PT700 controls a Brother PT-700 label printer on Linux through the usblp driver.
-type PT700 int // We have to poll() to read responses, it's easier to use a raw FD.
+type PT700 struct {
+	// We have to poll() to read responses, it's easier to use a raw FD.
+	fd     int
+	log    *slog.Logger
+	tracer tracer
+
+	writeTimeout time.Duration
+
+	// To Reconnect().
+	path string
+	wait bool
+	// USB device of the printer, to find it again if it comes back at another path.
+	// nil if it isn't a usblp device, or it couldn't be read.
+	device *usblp.Device
+
+	// Only known if opened from a Desc.
+	model string
+
+	// Total bytes written, for PrintResult.
+	written int
+}
+
+type OpenOpts struct {
+	// Logger logs status changes and timings at info level,
+	// and all the data sent to and received from the printer at debug level.
+	// If nil, nothing is logged.
+	Logger *slog.Logger
 
-// Open opens a PT700 printer. Path should be of the form /dev/usb/lpN.
-func Open(path string) (PT700, error) {
-	fd, err := unix.Open(path, unix.O_RDWR, 0)
-	return PT700(fd), err
+	// Trace records all the data sent to and received from the printer,
+	// in the format described by ReadTrace.
+	Trace io.Writer
+
+	// Wait for other processes to finish using the printer,
+	// instead of returning ErrBusy.
+	Wait bool
+
+	// WriteTimeout is how long to wait for the printer to accept data,
+	// for example if it stalls because it ran out of tape.
+	// If 0, defaults to 10s.
+	WriteTimeout time.Duration
 }
 
-// Print the images as pages of one job so the ~24.5mm of blank start tape is only needed once.
-// The images will be individually cut.
-func (p PT700) Print(imgs ...*monochrome.Image) error {
-	if err := p.reset(); err != nil {
-		return err
-	}
+const defaultWriteTimeout = 10 * time.Second
 
-	// Initialize. This seems to start the print job.
-	if err := p.write([]byte{0x1B, 0x40}); err != nil {
-		return fmt.Errorf("initialize: %w", err)
+// Open opens a PT700 printer. Path should be of the form /dev/usb/lpN,
+// or serial:/dev/ttyXXX?baud=9600&flow=none for printers with a serial port.
+// Serial ports default to 9600 baud without flow control, flow=rtscts enables hardware flow control.
+func Open(path string, opts OpenOpts) (*PT700, error) {
+	fd, err := open(path, opts.Wait)
+	if err != nil {
+		return nil, err
 	}
 
-	// Docs says we need to Status() at least once, do it just in case.
-	// We can also check the tape size in case it has changed.
-	status, err := p.status()
-	if err != nil {
-		return err
+	log := opts.Logger
+	if log == nil {
+		log = slog.New(discardHandler{})
 	}
-	if err := status.Err(); err != nil {
-		return err
+
+	writeTimeout := opts.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = defaultWriteTimeout
 	}
 
-	if err := checkImgs(status.MediaWidth, imgs...); err != nil {
-		return err
+	p := &PT700{
+		fd:           fd,
+		log:          log.With("printer", path),
+		tracer:       tracer{w: opts.Trace},
+		writeTimeout: writeTimeout,
+		path:         path,
+		wait:         opts.Wait,
+	}
+	// Best effort, it's only needed to Reconnect() if the path changes.
+	if device, err := usblp.Lookup(path); err == nil {
+		p.device = &device
 	}
+	return p, nil
+}
 
-	// Actually print.
-	for i, img := range imgs {
-		var pos pagePos
-		if i == 0 {
-			pos = pos | first
-		}
-		if i == len(imgs)-1 {
-			pos = pos | last
-		}
+// Model returns the model name of the printer, if it was opened from a Desc returned by Discover.
+func (p *PT700) Model() string {
+	return p.model
+}
 
-		if err := p.printPage(status.MediaWidth, pos, img); err != nil {
-			return fmt.Errorf("printing page %d: %w", i, err)
+func open(path string, wait bool) (int, error) {
+	var serial *serialPort
+	if IsSerial(path) {
+		port, err := parseSerial(path)
+		if err != nil {
+			return 0, err
 		}
+		serial, path = &port, port.path
 	}
 
-	return nil
-}
+	// Non blocking so writes can timeout if the printer stalls.
+	// Serial ports aren't the controlling terminal.
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NONBLOCK|unix.O_NOCTTY, 0)
+	if err != nil {
+		return 0, usblp.DiagnosePermission(path, err)
+	}
 
-func (p PT700) reset() error {
-	// Invalidate. Brother docs 2.1.1 sends 100 bytes, so we do too.
-	if err := p.write(make([]byte, 100)); err != nil {
-		return fmt.Errorf("invalidate: %w", err)
+	if err := lock(fd, wait); err != nil {
+		unix.Close(fd)
+		return 0, err
 	}
 
-	// Discard any leftover junk we or other programs didn't read.
-	return readUntilEOF(int(p))
+	if serial != nil {
+		if err := serial.configure(fd); err != nil {
+			unix.Close(fd)
+			return 0, err
+		}
+	}
+
+	return fd, nil
 }
 
-func checkImgs(width MediaWidth, imgs ...*monochrome.Image) error {
-	dx, err := width.Dx()
+// ErrDisconnected is returned when the printer goes away, for example if it's turned off and on again.
+// Reconnect() reopens it once it's back.
+var ErrDisconnected = errors.New("printer disconnected")
+
+// Reconnect reopens the printer after it was disconnected.
+// usblp printers are found again by serial number, or by USB vendor and product ID if they don't have one,
+// as they can come back at a different /dev/usb/lpN. Other printers have to come back at the same path.
+// If the printer hasn't come back yet, an error is returned and Reconnect() can be retried.
+func (p *PT700) Reconnect() error {
+	if p.fd >= 0 {
+		// The old fd is dead, there's nothing useful to do if this fails.
+		_ = unix.Close(p.fd)
+		// Don't close it again if we fail to reopen, it could be reused by then.
+		p.fd = -1
+	}
+
+	path, err := p.find()
 	if err != nil {
 		return err
 	}
-	minDy := width.MinDy()
 
-	for _, img := range imgs {
-		if img.Bounds().Dx() != dx {
-			return fmt.Errorf("printer has %v tape, expected %dpx wide image but got %dx", width, dx, img.Bounds().Dx())
-		}
-		if img.Bounds().Dy() < minDy {
-			return fmt.Errorf("printer can't print images shorter than %dpx, got %dpx", minDy, img.Bounds().Dy())
-		}
+	fd, err := open(path, p.wait)
+	if err != nil {
+		return err
 	}
 
+	p.fd = fd
+	p.path = path
+	p.log.Info("reconnected", "path", path)
 	return nil
 }
 
-// Position of page in job.
-// Can be both first and last if it's the only page.
-type pagePos int
+// find returns the current path of the printer.
+func (p *PT700) find() (string, error) {
+	switch {
+	case p.device == nil:
+		return p.path, nil
+	case p.device.Serial != "":
+		return usblp.Find("serial:" + p.device.Serial)
+	}
 
-const (
-	middle pagePos = 0
-	first  pagePos = 1 << iota
-	last
-)
+	devices, warnings, err := usblp.Connected()
+	if err != nil {
+		return "", err
+	}
 
-func (p PT700) printPage(width MediaWidth, pos pagePos, img *monochrome.Image) error {
-	// Not the first page? Wait for "Waiting to receive"
-	if pos&first == 0 {
-		if _, err := p.readStatus(StatusPhaseChange); err != nil {
-			return err
+	var paths []string
+	for _, device := range devices {
+		if device.VendorID == p.device.VendorID && device.ProductID == p.device.ProductID {
+			paths = append(paths, device.Path)
 		}
 	}
 
-	// Control codes (Brother PDF 2.1.2).
-	// Raster mode.
-	if err := p.write([]byte{0x1B, 0x69, 0x61, 0x01}); err != nil {
-		return fmt.Errorf("enabling raster mode: %w", err)
+	switch len(paths) {
+	case 0:
+		// It could be one of the devices we couldn't read.
+		return "", errors.Join(append([]error{fmt.Errorf("no %04x:%04x printer connected", p.device.VendorID, p.device.ProductID)}, warnings...)...)
+	case 1:
+		return paths[0], nil
+	default:
+		// Without a serial number, they can't be told apart.
+		if slices.Contains(paths, p.path) {
+			return p.path, nil
+		}
+		return "", fmt.Errorf("%d %04x:%04x printers connected, can't tell which one was reconnected", len(paths), p.device.VendorID, p.device.ProductID)
 	}
+}
 
-	// Print information
-	info := []byte{
-		0x1B, 0x69, 0x7A,
-		// Only validate the media width in case the tape has changed,
-		// the type and length don't really matter,
-		// PrinterRecovery should always be on according to the manual.
-		0x84,
-		0x00,        // Type, we don't request validation.
-		byte(width), // Media width mm.
-		0x00,        // Media length mm, we don't request validation.
-	}
-	// Number of raster lines (height of image).
-	info = binary.LittleEndian.AppendUint32(info, uint32(img.Bounds().Dy()))
-	// Starting page or not.
-	if pos&first != 0 {
-		info = append(info, 0x00)
-	} else {
-		info = append(info, 0x01)
-	}
-	// n10: always 0.
-	info = append(info, 0x00)
-	if err := p.write(info); err != nil {
-		return fmt.Errorf("print information: %w", err)
-	}
+// Print the images as pages of one job so the ~24.5mm of blank start tape is only needed once.
+// The images will be individually cut.
+func (p *PT700) Print(opts PrintOpts, imgs ...*monochrome.Image) (PrintResult, error) {
+	return p.printJob(opts, func(width MediaWidth) ([]page, error) {
+		if err := checkImgs(width, imgs...); err != nil {
+			return nil, err
+		}
 
-	// Mode settings.
-	if err := p.write([]byte{
-		0x1B, 0x69, 0x4D,
-		// Enable auto cut.
-		0x40,
-	}); err != nil {
-		return fmt.Errorf("mode settings: %w", err)
+		pages := make([]page, len(imgs))
+		for i, img := range imgs {
+			img, pos := img, pagePosition(i, len(imgs))
+			pages[i] = page{
+				lines: img.Bounds().Dy(),
+				feed:  pos&last != 0,
+				encode: func() ([]byte, error) {
+					var buf bytes.Buffer
+					err := encodePage(width, pos, img, opts.PinOffset, func(b []byte) error {
+						buf.Write(b)
+						return nil
+					})
+					return buf.Bytes(), err
+				},
+			}
+		}
+		return pages, nil
+	})
+}
+
+// PrintRaw prints a job encoded by Encode, or by Brother's software, waiting for each page to be printed.
+// The PinOffset of opts isn't used, it's part of the job.
+func (p *PT700) PrintRaw(opts PrintOpts, job []byte) (PrintResult, error) {
+	pages, err := splitJob(job)
+	if err != nil {
+		return PrintResult{}, err
 	}
 
-	// Advanced mode settings.
-	if err := p.write([]byte{
-		0x1B, 0x69, 0x4B,
-		// "Chain-printing" lets the printer print several jobs in a row,
-		// by not feeding out the label and cutting it for the last page.
-		// We print all the labels as pages of one job, so we actually don't want chain-printing.
-		0x08,
-	}); err != nil {
-		return fmt.Errorf("advanced mode settings: %w", err)
+	return p.printJob(opts, func(MediaWidth) ([]page, error) {
+		return pages, nil
+	})
+}
+
+// printJob starts a job, and prints the pages returned by prepare for the loaded tape.
+func (p *PT700) printJob(opts PrintOpts, prepare func(width MediaWidth) ([]page, error)) (res PrintResult, err error) {
+	res = PrintResult{
+		Phases: make(map[string]time.Duration),
 	}
+	jobStart, written := time.Now(), p.written
+	defer func() {
+		res.Bytes = p.written - written
+		res.Duration = time.Since(jobStart)
+		res.TapeUsed = jobLength(res.Pages, res.Lines)
+	}()
 
-	// Margin.
-	if err := p.write([]byte{
-		// Manual says min 2mm margins (14 dots) in 2.3.3. But 0 seems to work fine.
-		// Lets the caller deal with margins themselves.
-		0x1B, 0x69, 0x64, 0x00, 0x00,
-	}); err != nil {
-		return fmt.Errorf("margins: %w", err)
+	if err := p.reset(); err != nil {
+		return res, err
 	}
 
-	// Compression.
-	if err := p.write([]byte{
-		0x4D,
-		// No compression.
-		0x00,
-	}); err != nil {
-		return fmt.Errorf("compression: %w", err)
+	// Fail fast, before the printer has to answer a status request.
+	if err := p.portErr(); err != nil {
+		return res, err
 	}
 
-	// Raster data.
-	if err := p.printRaster(width, img); err != nil {
-		return fmt.Errorf("raster: %w", err)
+	// Initialize. This seems to start the print job.
+	if err := p.write(initialize); err != nil {
+		return res, fmt.Errorf("initialize: %w", err)
 	}
 
-	// Print.
-	printCmd := byte(0x0C)
-	if pos&last != 0 {
-		// Print and feed.
-		printCmd = 0x1A
+	// Docs says we need to Status() at least once, do it just in case.
+	// We can also check the tape size in case it has changed.
+	status, err := p.status()
+	if err != nil {
+		return res, err
 	}
-	if err := p.write([]byte{printCmd}); err != nil {
-		return fmt.Errorf("print: %w", err)
+	if err := status.Err(); err != nil {
+		return res, err
 	}
 
-	// First "Printing".
-	if _, err := p.readStatus(StatusPhaseChange); err != nil {
-		return err
+	pages, err := prepare(status.MediaWidth)
+	if err != nil {
+		return res, err
 	}
 
-	if pos&last != 0 {
-		// "Feeding".
-		if _, err := p.readStatus(StatusPhaseChange); err != nil {
-			return err
+	// Encode the next pages while the current one is sent and printed.
+	done := make(chan struct{})
+	defer close(done)
+	encoded := encodeAhead(pages, done)
+
+	// Actually print.
+	for i, pg := range pages {
+		enc := <-encoded
+		if enc.err != nil {
+			return res, &PrintError{Printed: i, Err: enc.err}
+		}
+
+		// Time each phase until the next one starts.
+		var phase string
+		var phaseStart time.Time
+		progress := func(next string) {
+			if phase != "" {
+				res.Phases[phase] += time.Since(phaseStart)
+			}
+			phase, phaseStart = next, time.Now()
+			opts.progress(i, len(pages), next)
+		}
+
+		start := time.Now()
+		err := p.printPage(pg, enc.data, i == 0, opts, progress)
+		if phase != "" && phase != ProgressPrinted {
+			res.Phases[phase] += time.Since(phaseStart)
+		}
+		if err != nil {
+			return res, &PrintError{Printed: i, Err: err}
 		}
+		res.Pages++
+		res.Lines += pg.lines
+		p.log.Info("printed page", "page", i, "lines", pg.lines, "duration", time.Since(start))
 	}
 
-	// Finally "Printing completed".
-	_, err := p.readStatus(StatusPrintingCompleted)
-	return err
+	return res, nil
 }
 
-func (p PT700) printRaster(width MediaWidth, img *monochrome.Image) error {
-	// Print bottom line first.
-	for y := img.Bounds().Max.Y; y > img.Bounds().Min.Y; y-- {
-		if err := p.rasterLine(width, img, y); err != nil {
-			return err
-		}
+func (p *PT700) reset() error {
+	if err := p.write(invalidate); err != nil {
+		return fmt.Errorf("invalidate: %w", err)
 	}
 
-	return nil
+	// Discard any leftover junk we or other programs didn't read.
+	return p.readUntilEOF()
 }
 
-func (p PT700) rasterLine(width MediaWidth, img *monochrome.Image, y int) error {
-	const totalPins = 128
+// printPage sends the commands to print pg, and waits for it to be printed.
+func (p *PT700) printPage(pg page, data []byte, first bool, opts PrintOpts, progress func(phase string)) error {
+	// Not the first page? Wait for "Waiting to receive"
+	if !first {
+		if _, err := p.readStatus(StatusPhaseChange, opts.Timeouts.status()); err != nil {
+			return err
+		}
+	}
 
-	line := make([]byte, totalPins/8)
+	progress(ProgressSending)
 
-	// Only the middle pins are used for printing, offset everything.
-	pin, err := width.unusedPins(totalPins)
-	if err != nil {
+	if err := p.write(data); err != nil {
 		return err
 	}
 
-	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
-		byt := pin / 8
-		bit := pin % 8
+	// First "Printing".
+	if _, err := p.readStatus(StatusPhaseChange, opts.Timeouts.status()); err != nil {
+		return err
+	}
+	progress(ProgressPrinting)
 
-		if img.BlackAt(x, y) {
-			line[byt] = line[byt] | (1<<7)>>bit
+	// Everything else can take as long as printing the page.
+	deadline := time.Now().Add(opts.Timeouts.printed(pg.lines))
+
+	if pg.feed {
+		// "Feeding".
+		if _, err := p.readStatus(StatusPhaseChange, time.Until(deadline)); err != nil {
+			return err
 		}
+		progress(ProgressFeeding)
+	}
 
-		pin++
+	// Finally "Printing completed".
+	if _, err := p.readStatus(StatusPrintingCompleted, time.Until(deadline)); err != nil {
+		return err
 	}
+	progress(ProgressPrinted)
 
-	// Manual says 0x67! But that doesn't work, and the example
-	// in 2.2.3 uses 0x47.
-	return p.write(append([]byte{0x47, 16, 0}, line...))
+	return nil
 }
 
-func (p PT700) Status() (Status, error) {
+func (p *PT700) Status() (Status, error) {
 	if err := p.reset(); err != nil {
 		return Status{}, err
 	}
@@ -261,60 +385,182 @@ func (p PT700) Status() (Status, error) {
 }
 
 // Status() but without reset().
-func (p PT700) status() (Status, error) {
+func (p *PT700) status() (Status, error) {
 	if err := p.write([]byte{0x1B, 0x69, 0x53}); err != nil {
 		return Status{}, fmt.Errorf("status write: %w", err)
 	}
 
-	return p.readStatus(StatusReplyToRequest)
+	return p.readStatus(StatusReplyToRequest, defaultStatusTimeout)
 }
 
-func (p PT700) readStatus(expectedType StatusType) (Status, error) {
-	resp := make([]byte, 32)
-	if err := p.read(resp, time.Second*10); err != nil {
-		return Status{}, fmt.Errorf("status read: %w", err)
-	}
+func (p *PT700) readStatus(expectedType StatusType, timeout time.Duration) (Status, error) {
+	deadline := time.Now().Add(timeout)
 
-	s := Status{
-		Err1:       Error1(resp[8]),
-		Err2:       Error2(resp[9]),
-		MediaWidth: MediaWidth(resp[10]),
-		MediaType:  MediaType(resp[11]),
-		Type:       StatusType(resp[18]),
-		Phase:      PhaseType(resp[19]),
-	}
+	for {
+		resp := make([]byte, 32)
+		if err := p.read(resp, time.Until(deadline)); err != nil {
+			return Status{}, fmt.Errorf("status read: %w", err)
+		}
+		p.logData("read", resp)
+
+		s := Status{
+			Err1:       Error1(resp[8]),
+			Err2:       Error2(resp[9]),
+			MediaWidth: MediaWidth(resp[10]),
+			MediaType:  MediaType(resp[11]),
+			Type:       StatusType(resp[18]),
+			Phase:      PhaseType(resp[19]),
+			TapeColor:  TapeColor(resp[24]),
+			TextColor:  TextColor(resp[25]),
+		}
+		p.log.Info("status", "type", s.Type, "phase", s.Phase, "err1", s.Err1, "err2", s.Err2, "width", s.MediaWidth, "media", s.MediaType)
 
-	if s.Type != expectedType {
-		return Status{}, fmt.Errorf("expected status type %v got %+v", expectedType, s)
+		switch {
+		case s.Type == expectedType:
+			return s, nil
+
+		// The printer can report errors at any time, return the actual error.
+		case s.Type == StatusErrorOccurred:
+			if err := s.Err(); err != nil {
+				return s, err
+			}
+			return s, fmt.Errorf("printer reported an error without details: %+v", s)
+
+		// Notifications (cover opened, cooling) can come before the status we're waiting for.
+		case s.Type == StatusNotification:
+			continue
+
+		default:
+			return Status{}, fmt.Errorf("expected status type %v got %+v", expectedType, s)
+		}
 	}
-	return s, nil
 }
 
-func (p PT700) write(b []byte) error {
+// writeChunk is the most data written at once.
+// Long pages are sent in chunks, so they only time out if the printer stops accepting data,
+// not because they take longer than the write timeout to send.
+const writeChunk = 4096
+
+func (p *PT700) write(b []byte) error {
+	p.logData("write", b)
+
+	// Reset whenever the printer accepts a chunk.
+	deadline := time.Now().Add(p.writeTimeout)
+
 	for wrote := 0; wrote != len(b); {
-		n, err := unix.Write(int(p), b[wrote:])
+		n, err := unix.Write(p.fd, b[wrote:min(len(b), wrote+writeChunk)])
 		switch {
 		case errors.Is(unix.EINTR, err):
 			continue
+		// The printer isn't ready for more.
+		case errors.Is(err, unix.EAGAIN):
+			if err := p.waitWritable(deadline); err != nil {
+				return fmt.Errorf("write: %w", err)
+			}
+			continue
+		case errors.Is(err, unix.ENODEV):
+			return ErrDisconnected
 		case err != nil:
 			return fmt.Errorf("write: %w", err)
 		}
+		p.tracer.trace(TraceWrite, b[wrote:wrote+n])
 
 		wrote += n
+		p.written += n
+		deadline = time.Now().Add(p.writeTimeout)
 	}
 
 	return nil
 }
 
+// waitWritable waits for the printer to accept more data, or deadline.
+// The port status is checked while waiting, so a printer that stalls because it ran out of tape or went offline
+// is reported as such instead of timing out.
+func (p *PT700) waitWritable(deadline time.Time) error {
+	for {
+		step := time.Now().Add(portStatusInterval)
+		if step.After(deadline) {
+			step = deadline
+		}
+
+		err := p.poll(unix.POLLOUT, step)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			if err := p.portErr(); err != nil {
+				return err
+			}
+			if time.Now().Before(deadline) {
+				continue
+			}
+		}
+		return err
+	}
+}
+
+// logData logs raw data at debug level, without hex encoding it if debug is disabled.
+func (p *PT700) logData(msg string, b []byte) {
+	if p.log.Enabled(context.Background(), slog.LevelDebug) {
+		p.log.Debug(msg, "data", hex.EncodeToString(b))
+	}
+}
+
 // io.ReadFull() but will poll().
-func (p PT700) read(buf []byte, timeout time.Duration) error {
+func (p *PT700) read(buf []byte, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 
+	for read := 0; read < len(buf); {
+		// Wait in short steps, to notice the printer going offline without waiting for the whole timeout.
+		step := time.Now().Add(portStatusInterval)
+		if step.After(deadline) {
+			step = deadline
+		}
+
+		err := p.poll(unix.POLLIN, step)
+		if errors.Is(err, os.ErrDeadlineExceeded) && time.Now().Before(deadline) {
+			if err := p.portErr(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		n, err := readFull(p.fd, buf[read:])
+		if err != nil {
+			return err
+		}
+		p.tracer.trace(TraceRead, buf[read:read+n])
+		read += n
+	}
+
+	return nil
+}
+
+// How often to check the port status while waiting for the printer to answer.
+const portStatusInterval = time.Second
+
+// portErr returns an error if the USB port status reports a problem.
+// Devices that aren't handled by usblp don't have a port status, and never have an error.
+func (p *PT700) portErr() error {
+	status, err := usblp.GetPortStatus(p.fd)
+	if err != nil {
+		return nil
+	}
+
+	if err := status.Err(); err != nil {
+		p.log.Info("port status", "status", fmt.Sprintf("0x%x", int(status)), "err", err)
+		return err
+	}
+	return nil
+}
+
+// poll waits until the printer is ready for events, or deadline.
+func (p *PT700) poll(events int16, deadline time.Time) error {
 	pollFds := []unix.PollFd{
-		{Fd: int32(p), Events: unix.POLLIN},
+		{Fd: int32(p.fd), Events: events},
 	}
 
-	for read := 0; read < len(buf); {
+	for {
 		// Negative timeout is an infinite timeout for poll().
 		remaining := time.Until(deadline).Milliseconds()
 		switch {
@@ -336,19 +582,13 @@ func (p PT700) read(buf []byte, timeout time.Duration) error {
 			return fmt.Errorf("POLLNVAL")
 		case (pollFds[0].Revents & unix.POLLERR) != 0,
 			(pollFds[0].Revents & unix.POLLHUP) != 0:
-			return fmt.Errorf("printer disconnected")
-		case (pollFds[0].Revents & unix.POLLIN) == 0:
-			return fmt.Errorf("poll() returned but no data, n %d, revents: %x", n, pollFds[0].Revents)
+			return ErrDisconnected
+		case (pollFds[0].Revents & events) == 0:
+			return fmt.Errorf("poll() returned but not ready, n %d, revents: %x", n, pollFds[0].Revents)
 		}
 
-		n, err = readFull(int(p), buf[read:])
-		if err != nil {
-			return err
-		}
-		read += n
+		return nil
 	}
-
-	return nil
 }
 
 // readFull reads up to len(b) bytes, or EOF from fd.
@@ -361,6 +601,11 @@ func readFull(fd int, b []byte) (int, error) {
 		switch {
 		case errors.Is(unix.EINTR, err):
 			continue
+		case errors.Is(err, unix.ENODEV):
+			return 0, ErrDisconnected
+		// Nothing more to read right now, same as EOF.
+		case errors.Is(err, unix.EAGAIN):
+			return read, nil
 		case err != nil:
 			return 0, err
 		// EOF.
@@ -377,23 +622,31 @@ func readFull(fd int, b []byte) (int, error) {
 	return read, nil
 }
 
-func readUntilEOF(fd int) error {
+func (p *PT700) readUntilEOF() error {
 	b := make([]byte, 128)
 
 	for {
-		n, err := unix.Read(fd, b)
+		n, err := unix.Read(p.fd, b)
 		switch {
 		case errors.Is(unix.EINTR, err):
 			continue
+		// Nothing more to read right now, same as EOF.
+		case errors.Is(err, unix.EAGAIN):
+			return nil
 		case err != nil:
 			return err
 		// EOF.
 		case n == 0:
 			return nil
 		}
+		p.tracer.trace(TraceRead, b[:n])
 	}
 }
 
-func (p PT700) Close() error {
-	return unix.Close(int(p))
+func (p *PT700) Close() error {
+	// Reconnect() failed, there's nothing to close.
+	if p.fd < 0 {
+		return nil
+	}
+	return unix.Close(p.fd)
 }
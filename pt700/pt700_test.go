@@ -0,0 +1,292 @@
+//go:build linux
+
+package pt700
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// fakePrinter emulates a printer on the other end of a socket, answering status requests and print commands
+// like a PT-P700 does, without waiting for anything to be printed.
+type fakePrinter struct {
+	fd    int
+	width MediaWidth
+
+	// received is everything sent to the printer, except status requests.
+	received []byte
+	done     chan error
+}
+
+// newFakePrinter returns a PT700 connected to a fakePrinter with tape of width loaded.
+// The fakePrinter stops once the PT700 is closed.
+func newFakePrinter(t testing.TB, width MediaWidth) (*PT700, *fakePrinter) {
+	t.Helper()
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Like Open.
+	if err := unix.SetNonblock(fds[0], true); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &PT700{
+		fd:           fds[0],
+		log:          slog.New(discardHandler{}),
+		writeTimeout: defaultWriteTimeout,
+	}
+	f := &fakePrinter{
+		fd:    fds[1],
+		width: width,
+		done:  make(chan error, 1),
+	}
+	go func() {
+		f.done <- f.run()
+		unix.Close(f.fd)
+	}()
+
+	return p, f
+}
+
+// wait closes p, and waits for f to stop.
+func (f *fakePrinter) wait(t testing.TB, p *PT700) {
+	t.Helper()
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-f.done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fakePrinter) run() error {
+	var buf []byte
+	chunk := make([]byte, 64<<10)
+
+	for {
+		n, err := unix.Read(f.fd, chunk)
+		switch {
+		case errors.Is(err, unix.EINTR):
+			continue
+		case err != nil:
+			return err
+		// Closed.
+		case n == 0:
+			if len(buf) != 0 {
+				return fmt.Errorf("%d bytes left over", len(buf))
+			}
+			return nil
+		}
+		buf = append(buf, chunk[:n]...)
+
+		for len(buf) != 0 {
+			n, err := f.command(buf)
+			if err != nil {
+				return err
+			}
+			// Incomplete, wait for more.
+			if n == 0 {
+				break
+			}
+			buf = buf[n:]
+		}
+	}
+}
+
+// command handles the command at the start of buf, and returns its length.
+// It returns 0 if buf doesn't have the whole command yet.
+func (f *fakePrinter) command(buf []byte) (int, error) {
+	// Length of the command.
+	n := 0
+	switch {
+	case buf[0] == 0x00, buf[0] == 0x5A, buf[0] == 0x0C, buf[0] == 0x1A:
+		n = 1
+	case buf[0] == 0x4D:
+		n = 2
+	case len(buf) < 3:
+		return 0, nil
+	case buf[0] == 0x47:
+		n = 3 + int(buf[1]) + int(buf[2])<<8
+	case bytes.HasPrefix(buf, initialize):
+		n = 2
+	case bytes.HasPrefix(buf, []byte{0x1B, 0x69, 0x53}):
+		return 3, f.reply(fakeStatus{StatusReplyToRequest, PhaseEditing})
+	case bytes.HasPrefix(buf, []byte{0x1B, 0x69, 0x7A}):
+		n = 13
+	case bytes.HasPrefix(buf, []byte{0x1B, 0x69, 0x64}):
+		n = 5
+	case bytes.HasPrefix(buf, []byte{0x1B, 0x69}):
+		n = 4
+	default:
+		return 0, fmt.Errorf("unknown command 0x%02x", buf[0])
+	}
+	if len(buf) < n {
+		return 0, nil
+	}
+	f.received = append(f.received, buf[:n]...)
+
+	switch buf[0] {
+	// Print: the next page can be sent once the printer is back to receiving.
+	case 0x0C:
+		return n, f.reply(fakeStatus{StatusPhaseChange, PhasePrinting}, fakeStatus{StatusPrintingCompleted, PhaseEditing}, fakeStatus{StatusPhaseChange, PhaseEditing})
+	// Print and feed.
+	case 0x1A:
+		return n, f.reply(fakeStatus{StatusPhaseChange, PhasePrinting}, fakeStatus{StatusPhaseChange, PhaseEditing}, fakeStatus{StatusPrintingCompleted, PhaseEditing})
+	}
+	return n, nil
+}
+
+type fakeStatus struct {
+	typ   StatusType
+	phase PhaseType
+}
+
+// reply sends statuses.
+func (f *fakePrinter) reply(statuses ...fakeStatus) error {
+	var b []byte
+	for _, s := range statuses {
+		status := make([]byte, 32)
+		status[0], status[1], status[2] = 0x80, 0x20, 0x42
+		status[10] = byte(f.width)
+		status[11] = TypeLaminated
+		status[18] = byte(s.typ)
+		status[19] = byte(s.phase)
+		b = append(b, status...)
+	}
+
+	for len(b) != 0 {
+		n, err := unix.Write(f.fd, b)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func TestPrint(t *testing.T) {
+	imgs := []*monochrome.Image{
+		testLabel(t, Width12, 200),
+		testLabel(t, Width12, 300),
+	}
+
+	p, f := newFakePrinter(t, Width12)
+
+	var phases []string
+	res, err := p.Print(PrintOpts{
+		PinOffset: 2,
+		Progress: func(page, totalPages int, phase string) {
+			phases = append(phases, fmt.Sprintf("%d/%d %s", page, totalPages, phase))
+		},
+	}, imgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.wait(t, p)
+
+	if res.Pages != 2 || res.Lines != 500 {
+		t.Errorf("got %d pages of %d lines, want 2 pages of 500 lines", res.Pages, res.Lines)
+	}
+	if res.TapeUsed != JobLength(imgs...) {
+		t.Errorf("got %vmm of tape used, want %vmm", res.TapeUsed, JobLength(imgs...))
+	}
+
+	wantPhases := []string{
+		"0/2 sending", "0/2 printing", "0/2 printed",
+		"1/2 sending", "1/2 printing", "1/2 feeding", "1/2 printed",
+	}
+	if !slices.Equal(phases, wantPhases) {
+		t.Errorf("got phases %v, want %v", phases, wantPhases)
+	}
+
+	// Print sends what Encode returns, with status requests.
+	want, err := Encode(Width12, PrintOpts{PinOffset: 2}, imgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(f.received, want) {
+		t.Errorf("printer received %d bytes that don't match the %d bytes of Encode", len(f.received), len(want))
+	}
+	if res.Bytes != len(want)+3 {
+		t.Errorf("got %d bytes written, want %d", res.Bytes, len(want)+3)
+	}
+}
+
+func TestPrintRaw(t *testing.T) {
+	job, err := Encode(Width12, PrintOpts{}, testLabel(t, Width12, 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, f := newFakePrinter(t, Width12)
+	res, err := p.PrintRaw(PrintOpts{}, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.wait(t, p)
+
+	if res.Pages != 1 || res.Lines != 200 {
+		t.Errorf("got %d pages of %d lines, want 1 page of 200 lines", res.Pages, res.Lines)
+	}
+	if !bytes.Equal(f.received, job) {
+		t.Errorf("printer received %d bytes that don't match the %d byte job", len(f.received), len(job))
+	}
+}
+
+func TestPrintWrongTape(t *testing.T) {
+	p, f := newFakePrinter(t, Width24)
+
+	if _, err := p.Print(PrintOpts{}, testLabel(t, Width12, 200)); err == nil {
+		t.Errorf("expected an error printing a 12mm label on 24mm tape")
+	}
+	f.wait(t, p)
+}
+
+func BenchmarkPrint(b *testing.B) {
+	// A long label, with several chunks to write.
+	imgs := []*monochrome.Image{
+		testLabel(b, Width24, 1000),
+		testLabel(b, Width24, 1000),
+	}
+
+	p, f := newFakePrinter(b, Width24)
+	defer f.wait(b, p)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Print(PrintOpts{}, imgs...); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestReconnectGone(t *testing.T) {
+	p, f := newFakePrinter(t, Width12)
+	p.path = filepath.Join(t.TempDir(), "lp0")
+
+	if err := p.Reconnect(); err == nil {
+		t.Fatalf("expected an error reconnecting to a printer that's gone")
+	}
+	// The old fd was closed by Reconnect.
+	if err := <-f.done; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("closing after a failed reconnect: %v", err)
+	}
+}
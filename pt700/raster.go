@@ -0,0 +1,56 @@
+package pt700
+
+import (
+	"go.afab.re/etiquette/monochrome"
+)
+
+// RasterGeometry maps the columns of images to the pins of a print head.
+// PTouch printers expect full width data even with narrow media,
+// software has to explicitly skip pins outside of the print area.
+type RasterGeometry struct {
+	// Pins is the number of pins of the print head, for example 128 for the PT-700 or 560 for wider printers.
+	Pins int
+	// Dx is the width of the print area of the tape, in pixels.
+	Dx int
+	// Offset shifts printing by this many pins, positive towards the last pin.
+	Offset int
+}
+
+// Geometry returns the raster geometry of the tape on a print head with pins.
+func (w MediaWidth) Geometry(pins int) (RasterGeometry, error) {
+	dx, err := w.Dx()
+	if err != nil {
+		return RasterGeometry{}, err
+	}
+
+	return RasterGeometry{
+		Pins: pins,
+		Dx:   dx,
+	}, nil
+}
+
+// UnusedPins returns the number of pins before the print area, the tape is centered on the print head.
+func (g RasterGeometry) UnusedPins() int {
+	return (g.Pins - g.Dx) / 2
+}
+
+// Pin returns the pin printing column x of the print area.
+// ok is false if the offset shifts it past the edge of the print head.
+func (g RasterGeometry) Pin(x int) (pin int, ok bool) {
+	pin = g.UnusedPins() + g.Offset + x
+	return pin, pin >= 0 && pin < g.Pins
+}
+
+// Line packs row y of img into a raster line for every pin of the print head, most significant bit first.
+func (g RasterGeometry) Line(img *monochrome.Image, y int) []byte {
+	line := make([]byte, (g.Pins+7)/8)
+
+	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+		pin, ok := g.Pin(x - img.Bounds().Min.X)
+		if ok && img.BlackAt(x, y) {
+			line[pin/8] |= (1 << 7) >> (pin % 8)
+		}
+	}
+
+	return line
+}
@@ -0,0 +1,106 @@
+package pt700
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+func TestGeometry(t *testing.T) {
+	for _, test := range []struct {
+		width  MediaWidth
+		pins   int
+		unused int
+	}{
+		{Width3_5, 128, 52},
+		{Width6, 128, 48},
+		{Width9, 128, 39},
+		{Width12, 128, 29},
+		{Width18, 128, 8},
+		{Width24, 128, 0},
+		{Width12, 560, 245},
+		{Width24, 560, 216},
+	} {
+		g, err := test.width.Geometry(test.pins)
+		if err != nil {
+			t.Fatalf("%v on %d pins: %v", test.width, test.pins, err)
+		}
+		if got := g.UnusedPins(); got != test.unused {
+			t.Errorf("%v on %d pins: got %d unused pins, want %d", test.width, test.pins, got, test.unused)
+		}
+	}
+
+	if _, err := WidthNoMedia.Geometry(128); err == nil {
+		t.Errorf("no media: expected an error")
+	}
+}
+
+func TestPin(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		g    RasterGeometry
+		x    int
+		pin  int
+		ok   bool
+	}{
+		{"first", RasterGeometry{Pins: 128, Dx: 70}, 0, 29, true},
+		{"last", RasterGeometry{Pins: 128, Dx: 70}, 69, 98, true},
+		{"full width last", RasterGeometry{Pins: 128, Dx: 128}, 127, 127, true},
+		{"560 pins", RasterGeometry{Pins: 560, Dx: 128}, 0, 216, true},
+		// The extra pin of odd widths is after the print area.
+		{"odd dx", RasterGeometry{Pins: 128, Dx: 51}, 0, 38, true},
+		{"odd dx last", RasterGeometry{Pins: 128, Dx: 51}, 50, 88, true},
+		{"offset", RasterGeometry{Pins: 128, Dx: 70, Offset: 3}, 0, 32, true},
+		{"negative offset", RasterGeometry{Pins: 128, Dx: 70, Offset: -3}, 0, 26, true},
+		{"offset past first pin", RasterGeometry{Pins: 128, Dx: 70, Offset: -30}, 0, -1, false},
+		{"offset past last pin", RasterGeometry{Pins: 128, Dx: 128, Offset: 1}, 127, 128, false},
+		{"offset 560 pins", RasterGeometry{Pins: 560, Dx: 112, Offset: 2}, 111, 337, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pin, ok := test.g.Pin(test.x)
+			if pin != test.pin || ok != test.ok {
+				t.Errorf("Pin(%d) = %d, %v, want %d, %v", test.x, pin, ok, test.pin, test.ok)
+			}
+		})
+	}
+}
+
+func TestLine(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		g    RasterGeometry
+		// Black columns of the image.
+		black []int
+		// Pins set in the line.
+		pins []int
+	}{
+		{"128 pins", RasterGeometry{Pins: 128, Dx: 70}, []int{0, 1, 69}, []int{29, 30, 98}},
+		{"560 pins", RasterGeometry{Pins: 560, Dx: 128}, []int{0, 127}, []int{216, 343}},
+		{"odd dx", RasterGeometry{Pins: 128, Dx: 51}, []int{0, 50}, []int{38, 88}},
+		{"offset", RasterGeometry{Pins: 128, Dx: 70, Offset: 5}, []int{0, 69}, []int{34, 103}},
+		// Columns shifted past the print head aren't printed.
+		{"offset clipped", RasterGeometry{Pins: 128, Dx: 128, Offset: -2}, []int{0, 1, 2, 127}, []int{0, 125}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			// Images don't have to start at 0.
+			img := monochrome.New(image.Rect(10, 5, 10+test.g.Dx, 7))
+			for _, x := range test.black {
+				img.SetBlack(10+x, 6, true)
+			}
+
+			want := make([]byte, (test.g.Pins+7)/8)
+			for _, pin := range test.pins {
+				want[pin/8] |= 0x80 >> (pin % 8)
+			}
+
+			if got := test.g.Line(img, 6); !bytes.Equal(got, want) {
+				t.Errorf("got line %x, want %x", got, want)
+			}
+			if got := test.g.Line(img, 5); !bytes.Equal(got, make([]byte, len(want))) {
+				t.Errorf("got line %x for a white row", got)
+			}
+		})
+	}
+}
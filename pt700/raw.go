@@ -0,0 +1,91 @@
+package pt700
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// splitJob splits a raw print job into pages, each ending with a print command.
+// Invalidate, initialize and status request commands are dropped, Print sends its own.
+func splitJob(job []byte) ([]page, error) {
+	var pages []page
+	var data []byte
+	lines := 0
+
+	for i := 0; i < len(job); {
+		// Length of the command at i.
+		n := 0
+		keep := true
+
+		switch {
+		// Invalidate.
+		case job[i] == 0x00:
+			n, keep = 1, false
+		// Initialize.
+		case bytes.HasPrefix(job[i:], initialize):
+			n, keep = len(initialize), false
+		// Status request.
+		case bytes.HasPrefix(job[i:], []byte{0x1B, 0x69, 0x53}):
+			n, keep = 3, false
+		// Print information.
+		case bytes.HasPrefix(job[i:], []byte{0x1B, 0x69, 0x7A}):
+			n = 13
+		// Margin.
+		case bytes.HasPrefix(job[i:], []byte{0x1B, 0x69, 0x64}):
+			n = 5
+		// Other settings with one parameter: raster mode, mode, advanced mode, cut every, status notifications.
+		case bytes.HasPrefix(job[i:], []byte{0x1B, 0x69}):
+			n = 4
+		// Compression.
+		case job[i] == 0x4D:
+			n = 2
+		// Raster line, with a little endian length.
+		// Brother's documentation uses 0x67 with a one byte length instead.
+		case job[i] == 0x47 || job[i] == 0x67:
+			if i+3 > len(job) {
+				return nil, fmt.Errorf("truncated raster line at byte %d", i)
+			}
+			n = 3 + int(job[i+1]) + int(job[i+2])<<8
+			if job[i] == 0x67 {
+				n = 3 + int(job[i+2])
+			}
+			lines++
+		// Blank raster line.
+		case job[i] == 0x5A:
+			n = 1
+			lines++
+		// Print, and print and feed.
+		case job[i] == 0x0C || job[i] == 0x1A:
+			pageData := append(data, job[i])
+			pages = append(pages, page{
+				lines: lines,
+				feed:  job[i] == 0x1A,
+				encode: func() ([]byte, error) {
+					return pageData, nil
+				},
+			})
+			data, lines = nil, 0
+			i++
+			continue
+		default:
+			return nil, fmt.Errorf("unknown command 0x%02x at byte %d", job[i], i)
+		}
+
+		if i+n > len(job) {
+			return nil, fmt.Errorf("truncated command 0x%02x at byte %d", job[i], i)
+		}
+		if keep {
+			data = append(data, job[i:i+n]...)
+		}
+		i += n
+	}
+
+	if len(data) != 0 {
+		return nil, fmt.Errorf("job doesn't end with a print command")
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("job has no pages")
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,133 @@
+package pt700
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+func TestSplitJob(t *testing.T) {
+	imgs := []*monochrome.Image{
+		testLabel(t, Width12, 200),
+		testLabel(t, Width12, 180),
+		testLabel(t, Width12, 250),
+	}
+
+	job, err := Encode(Width12, PrintOpts{}, imgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := splitJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != len(imgs) {
+		t.Fatalf("got %d pages, want %d", len(pages), len(imgs))
+	}
+
+	// The pages put back together are the job, without invalidate and initialize.
+	var got []byte
+	for i, p := range pages {
+		if p.lines != imgs[i].Bounds().Dy() {
+			t.Errorf("page %d: got %d lines, want %d", i, p.lines, imgs[i].Bounds().Dy())
+		}
+		if want := i == len(pages)-1; p.feed != want {
+			t.Errorf("page %d: got feed %v, want %v", i, p.feed, want)
+		}
+
+		data, err := p.encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, data...)
+	}
+
+	want := bytes.TrimPrefix(job, invalidate)
+	want = bytes.TrimPrefix(want, initialize)
+	if !bytes.Equal(got, want) {
+		t.Errorf("pages don't match the job")
+	}
+}
+
+func TestSplitJobCommands(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		job   []byte
+		lines []int
+		data  [][]byte
+	}{
+		{
+			name:  "status requests dropped",
+			job:   []byte{0x00, 0x00, 0x1B, 0x40, 0x1B, 0x69, 0x53, 0x5A, 0x1A},
+			lines: []int{1},
+			data:  [][]byte{{0x5A, 0x1A}},
+		},
+		{
+			name:  "0x67 raster lines",
+			job:   []byte{0x67, 0x00, 0x02, 0xFF, 0x80, 0x67, 0x00, 0x01, 0x01, 0x1A},
+			lines: []int{2},
+			data:  [][]byte{{0x67, 0x00, 0x02, 0xFF, 0x80, 0x67, 0x00, 0x01, 0x01, 0x1A}},
+		},
+		{
+			name:  "settings",
+			job:   []byte{0x1B, 0x69, 0x61, 0x01, 0x1B, 0x69, 0x64, 0x0E, 0x00, 0x4D, 0x02, 0x47, 0x01, 0x00, 0xAA, 0x0C, 0x5A, 0x5A, 0x1A},
+			lines: []int{1, 2},
+			data: [][]byte{
+				{0x1B, 0x69, 0x61, 0x01, 0x1B, 0x69, 0x64, 0x0E, 0x00, 0x4D, 0x02, 0x47, 0x01, 0x00, 0xAA, 0x0C},
+				{0x5A, 0x5A, 0x1A},
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pages, err := splitJob(test.job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pages) != len(test.lines) {
+				t.Fatalf("got %d pages, want %d", len(pages), len(test.lines))
+			}
+
+			for i, p := range pages {
+				if p.lines != test.lines[i] {
+					t.Errorf("page %d: got %d lines, want %d", i, p.lines, test.lines[i])
+				}
+				data, err := p.encode()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, test.data[i]) {
+					t.Errorf("page %d: got %x, want %x", i, data, test.data[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitJobErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		job  []byte
+		err  string
+	}{
+		{"empty", nil, "job has no pages"},
+		{"only initialize", []byte{0x00, 0x1B, 0x40}, "job has no pages"},
+		{"no print command", []byte{0x5A, 0x5A}, "doesn't end with a print command"},
+		{"unknown command", []byte{0x5A, 0x42, 0x1A}, "unknown command 0x42 at byte 1"},
+		{"truncated raster line length", []byte{0x5A, 0x47, 0x10}, "truncated raster line at byte 1"},
+		{"truncated raster line", []byte{0x47, 0x10, 0x00, 0xFF, 0x1A}, "truncated command 0x47 at byte 0"},
+		{"truncated print information", []byte{0x1B, 0x69, 0x7A, 0x84, 0x00}, "truncated command 0x1b at byte 0"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := splitJob(test.job)
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %q, want %q", err, test.err)
+			}
+		})
+	}
+}
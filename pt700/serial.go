@@ -0,0 +1,115 @@
+//go:build linux
+
+package pt700
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// serialPrefix starts the path of printers with a serial port: serial:/dev/ttyUSB0?baud=115200&flow=rtscts.
+// usblp.Find uses serial:XXXX for USB serial numbers, serial ports are told apart by their absolute path.
+const serialPrefix = "serial:/"
+
+// IsSerial reports whether path is a serial port, rather than a usblp device.
+func IsSerial(path string) bool {
+	return strings.HasPrefix(path, serialPrefix)
+}
+
+// serialPort is the configuration of a serial port.
+type serialPort struct {
+	path string
+	baud int
+	// rtscts enables hardware flow control.
+	// Software flow control isn't supported, the XON and XOFF characters can appear in raster data.
+	rtscts bool
+}
+
+// Default baud rate of Brother printers with a serial port.
+const defaultBaud = 9600
+
+// Baud rates supported by termios.
+var bauds = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// parseSerial parses a serial:/dev/ttyXXX?baud=N&flow=none|rtscts path.
+func parseSerial(path string) (serialPort, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return serialPort{}, err
+	}
+
+	port := serialPort{
+		path: u.Path,
+		baud: defaultBaud,
+	}
+
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "baud":
+			baud, err := strconv.Atoi(value)
+			if err != nil {
+				return serialPort{}, fmt.Errorf("%s: baud: %w", path, err)
+			}
+			if _, ok := bauds[baud]; !ok {
+				return serialPort{}, fmt.Errorf("%s: unsupported baud rate %d", path, baud)
+			}
+			port.baud = baud
+		case "flow":
+			switch value {
+			case "none":
+				port.rtscts = false
+			case "rtscts":
+				port.rtscts = true
+			default:
+				return serialPort{}, fmt.Errorf("%s: unsupported flow control %q, expected none or rtscts", path, value)
+			}
+		default:
+			return serialPort{}, fmt.Errorf("%s: unknown option %q, expected baud or flow", path, key)
+		}
+	}
+
+	return port, nil
+}
+
+// configure puts the serial port in raw mode, 8N1, with its baud rate and flow control.
+func (s serialPort) configure(fd int) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("%s: not a serial port: %w", s.path, err)
+	}
+
+	// Like cfmakeraw().
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL
+
+	speed := bauds[s.baud]
+	t.Cflag |= speed
+	t.Ispeed, t.Ospeed = speed, speed
+
+	if s.rtscts {
+		t.Cflag |= unix.CRTSCTS
+	}
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return fmt.Errorf("%s: configuring serial port: %w", s.path, err)
+	}
+	return nil
+}
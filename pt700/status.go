@@ -12,15 +12,27 @@ type Status struct {
 	MediaType  MediaType
 	Type       StatusType
 	Phase      PhaseType
+	// Colors of the cassette, only reported by some models.
+	TapeColor TapeColor
+	TextColor TextColor
+}
+
+// Media describes the loaded tape, for example "Black on White 12mm".
+func (s Status) Media() string {
+	if s.TapeColor == TapeNone || s.TextColor == TextNone {
+		return s.MediaWidth.String()
+	}
+	return fmt.Sprintf("%v on %v %v", s.TextColor, s.TapeColor, s.MediaWidth)
 }
 
 // Err returns an error representing this status, or nil if there is no error.
+// The error is an Error1 or Error2, to check the cause with errors.As.
 func (s Status) Err() error {
 	if s.Err1 != 0 {
-		return fmt.Errorf("%v", s.Err1)
+		return s.Err1
 	}
 	if s.Err2 != 0 {
-		return fmt.Errorf("%v", s.Err2)
+		return s.Err2
 	}
 	return nil
 }
@@ -38,6 +50,10 @@ const (
 	_
 )
 
+func (e Error1) Error() string {
+	return e.String()
+}
+
 func (e Error1) String() string {
 	return bitfieldString(e, []bitfield[Error1]{
 		{Err1NoMedia, "NoMedia"},
@@ -89,6 +105,10 @@ const (
 	_
 )
 
+func (e Error2) Error() string {
+	return e.String()
+}
+
 func (e Error2) String() string {
 	return bitfieldString(e, []bitfield[Error2]{
 		{Err2ReplaceMedia, "ReplaceMedia"},
@@ -148,22 +168,22 @@ func (w MediaWidth) MinDy() int {
 	return 172
 }
 
-// PTouch printers expect full width data even with narrow media.
-// Software has to explicitly skip pins outside of the print area.
-func (w MediaWidth) unusedPins(printerPins int) (int, error) {
-	width, err := w.Dx()
-	if err != nil {
-		return 0, err
-	}
-
-	return (128 - width) / 2, nil
+// MaxDy returns the maximum height of images that can be printed, in pixels.
+func (w MediaWidth) MaxDy() int {
+	// Brother PDF 2.3.3 gives a maximum length of 1m.
+	return 1000 * 10 * dpi / 254
 }
 
 func (w MediaWidth) DPI() int {
-	// Brother PDF 2.3.4
-	return 180
+	return dpi
 }
 
+// Brother PDF 2.3.4
+const dpi = 180
+
+// Number of pins of the PT-700 print head.
+const pins = 128
+
 type MediaType byte
 
 const (
@@ -0,0 +1,82 @@
+package pt700
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// TraceDirection is the direction data was sent in.
+type TraceDirection string
+
+const (
+	// Data written to the printer.
+	TraceWrite TraceDirection = "w"
+	// Data read from the printer.
+	TraceRead TraceDirection = "r"
+)
+
+// TraceEntry is one read or write recorded in a trace.
+type TraceEntry struct {
+	Time      time.Time
+	Direction TraceDirection
+	Data      []byte
+}
+
+// tracer writes a trace, one line per entry:
+//
+//	<RFC 3339 time> <w|r> <hex data>
+type tracer struct {
+	w io.Writer
+}
+
+func (t tracer) trace(dir TraceDirection, b []byte) {
+	if t.w == nil || len(b) == 0 {
+		return
+	}
+
+	// The trace is a debugging aid, don't fail the print because of it.
+	_, _ = fmt.Fprintf(t.w, "%s %s %s\n", time.Now().Format(time.RFC3339Nano), dir, hex.EncodeToString(b))
+}
+
+// ReadTrace parses a trace recorded with OpenOpts.Trace.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+
+	scanner := bufio.NewScanner(r)
+	// Raster lines are small, but be generous in case of big writes.
+	scanner.Buffer(nil, 1<<20)
+
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 fields, got %d", line, len(fields))
+		}
+
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		dir := TraceDirection(fields[1])
+		if dir != TraceWrite && dir != TraceRead {
+			return nil, fmt.Errorf("line %d: unknown direction %q", line, dir)
+		}
+
+		data, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		entries = append(entries, TraceEntry{
+			Time:      t,
+			Direction: dir,
+			Data:      data,
+		})
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,375 @@
+// Package pwg decodes PWG raster and Apple raster (URF) streams, the page formats IPP clients
+// and CUPS send to printers, to grayscale images.
+package pwg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+)
+
+// Page is a page of a raster stream.
+type Page struct {
+	*image.Gray
+	// DPI is the horizontal and vertical resolution of the page.
+	DPI image.Point
+}
+
+var (
+	pwgMagic = []byte("RaS2")
+	urfMagic = []byte("UNIRAST\x00")
+)
+
+// ErrFormat is returned when a stream isn't PWG raster or URF.
+var ErrFormat = errors.New("not a PWG raster or URF stream")
+
+// IsRaster reports whether a stream starting with prefix is PWG raster or URF.
+func IsRaster(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, pwgMagic) || bytes.HasPrefix(prefix, urfMagic)
+}
+
+// Decode decodes all the pages of a PWG raster or URF stream.
+func Decode(r io.Reader) ([]Page, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(len(urfMagic))
+	if err != nil && !bytes.HasPrefix(magic, pwgMagic) {
+		return nil, ErrFormat
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, pwgMagic):
+		br.Discard(len(pwgMagic))
+		return decodePWG(br)
+	case bytes.HasPrefix(magic, urfMagic):
+		br.Discard(len(urfMagic))
+		return decodeURF(br)
+	default:
+		return nil, ErrFormat
+	}
+}
+
+// Largest pages decoded, in pixels, so a corrupt header can't use up all the memory:
+// a 1m long label at 600dpi is about 24000 pixels long.
+const (
+	maxSide   = 1 << 16
+	maxPixels = 1 << 28
+)
+
+// format describes how the pixels of a page are encoded.
+type format struct {
+	width, height int
+	// bitsPerPixel is 1 for bilevel pages, or a multiple of 8.
+	bitsPerPixel int
+	// white is the value of the bytes of a white pixel.
+	white byte
+	// gray converts the bytes of a pixel to a gray level, for pages with at least 8 bits per pixel.
+	gray func(px []byte) uint8
+	// blackBit is set if 1 bits are black in bilevel pages.
+	blackBit bool
+}
+
+func (f format) bytesPerLine() int {
+	return (f.width*f.bitsPerPixel + 7) / 8
+}
+
+// decode decodes the compressed pixels of a page.
+// Each line starts with the number of times it's repeated minus one, followed by runs of pixels:
+// n < 128 repeats the next pixel n+1 times, n > 128 is followed by 257-n pixels, and 128 fills the line with white.
+// Bilevel pixels are compressed a byte at a time.
+func (f format) decode(r *bufio.Reader) (*image.Gray, error) {
+	if f.width <= 0 || f.height <= 0 || f.width > maxSide || f.height > maxSide || f.width*f.height > maxPixels {
+		return nil, fmt.Errorf("invalid page size %dx%d", f.width, f.height)
+	}
+
+	unit := max(1, f.bitsPerPixel/8)
+	line := make([]byte, f.bytesPerLine())
+	img := image.NewGray(image.Rect(0, 0, f.width, f.height))
+
+	for y := 0; y < f.height; {
+		repeat, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", y, unexpected(err))
+		}
+
+		for i := 0; i < len(line); {
+			n, err := r.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", y, unexpected(err))
+			}
+
+			switch {
+			case n == 128:
+				for j := i; j < len(line); j++ {
+					line[j] = f.white
+				}
+				i = len(line)
+
+			case n < 128:
+				count := (int(n) + 1) * unit
+				if i+count > len(line) {
+					return nil, fmt.Errorf("line %d: run overflows the line", y)
+				}
+				if _, err := io.ReadFull(r, line[i:i+unit]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", y, unexpected(err))
+				}
+				for j := i + unit; j < i+count; j += unit {
+					copy(line[j:j+unit], line[i:i+unit])
+				}
+				i += count
+
+			default:
+				count := (257 - int(n)) * unit
+				if i+count > len(line) {
+					return nil, fmt.Errorf("line %d: run overflows the line", y)
+				}
+				if _, err := io.ReadFull(r, line[i:i+count]); err != nil {
+					return nil, fmt.Errorf("line %d: %w", y, unexpected(err))
+				}
+				i += count
+			}
+		}
+
+		for n := 0; n <= int(repeat) && y < f.height; n++ {
+			f.convert(line, img.Pix[img.PixOffset(0, y):][:f.width])
+			y++
+		}
+	}
+
+	return img, nil
+}
+
+// convert converts a line of pixels to gray levels.
+func (f format) convert(line []byte, row []uint8) {
+	if f.bitsPerPixel == 1 {
+		for x := range row {
+			bit := line[x/8]&(0x80>>(x%8)) != 0
+			if bit == f.blackBit {
+				row[x] = 0
+			} else {
+				row[x] = 0xFF
+			}
+		}
+		return
+	}
+
+	unit := f.bitsPerPixel / 8
+	for x := range row {
+		row[x] = f.gray(line[x*unit : (x+1)*unit])
+	}
+}
+
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Gray levels of pixels, from their color components of 8 or 16 bits.
+// Only the most significant byte of 16 bit components is used.
+
+func grayLevel(bytesPerColor int) func(px []byte) uint8 {
+	return func(px []byte) uint8 {
+		return px[0]
+	}
+}
+
+func blackLevel(bytesPerColor int) func(px []byte) uint8 {
+	return func(px []byte) uint8 {
+		return 0xFF - px[0]
+	}
+}
+
+func rgbLevel(bytesPerColor int) func(px []byte) uint8 {
+	return func(px []byte) uint8 {
+		r, g, b := uint32(px[0]), uint32(px[bytesPerColor]), uint32(px[2*bytesPerColor])
+		// Rec. 601 weights, the same as color.GrayModel.
+		return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 16)
+	}
+}
+
+func cmykLevel(bytesPerColor int) func(px []byte) uint8 {
+	return func(px []byte) uint8 {
+		c, m, y, k := uint32(px[0]), uint32(px[bytesPerColor]), uint32(px[2*bytesPerColor]), uint32(px[3*bytesPerColor])
+		ink := (19595*c+38470*m+7471*y+1<<15)>>16 + k
+		return 0xFF - uint8(min(ink, 0xFF))
+	}
+}
+
+// PWG raster page header fields, as offsets in the header.
+// https://ftp.pwg.org/pub/pwg/candidates/cs-ippraster10-20120420-5102.4.pdf
+const (
+	pwgHeaderSize = 1796
+
+	pwgHWResolution = 276
+	pwgWidth        = 372
+	pwgHeight       = 376
+	pwgBitsPerColor = 384
+	pwgBitsPerPixel = 388
+	pwgBytesPerLine = 392
+	pwgColorOrder   = 396
+	pwgColorSpace   = 400
+	pwgNumColors    = 420
+)
+
+// PWG raster color spaces.
+const (
+	pwgRGB      = 1
+	pwgBlack    = 3
+	pwgCMYK     = 6
+	pwgSGray    = 18
+	pwgSRGB     = 19
+	pwgAdobeRGB = 20
+)
+
+func decodePWG(r *bufio.Reader) ([]Page, error) {
+	var pages []Page
+	header := make([]byte, pwgHeaderSize)
+	for {
+		_, err := io.ReadFull(r, header)
+		if errors.Is(err, io.EOF) && len(pages) != 0 {
+			return pages, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("page %d: header: %w", len(pages), unexpected(err))
+		}
+
+		field := func(offset int) int {
+			return int(binary.BigEndian.Uint32(header[offset:]))
+		}
+
+		f := format{
+			width:        field(pwgWidth),
+			height:       field(pwgHeight),
+			bitsPerPixel: field(pwgBitsPerPixel),
+		}
+		bitsPerColor := field(pwgBitsPerColor)
+		colors := field(pwgNumColors)
+
+		if field(pwgColorOrder) != 0 {
+			return nil, fmt.Errorf("page %d: unsupported color order %d", len(pages), field(pwgColorOrder))
+		}
+		if bitsPerColor != 1 && bitsPerColor != 8 && bitsPerColor != 16 {
+			return nil, fmt.Errorf("page %d: unsupported %d bits per color", len(pages), bitsPerColor)
+		}
+		if f.bitsPerPixel != bitsPerColor*colors || (bitsPerColor == 1 && colors != 1) {
+			return nil, fmt.Errorf("page %d: unsupported %d bits per pixel with %d colors", len(pages), f.bitsPerPixel, colors)
+		}
+		if field(pwgBytesPerLine) != f.bytesPerLine() {
+			return nil, fmt.Errorf("page %d: %d bytes per line, expected %d", len(pages), field(pwgBytesPerLine), f.bytesPerLine())
+		}
+
+		bytesPerColor := max(1, bitsPerColor/8)
+		switch space := field(pwgColorSpace); {
+		case space == pwgSGray && colors == 1:
+			f.white = 0xFF
+			f.gray = grayLevel(bytesPerColor)
+		case space == pwgBlack && colors == 1:
+			f.blackBit = true
+			f.gray = blackLevel(bytesPerColor)
+		case (space == pwgRGB || space == pwgSRGB || space == pwgAdobeRGB) && colors == 3:
+			f.white = 0xFF
+			f.gray = rgbLevel(bytesPerColor)
+		case space == pwgCMYK && colors == 4:
+			f.gray = cmykLevel(bytesPerColor)
+		default:
+			return nil, fmt.Errorf("page %d: unsupported color space %d with %d colors", len(pages), space, colors)
+		}
+
+		img, err := f.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", len(pages), err)
+		}
+
+		pages = append(pages, Page{
+			Gray: img,
+			DPI:  image.Pt(field(pwgHWResolution), field(pwgHWResolution+4)),
+		})
+	}
+}
+
+// URF page header fields, as offsets in the header.
+const (
+	urfHeaderSize = 32
+
+	urfBitsPerPixel = 0
+	urfColorSpace   = 1
+	urfWidth        = 12
+	urfHeight       = 16
+	urfDPI          = 20
+)
+
+// URF color spaces.
+const (
+	urfSGray    = 0
+	urfSRGB     = 1
+	urfAdobeRGB = 3
+	urfGray     = 4
+	urfRGB      = 5
+	urfCMYK     = 6
+)
+
+func decodeURF(r *bufio.Reader) ([]Page, error) {
+	var count [4]byte
+	if _, err := io.ReadFull(r, count[:]); err != nil {
+		return nil, fmt.Errorf("page count: %w", unexpected(err))
+	}
+
+	var pages []Page
+	header := make([]byte, urfHeaderSize)
+	for i := 0; i < int(binary.BigEndian.Uint32(count[:])); i++ {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("page %d: header: %w", i, unexpected(err))
+		}
+
+		field := func(offset int) int {
+			return int(binary.BigEndian.Uint32(header[offset:]))
+		}
+
+		f := format{
+			width:        field(urfWidth),
+			height:       field(urfHeight),
+			bitsPerPixel: int(header[urfBitsPerPixel]),
+			white:        0xFF,
+		}
+
+		var colors int
+		var level func(bytesPerColor int) func(px []byte) uint8
+		switch header[urfColorSpace] {
+		case urfSGray, urfGray:
+			colors = 1
+			level = grayLevel
+		case urfSRGB, urfAdobeRGB, urfRGB:
+			colors = 3
+			level = rgbLevel
+		case urfCMYK:
+			colors = 4
+			f.white = 0
+			level = cmykLevel
+		default:
+			return nil, fmt.Errorf("page %d: unsupported color space %d", i, header[urfColorSpace])
+		}
+		if f.bitsPerPixel != 8*colors && f.bitsPerPixel != 16*colors {
+			return nil, fmt.Errorf("page %d: unsupported %d bits per pixel with %d colors", i, f.bitsPerPixel, colors)
+		}
+		f.gray = level(f.bitsPerPixel / 8 / colors)
+
+		img, err := f.decode(r)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", i, err)
+		}
+
+		dpi := field(urfDPI)
+		pages = append(pages, Page{
+			Gray: img,
+			DPI:  image.Pt(dpi, dpi),
+		})
+	}
+
+	return pages, nil
+}
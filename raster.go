@@ -0,0 +1,56 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/pwg"
+)
+
+// Raster converts a page of a PWG raster or URF stream to a label.
+// Landscape pages are rotated so their top is at the start of the tape's width, like text.
+// The page is scaled to the resolution of the printer, and cropped to the printable width of the tape around its center
+// unless opts.Fit is set, as pages sized for a tape include its unprintable margins.
+func Raster(b Bounds, page pwg.Page, opts ImageOpts) (*monochrome.Image, error) {
+	if page.DPI.X <= 0 || page.DPI.Y <= 0 {
+		return nil, fmt.Errorf("invalid page resolution %dx%d", page.DPI.X, page.DPI.Y)
+	}
+
+	return fitPage(b, page.Gray, page.DPI, true, opts)
+}
+
+// fitPage converts a page with a resolution of dpi to a label, rotating it if it's landscape and scaling it to b.DPI.
+// Pages wider than the printable width of the tape are cropped around their center if crop is set, or shrunk to fit it.
+func fitPage(b Bounds, gray *image.Gray, dpi image.Point, crop bool, opts ImageOpts) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	if gray.Bounds().Dx() > gray.Bounds().Dy() {
+		gray = monochrome.Rotate270Gray(gray)
+		dpi = image.Pt(dpi.Y, dpi.X)
+	}
+
+	if dpi.X != b.DPI || dpi.Y != b.DPI {
+		dx := max(1, int(math.Round(float64(gray.Bounds().Dx()*b.DPI)/float64(dpi.X))))
+		dy := max(1, int(math.Round(float64(gray.Bounds().Dy()*b.DPI)/float64(dpi.Y))))
+		gray = resize(gray, dx, dy, opts.Filter)
+	}
+
+	inner, _ := opts.Frame.inner(b, 0)
+	if !opts.Fit && gray.Bounds().Dx() > inner.Dx {
+		if !crop {
+			opts.Fit = true
+			return Image(b, gray, opts)
+		}
+
+		r := gray.Bounds()
+		r.Min.X += (r.Dx() - inner.Dx) / 2
+		r.Max.X = r.Min.X + inner.Dx
+		gray = gray.SubImage(r).(*image.Gray)
+	}
+
+	return Image(b, gray, opts)
+}
@@ -0,0 +1,149 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Filter is how images are resampled when they're scaled.
+type Filter int
+
+const (
+	// Auto uses Box when downscaling, so thin lines turn gray instead of disappearing,
+	// and Nearest when upscaling.
+	FilterAuto Filter = iota
+	// Nearest picks the closest pixel, keeping pixel art and barcodes crisp.
+	FilterNearest
+	// Box averages the pixels covered by each pixel.
+	FilterBox
+	// CatmullRom is a cubic filter, for smooth photos.
+	FilterCatmullRom
+)
+
+func (f Filter) String() string {
+	switch f {
+	case FilterAuto:
+		return "auto"
+	case FilterNearest:
+		return "nearest"
+	case FilterBox:
+		return "box"
+	case FilterCatmullRom:
+		return "catmull-rom"
+	default:
+		return fmt.Sprintf("Filter(%d)", int(f))
+	}
+}
+
+// ParseFilter parses the String() of a Filter.
+func ParseFilter(s string) (Filter, error) {
+	for _, f := range []Filter{FilterAuto, FilterNearest, FilterBox, FilterCatmullRom} {
+		if s == f.String() {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown filter %q", s)
+}
+
+// kernel returns the filter's weight function and its support in source pixels, for a scale factor of 1.
+func (f Filter) kernel() (func(t float64) float64, float64) {
+	switch f {
+	case FilterCatmullRom:
+		return func(t float64) float64 {
+			t = math.Abs(t)
+			if t < 1 {
+				return (3*t*t*t - 5*t*t + 2) / 2
+			}
+			return (-t*t*t + 5*t*t - 8*t + 4) / 2
+		}, 2
+	default:
+		return func(t float64) float64 { return 1 }, 0.5
+	}
+}
+
+// resize scales img to dx by dy pixels.
+func resize(img *image.Gray, dx, dy int, filter Filter) *image.Gray {
+	if filter == FilterAuto {
+		filter = FilterBox
+		if dx >= img.Bounds().Dx() {
+			filter = FilterNearest
+		}
+	}
+
+	// Separable, so scale across and then along.
+	return resizeAxis(resizeAxis(img, dx, true, filter), dy, false, filter)
+}
+
+// resizeAxis scales img to n pixels along one axis, x if horizontal or y otherwise.
+func resizeAxis(img *image.Gray, n int, horizontal bool, filter Filter) *image.Gray {
+	src := img.Bounds().Sub(img.Bounds().Min)
+	srcN, other := src.Dx(), src.Dy()
+	dstR := image.Rect(0, 0, n, other)
+	if !horizontal {
+		srcN, other = src.Dy(), src.Dx()
+		dstR = image.Rect(0, 0, other, n)
+	}
+	dst := image.NewGray(dstR)
+
+	at := func(i, j int) float64 {
+		if horizontal {
+			return float64(img.GrayAt(img.Rect.Min.X+i, img.Rect.Min.Y+j).Y)
+		}
+		return float64(img.GrayAt(img.Rect.Min.X+j, img.Rect.Min.Y+i).Y)
+	}
+	set := func(i, j int, v float64) {
+		v = math.Round(min(max(v, 0), 0xFF))
+		if horizontal {
+			dst.Pix[dst.PixOffset(i, j)] = uint8(v)
+		} else {
+			dst.Pix[dst.PixOffset(j, i)] = uint8(v)
+		}
+	}
+
+	scale := float64(srcN) / float64(n)
+
+	if filter == FilterNearest {
+		for i := 0; i < n; i++ {
+			si := min(int((float64(i)+0.5)*scale), srcN-1)
+			for j := 0; j < other; j++ {
+				set(i, j, at(si, j))
+			}
+		}
+		return dst
+	}
+
+	// Stretch the kernel when downscaling, so every source pixel contributes.
+	kernel, support := filter.kernel()
+	stretch := max(scale, 1)
+	support *= stretch
+
+	for i := 0; i < n; i++ {
+		center := (float64(i) + 0.5) * scale
+
+		// Weights of the source pixels, which don't depend on j.
+		lo := max(int(math.Floor(center-support)), 0)
+		hi := min(int(math.Ceil(center+support)), srcN)
+		weights := make([]float64, hi-lo)
+		var total float64
+		for si := lo; si < hi; si++ {
+			t := (float64(si) + 0.5 - center) / stretch
+			if math.Abs(t)*stretch > support {
+				continue
+			}
+			weights[si-lo] = kernel(t)
+			total += weights[si-lo]
+		}
+
+		for j := 0; j < other; j++ {
+			var sum float64
+			for si := lo; si < hi; si++ {
+				sum += weights[si-lo] * at(si, j)
+			}
+			set(i, j, sum/total)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,100 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+type SplitOpts struct {
+	// Overlap is the length at the end of each page repeated at the start of the next one, in mm.
+	Overlap float64
+	// Marker draws an arrow at the end of every page but the last,
+	// to show the label continues on the next one.
+	Marker bool
+}
+
+// Split splits an image longer than b.MaxDy into several images of similar lengths,
+// that each fit within b.
+// Images that already fit are returned as is.
+func Split(b Bounds, img *monochrome.Image, opts SplitOpts) ([]*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	dy := img.Bounds().Dy()
+	if b.MaxDy == 0 || dy <= b.MaxDy {
+		return []*monochrome.Image{img}, nil
+	}
+
+	overlap := b.MMToPx(opts.Overlap)
+
+	// Space for the marker, and a gap the same size before it.
+	var markerDy int
+	if opts.Marker {
+		markerDy = 2 * arrowDy(b)
+	}
+
+	// Every page but the first shows overlap pixels of the previous one.
+	step := b.MaxDy - markerDy - overlap
+	if step <= 0 {
+		return nil, fmt.Errorf("overlap %vmm is too long for %dpx pages", opts.Overlap, b.MaxDy)
+	}
+
+	pages := (dy - overlap + step - 1) / step
+	step = (dy - overlap + pages - 1) / pages
+
+	// Labels start at Max.Y, so that's the first page.
+	var imgs []*monochrome.Image
+	for i := 0; i < pages; i++ {
+		y := img.Bounds().Max.Y - i*step
+		r := image.Rect(
+			img.Bounds().Min.X, max(y-step-overlap, img.Bounds().Min.Y),
+			img.Bounds().Max.X, y,
+		)
+
+		last := i == pages-1
+
+		pageR := r
+		if !last {
+			pageR.Min.Y -= markerDy
+		}
+
+		page := monochrome.New(pageR)
+		monochrome.Draw(page, r, img, r.Min, draw.Src)
+
+		if opts.Marker && !last {
+			drawArrow(b, page)
+		}
+
+		// The last page could be shorter than the others.
+		page, err := pad(b, b.MinDy, AlignStart, page)
+		if err != nil {
+			return nil, err
+		}
+
+		imgs = append(imgs, page)
+	}
+
+	return imgs, nil
+}
+
+// Length of the continuation arrow, in pixels.
+func arrowDy(b Bounds) int {
+	return b.Dx / 3
+}
+
+// drawArrow draws an arrow pointing to the end of the label, at the end (Min.Y) of img.
+func drawArrow(b Bounds, img *monochrome.Image) {
+	dy := arrowDy(b)
+	midX := img.Bounds().Min.X + img.Bounds().Dx()/2
+
+	// Triangle, narrowing to a point at Min.Y.
+	for y := 0; y < dy; y++ {
+		halfWidth := y * b.Dx / (4 * dy)
+
+		img.FillRect(image.Rect(midX-halfWidth, img.Bounds().Min.Y+y, midX+halfWidth+1, img.Bounds().Min.Y+y+1), true)
+	}
+}
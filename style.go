@@ -0,0 +1,209 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Style of text.
+type Style int
+
+const (
+	StyleRegular    Style = 0
+	StyleBold       Style = 1 << 0
+	StyleItalic     Style = 1 << 1
+	StyleBoldItalic       = StyleBold | StyleItalic
+)
+
+func (s Style) String() string {
+	switch s {
+	case StyleRegular:
+		return "regular"
+	case StyleBold:
+		return "bold"
+	case StyleItalic:
+		return "italic"
+	case StyleBoldItalic:
+		return "bold-italic"
+	default:
+		return fmt.Sprintf("Style(%d)", int(s))
+	}
+}
+
+// ParseStyle parses "regular", "bold", "italic" or "bold-italic".
+func ParseStyle(s string) (Style, error) {
+	for _, style := range []Style{StyleRegular, StyleBold, StyleItalic, StyleBoldItalic} {
+		if strings.EqualFold(s, style.String()) {
+			return style, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown style %q, expected regular, bold, italic or bold-italic", s)
+}
+
+// AddFont sets the font used to render text in a style.
+// Styles without a font are synthesized from the closest font available:
+// bold by thickening glyphs, and italic by slanting them.
+func (r *Renderer) AddFont(style Style, f *opentype.Font) {
+	r.fonts[style] = f
+
+	// Cached faces and sizes might have been synthesized for this style.
+	r.faces = make(map[faceKey]font.Face)
+	r.fits = make(map[fitKey]float64)
+}
+
+// fontFor returns the font closest to style, and the style that needs to be synthesized on top of it.
+func (r *Renderer) fontFor(style Style) (*opentype.Font, Style) {
+	for _, s := range []Style{style, style &^ StyleItalic, style &^ StyleBold} {
+		if f, ok := r.fonts[s]; ok {
+			return f, style &^ s
+		}
+	}
+	return r.fonts[StyleRegular], style
+}
+
+const (
+	// Slant of synthesized italics, tan(12°).
+	italicSlant = 0.2126
+	// Extra thickness of synthesized bold, as a fraction of the font size.
+	boldWeight = 1.0 / 24
+)
+
+// synthFace synthesizes a bold and / or italic face from a regular one.
+type synthFace struct {
+	font.Face
+
+	// Pixels added to the width of strokes.
+	bold int
+	// Horizontal offset per pixel above the baseline.
+	slant float64
+}
+
+func newSynthFace(face font.Face, style Style, size float64, dpi int) font.Face {
+	if style == StyleRegular {
+		return face
+	}
+
+	s := synthFace{Face: face}
+	if style&StyleBold != 0 {
+		s.bold = max(1, int(math.Round(size*float64(dpi)/72*boldWeight)))
+	}
+	if style&StyleItalic != 0 {
+		s.slant = italicSlant
+	}
+	return s
+}
+
+// shift is the horizontal offset of a row of pixels y below the baseline.
+func (s synthFace) shift(y float64) float64 {
+	return -y * s.slant
+}
+
+func (s synthFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	dr, mask, maskp, advance, ok := s.Face.Glyph(dot, r)
+	if !ok || dr.Empty() {
+		return dr, mask, maskp, advance + fixed.I(s.bold), ok
+	}
+
+	baseline := float64(dot.Y) / 64
+	dst := image.NewAlpha(image.Rect(
+		dr.Min.X+int(math.Floor(s.shift(float64(dr.Max.Y)-baseline))),
+		dr.Min.Y,
+		dr.Max.X+int(math.Ceil(s.shift(float64(dr.Min.Y)-baseline)))+s.bold,
+		dr.Max.Y,
+	))
+
+	// Alpha of the original glyph at (x, y), in dst coordinates.
+	at := func(x, y int) float64 {
+		if !(image.Point{x, y}).In(dr) {
+			return 0
+		}
+		_, _, _, a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+		return float64(a)
+	}
+
+	for y := dst.Rect.Min.Y; y < dst.Rect.Max.Y; y++ {
+		// Shift the middle of the pixel, interpolating between the two source pixels it lands on.
+		shift := s.shift(float64(y) + 0.5 - baseline)
+		whole := int(math.Floor(shift))
+		frac := shift - float64(whole)
+
+		for x := dst.Rect.Min.X; x < dst.Rect.Max.X; x++ {
+			var a float64
+			for b := 0; b <= s.bold; b++ {
+				sx := x - whole - b
+				a = max(a, (1-frac)*at(sx, y)+frac*at(sx-1, y))
+			}
+			dst.SetAlpha(x, y, color.Alpha{uint8(math.Round(a / 0xffff * 0xff))})
+		}
+	}
+
+	return dst.Rect, dst, dst.Rect.Min, advance + fixed.I(s.bold), true
+}
+
+func (s synthFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	bounds, advance, ok := s.Face.GlyphBounds(r)
+
+	bounds.Min.X += fixed.Int26_6(math.Floor(s.shift(float64(bounds.Max.Y))))
+	bounds.Max.X += fixed.Int26_6(math.Ceil(s.shift(float64(bounds.Min.Y)))) + fixed.I(s.bold)
+
+	return bounds, advance + fixed.I(s.bold), ok
+}
+
+func (s synthFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	advance, ok := s.Face.GlyphAdvance(r)
+	return advance + fixed.I(s.bold), ok
+}
+
+// Hinting is how glyph outlines are snapped to pixels.
+type Hinting int
+
+const (
+	// Full snaps glyphs to pixels horizontally and vertically.
+	HintingFull Hinting = iota
+	// Vertical only snaps glyphs to pixels vertically.
+	HintingVertical
+	// None draws glyphs as designed.
+	HintingNone
+)
+
+func (h Hinting) String() string {
+	switch h {
+	case HintingFull:
+		return "full"
+	case HintingVertical:
+		return "vertical"
+	case HintingNone:
+		return "none"
+	default:
+		return fmt.Sprintf("Hinting(%d)", int(h))
+	}
+}
+
+// ParseHinting parses the String() of a Hinting.
+func ParseHinting(s string) (Hinting, error) {
+	for _, h := range []Hinting{HintingFull, HintingVertical, HintingNone} {
+		if s == h.String() {
+			return h, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown hinting %q", s)
+}
+
+func (h Hinting) font() font.Hinting {
+	switch h {
+	case HintingVertical:
+		return font.HintingVertical
+	case HintingNone:
+		return font.HintingNone
+	default:
+		return font.HintingFull
+	}
+}
@@ -0,0 +1,86 @@
+package etiquette
+
+import (
+	"image"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// TestPage draws a calibration pattern, to check the alignment and density of new printers and tapes.
+// From the start of the label:
+//   - Full width rules 1, 2, 3 and 4 pixels thick.
+//   - Pin index marks: a band with every pin, and marks every 10 and 50 pins.
+//     The first and last pins are marked all the way, to check the tape is centered.
+//   - A 50mm ruler, with marks every mm.
+//   - Checkerboards of 1, 2 and 4 pixel squares.
+func TestPage(b Bounds) (*monochrome.Image, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+
+	margin := b.MMToPx(2)
+	gap := b.MMToPx(1)
+
+	rulesDy := 0
+	for thickness := 1; thickness <= 4; thickness++ {
+		rulesDy += thickness + gap
+	}
+	pinsDy := b.MMToPx(8)
+	rulerDy := b.MMToPx(50)
+	checkerDy := b.MMToPx(5)
+
+	dy := 2*margin + rulesDy + pinsDy + rulerDy + 3*checkerDy + 5*gap
+	img := monochrome.New(image.Rect(0, 0, b.Dx, dy))
+
+	// Labels are printed from Max.Y, d is the distance from the start of the label.
+	d := margin
+	rect := func(x0, d0, x1, d1 int) {
+		img.FillRect(image.Rect(x0, dy-d1, x1, dy-d0), true)
+	}
+
+	for thickness := 1; thickness <= 4; thickness++ {
+		rect(0, d, b.Dx, d+thickness)
+		d += thickness + gap
+	}
+
+	rect(0, d, b.Dx, d+gap)
+	for x := 0; x < b.Dx; x++ {
+		switch {
+		case x == 0 || x == b.Dx-1:
+			rect(x, d, x+1, d+pinsDy)
+		case x%50 == 0:
+			rect(x, d, x+1, d+pinsDy*3/4)
+		case x%10 == 0:
+			rect(x, d, x+1, d+pinsDy/2)
+		}
+	}
+	d += pinsDy + gap
+
+	for mm := 0; mm <= 50; mm++ {
+		length := b.Dx / 6
+		switch {
+		case mm%10 == 0:
+			length = b.Dx / 2
+		case mm%5 == 0:
+			length = b.Dx / 3
+		}
+
+		y := d + b.MMToPx(float64(mm))
+		rect(0, y, length, y+1)
+		rect(b.Dx-length, y, b.Dx, y+1)
+	}
+	d += rulerDy + gap
+
+	for _, square := range []int{1, 2, 4} {
+		for x := 0; x < b.Dx; x++ {
+			for y := 0; y < checkerDy; y++ {
+				if (x/square+y/square)%2 == 0 {
+					rect(x, d+y, x+1, d+y+1)
+				}
+			}
+		}
+		d += checkerDy + gap
+	}
+
+	return Image(b, img, ImageOpts{})
+}
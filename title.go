@@ -0,0 +1,57 @@
+package etiquette
+
+import (
+	"image"
+	"image/draw"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// Titled renders a title, with smaller subtitle text under it.
+// The subtitle can have several lines, and is rendered like Text in the bottom third of the tape.
+// opts apply to the whole label, except Size which only applies to the title.
+func (r *Renderer) Titled(b Bounds, title, subtitle string, opts TextOpts) (*monochrome.Image, error) {
+	if subtitle == "" {
+		return r.Text(b, title, opts)
+	}
+
+	// Render each part to fit in its share of the inside of the frame, without any minimum length.
+	dx := b.Dx - 2*opts.Frame.size(b)
+	titleDx := dx * 2 / 3
+
+	partOpts := opts.partOpts()
+	titleImg, err := r.textGray(Bounds{Dx: titleDx, DPI: b.DPI}, title, partOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	partOpts.Size = 0
+	subtitleImg, err := r.textGray(Bounds{Dx: dx - titleDx, DPI: b.DPI}, subtitle, partOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	dy := max(titleImg.Bounds().Dy(), subtitleImg.Bounds().Dy())
+	dst := image.NewGray(image.Rect(0, 0, dx, dy))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+
+	// The top of the text is at Min.X.
+	x := 0
+	for _, part := range []*image.Gray{titleImg, subtitleImg} {
+		pb := part.Bounds()
+
+		// Labels are printed from Max.Y.
+		y := (dy - pb.Dy() + 1) / 2
+		switch opts.Align {
+		case AlignStart:
+			y = dy - pb.Dy()
+		case AlignEnd:
+			y = 0
+		}
+
+		draw.Draw(dst, pb.Sub(pb.Min).Add(image.Pt(x, y)), part, pb.Min, draw.Src)
+		x += pb.Dx()
+	}
+
+	return Image(b, dst, opts.imageOpts())
+}
@@ -0,0 +1,160 @@
+package etiquette
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MissingGlyphsError is returned when a font doesn't have glyphs for some of the text.
+type MissingGlyphsError struct {
+	// Runes without glyphs, in the order they first appear.
+	Runes []rune
+}
+
+func (e MissingGlyphsError) Error() string {
+	return fmt.Sprintf("font has no glyphs for %q", string(e.Runes))
+}
+
+// ShapingError is returned for text in scripts that need complex shaping (joined or reordered glyphs),
+// which isn't supported: drawing glyphs one after the other would print them wrong.
+// Right to left scripts that don't join glyphs, like Hebrew, are supported, and so are the Arabic letters
+// with presentation forms.
+type ShapingError struct {
+	Script string
+}
+
+func (e ShapingError) Error() string {
+	return fmt.Sprintf("%s text needs shaping, which isn't supported", e.Script)
+}
+
+// Scripts that join or reorder glyphs.
+var shapedScripts = []string{
+	"Arabic", "Syriac", "Thaana", "Nko", "Mongolian",
+	"Devanagari", "Bengali", "Gurmukhi", "Gujarati", "Oriya", "Tamil", "Telugu", "Kannada", "Malayalam",
+	"Sinhala", "Thai", "Lao", "Tibetan", "Myanmar", "Khmer",
+}
+
+// checkShaping returns a ShapingError if text needs complex shaping.
+// shapeArabic must already have been applied to text.
+func checkShaping(text string) error {
+	for _, c := range text {
+		for _, script := range shapedScripts {
+			if !unicode.Is(unicode.Scripts[script], c) {
+				continue
+			}
+			if script == "Arabic" && !arabicJoins(c) {
+				break
+			}
+			return ShapingError{Script: script}
+		}
+	}
+
+	return nil
+}
+
+// missing returns the runes of text the font has no glyphs for.
+func (r *Renderer) missing(text string, style Style) ([]rune, error) {
+	f, _ := r.fontFor(style)
+
+	var (
+		missing []rune
+		seen    = make(map[rune]bool)
+	)
+
+	for _, c := range text {
+		// Handled by Text(), not drawn.
+		if c == '\n' || seen[c] {
+			continue
+		}
+		seen[c] = true
+
+		idx, err := f.GlyphIndex(&r.buf, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if idx == 0 {
+			missing = append(missing, c)
+		}
+	}
+
+	return missing, nil
+}
+
+// transliterate replaces the runes of text in missing with ASCII approximations,
+// if there is one.
+func transliterate(text string, missing []rune) string {
+	return strings.Map(func(c rune) rune {
+		for _, m := range missing {
+			if c == m {
+				if ascii, ok := asciiFallbacks[c]; ok {
+					return ascii
+				}
+			}
+		}
+		return c
+	}, text)
+}
+
+// asciiFallbacks approximates common non ASCII characters.
+// Only single runes are supported, so ligatures like Æ lose a letter.
+var asciiFallbacks = func() map[rune]rune {
+	pairs := []string{
+		"ÀÁÂÃÄÅĀĂĄ", "A",
+		"àáâãäåāăą", "a",
+		"ÇĆĈĊČ", "C",
+		"çćĉċč", "c",
+		"ĎĐ", "D",
+		"ďđ", "d",
+		"ÈÉÊËĒĔĖĘĚ", "E",
+		"èéêëēĕėęě", "e",
+		"ĜĞĠĢ", "G",
+		"ĝğġģ", "g",
+		"ĤĦ", "H",
+		"ĥħ", "h",
+		"ÌÍÎÏĨĪĬĮİ", "I",
+		"ìíîïĩīĭįı", "i",
+		"Ĵ", "J",
+		"ĵ", "j",
+		"Ķ", "K",
+		"ķ", "k",
+		"ĹĻĽĿŁ", "L",
+		"ĺļľŀł", "l",
+		"ÑŃŅŇ", "N",
+		"ñńņň", "n",
+		"ÒÓÔÕÖØŌŎŐ", "O",
+		"òóôõöøōŏő", "o",
+		"ŔŖŘ", "R",
+		"ŕŗř", "r",
+		"ŚŜŞŠ", "S",
+		"śŝşš", "s",
+		"ŢŤŦ", "T",
+		"ţťŧ", "t",
+		"ÙÚÛÜŨŪŬŮŰŲ", "U",
+		"ùúûüũūŭůűų", "u",
+		"Ŵ", "W",
+		"ŵ", "w",
+		"ÝŶŸ", "Y",
+		"ýÿŷ", "y",
+		"ŹŻŽ", "Z",
+		"źżž", "z",
+		"Æ", "A",
+		"æ", "a",
+		"ß", "s",
+		"‘’‚′", "'",
+		"“”„″«»", "\"",
+		"‐‑‒–—−", "-",
+		"•·", "*",
+		"    ", " ",
+	}
+
+	fallbacks := make(map[rune]rune)
+	for i := 0; i < len(pairs); i += 2 {
+		ascii := []rune(pairs[i+1])[0]
+		for _, c := range pairs[i] {
+			fallbacks[c] = ascii
+		}
+	}
+	return fallbacks
+}()
@@ -0,0 +1,125 @@
+//go:build linux
+
+package usblp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// PermissionError is returned when the device node of a printer can't be opened for lack of permissions,
+// and explains how to get them.
+type PermissionError struct {
+	Path string
+	// Group owning the device node, if it can read and write it.
+	Group string
+	// Configured is true if the user is in Group, but not in this session yet.
+	Configured bool
+	// NixOS is true if udev rules and groups have to be set in the NixOS configuration.
+	NixOS bool
+
+	Err error
+}
+
+func (e *PermissionError) Error() string {
+	var fix string
+	switch {
+	case e.Group != "" && e.Configured:
+		fix = fmt.Sprintf("You're in the %[1]s group, but not in this session yet: log out and back in, or run newgrp %[1]s.", e.Group)
+
+	case e.Group != "" && e.NixOS:
+		fix = fmt.Sprintf("Add yourself to the %[1]s group in configuration.nix, then log out and back in:\n\n"+
+			"    users.users.<name>.extraGroups = [ %[2]q ];", e.Group, e.Group)
+
+	case e.Group != "":
+		fix = fmt.Sprintf("Add yourself to the %[1]s group, then log out and back in:\n\n"+
+			"    sudo usermod -aG %[1]s $USER", e.Group)
+
+	case e.NixOS:
+		fix = "Add a udev rule giving the lp group access to it in configuration.nix, and add yourself to the group:\n\n" +
+			"    services.udev.extraRules = ''\n" +
+			"      " + udevRule + "\n" +
+			"    '';\n" +
+			"    users.users.<name>.extraGroups = [ \"lp\" ];"
+
+	default:
+		fix = "Add a udev rule giving the lp group access to it, and add yourself to the group, then log out and back in:\n\n" +
+			"    echo '" + udevRule + "' | sudo tee /etc/udev/rules.d/60-etiquette.rules\n" +
+			"    sudo udevadm control --reload && sudo udevadm trigger\n" +
+			"    sudo usermod -aG lp $USER"
+	}
+
+	return fmt.Sprintf("can't open %s: %v\n%s", e.Path, e.Err, fix)
+}
+
+func (e *PermissionError) Unwrap() error {
+	return e.Err
+}
+
+// udevRule lets the lp group, and the user logged in at the seat, use Brother printers.
+const udevRule = `SUBSYSTEM=="usbmisc", KERNEL=="lp*", ATTRS{idVendor}=="04f9", GROUP="lp", MODE="0660", TAG+="uaccess"`
+
+// DiagnosePermission returns a *PermissionError explaining how to get access to the device node at path,
+// if opening it failed with err because of its permissions. Otherwise it returns err.
+func DiagnosePermission(path string, err error) error {
+	if !errors.Is(err, fs.ErrPermission) {
+		return err
+	}
+
+	perr := &PermissionError{
+		Path:  path,
+		NixOS: isNixOS(),
+		Err:   err,
+	}
+
+	info, statErr := os.Stat(path)
+	if statErr != nil {
+		return perr
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return perr
+	}
+
+	// A udev rule is needed if the group can't read and write the device.
+	if info.Mode().Perm()&0o060 != 0o060 {
+		return perr
+	}
+
+	gid := strconv.FormatUint(uint64(stat.Gid), 10)
+	group, lookupErr := user.LookupGroupId(gid)
+	if lookupErr != nil {
+		return perr
+	}
+	perr.Group = group.Name
+
+	// The groups of this session are only updated when logging in again.
+	if u, err := user.Current(); err == nil {
+		if configured, err := u.GroupIds(); err == nil {
+			perr.Configured = slices.Contains(configured, gid)
+		}
+	}
+
+	return perr
+}
+
+func isNixOS() bool {
+	release, err := os.ReadFile("/etc/os-release")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(release), "\n") {
+		if line == "ID=nixos" || line == `ID="nixos"` {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package usblp
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// LPGETSTATUS from linux/lp.h.
+const lpGetStatus = 0x060b
+
+// PortStatus is the IEEE 1284 port status the usblp driver reads from the printer.
+type PortStatus int
+
+// Bits of PortStatus, from linux/lp.h.
+const (
+	// Active low.
+	statusNoError  PortStatus = 0x08
+	statusSelect   PortStatus = 0x10
+	statusPaperOut PortStatus = 0x20
+)
+
+var (
+	ErrPaperOut = errors.New("printer out of paper")
+	ErrOffline  = errors.New("printer offline")
+	ErrPort     = errors.New("printer reported an error")
+)
+
+// GetPortStatus reads the port status of the printer open as fd.
+// It only asks the USB device, so it's much faster than waiting for the printer to answer a command.
+func GetPortStatus(fd int) (PortStatus, error) {
+	status, err := unix.IoctlGetInt(fd, lpGetStatus)
+	return PortStatus(status), err
+}
+
+// Err returns an error representing this status, or nil if the printer is ready.
+func (s PortStatus) Err() error {
+	switch {
+	case s&statusPaperOut != 0:
+		return ErrPaperOut
+	case s&statusSelect == 0:
+		return ErrOffline
+	case s&statusNoError == 0:
+		return ErrPort
+	default:
+		return nil
+	}
+}
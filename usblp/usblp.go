@@ -0,0 +1,233 @@
+//go:build linux
+
+// Package usblp finds printers handled by the Linux usblp driver, and reads their port status.
+package usblp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Device is a printer handled by the usblp driver.
+type Device struct {
+	// Path of the device node, of the form /dev/usb/lpN.
+	Path string
+	DeviceInfo
+}
+
+// DeviceInfo describes the USB device of a printer.
+// Strings are empty if the device doesn't report them.
+type DeviceInfo struct {
+	Manufacturer string
+	Product      string
+	// Serial number of the USB device.
+	Serial string
+
+	VendorID  uint16
+	ProductID uint16
+
+	// BusPath is the USB bus and ports the device is plugged into, for example 1-1.2.
+	// It stays the same as long as the device is plugged into the same port.
+	BusPath string
+}
+
+func (i DeviceInfo) String() string {
+	s := fmt.Sprintf("%04x:%04x", i.VendorID, i.ProductID)
+	if name := strings.TrimSpace(i.Manufacturer + " " + i.Product); name != "" {
+		s = fmt.Sprintf("%s (%s)", name, s)
+	}
+	if i.Serial != "" {
+		s += " serial:" + i.Serial
+	}
+	if i.BusPath != "" {
+		s += " usb:" + i.BusPath
+	}
+	return s
+}
+
+// Where usblp devices show up in sysfs.
+const sysClass = "/sys/class/usbmisc"
+
+// Connected returns all the connected usblp devices.
+// This only reads sysfs, the devices aren't opened.
+//
+// Devices that can't be read are skipped and reported in warnings,
+// so one broken device doesn't hide the others.
+func Connected() (devices []Device, warnings []error, err error) {
+	entries, err := os.ReadDir(sysClass)
+	switch {
+	// No usbmisc devices at all.
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil, nil
+	case err != nil:
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "lp") {
+			continue
+		}
+
+		device, err := connected(entry.Name())
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, warnings, nil
+}
+
+// Where USB devices show up in sysfs.
+const sysUSB = "/sys/bus/usb/devices"
+
+// USBDevices returns all the connected USB devices, whether usblp handles them or not.
+// Devices that can't be read are skipped and reported in warnings.
+func USBDevices() (infos []DeviceInfo, warnings []error, err error) {
+	entries, err := os.ReadDir(sysUSB)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil, nil
+	case err != nil:
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		// Interfaces of devices are named after them, with a :config.interface suffix.
+		if strings.Contains(entry.Name(), ":") {
+			continue
+		}
+
+		info, err := deviceInfo(filepath.Join(sysUSB, entry.Name()))
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, warnings, nil
+}
+
+func connected(name string) (Device, error) {
+	// device is the USB interface, attributes are on the parent USB device.
+	iface, err := filepath.EvalSymlinks(filepath.Join(sysClass, name, "device"))
+	if err != nil {
+		return Device{}, err
+	}
+
+	info, err := deviceInfo(filepath.Dir(iface))
+	if err != nil {
+		return Device{}, err
+	}
+
+	return Device{
+		Path:       filepath.Join("/dev/usb", name),
+		DeviceInfo: info,
+	}, nil
+}
+
+// deviceInfo reads the attributes of a USB device from its sysfs directory.
+func deviceInfo(dir string) (DeviceInfo, error) {
+	info := DeviceInfo{
+		// The directory is named after the bus and ports.
+		BusPath: filepath.Base(dir),
+	}
+
+	for name, dst := range map[string]*string{
+		"manufacturer": &info.Manufacturer,
+		"product":      &info.Product,
+		"serial":       &info.Serial,
+	} {
+		val, err := attr(dir, name)
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+		*dst = val
+	}
+
+	for name, dst := range map[string]*uint16{
+		"idVendor":  &info.VendorID,
+		"idProduct": &info.ProductID,
+	} {
+		val, err := attr(dir, name)
+		if err != nil {
+			return DeviceInfo{}, err
+		}
+
+		id, err := strconv.ParseUint(val, 16, 16)
+		if err != nil {
+			return DeviceInfo{}, fmt.Errorf("%s: %w", name, err)
+		}
+		*dst = uint16(id)
+	}
+
+	return info, nil
+}
+
+// attr reads a sysfs attribute, returning "" if it doesn't exist.
+func attr(dir, name string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Find returns the path of the device node of a printer.
+// name is either the path itself (/dev/usb/lpN), or serial:XXXX to find a printer by
+// USB serial number, which doesn't change when printers are plugged in a different order.
+// serial:/dev/ttyXXX, a serial port rather than a serial number, is returned as is.
+func Find(name string) (string, error) {
+	serial, ok := strings.CutPrefix(name, "serial:")
+	if !ok || strings.HasPrefix(serial, "/") {
+		return name, nil
+	}
+
+	devices, warnings, err := Connected()
+	if err != nil {
+		return "", err
+	}
+
+	for _, device := range devices {
+		if device.Serial == serial {
+			return device.Path, nil
+		}
+	}
+
+	// It could be one of the devices we couldn't read.
+	return "", errors.Join(append([]error{fmt.Errorf("no printer with serial %q connected", serial)}, warnings...)...)
+}
+
+// Lookup returns the usblp device at path, of the form /dev/usb/lpN.
+func Lookup(path string) (Device, error) {
+	dir, name := filepath.Split(path)
+	if filepath.Clean(dir) != "/dev/usb" || !strings.HasPrefix(name, "lp") {
+		return Device{}, fmt.Errorf("%s isn't a usblp device", path)
+	}
+
+	return connected(name)
+}
+
+// Name returns the name identifying the printer at path across runs: serial:XXXX if it's a usblp device
+// that reports a serial number, as /dev/usb/lpN changes when printers are reconnected.
+// Other paths, and names that are already serial:XXXX, are returned as is.
+func Name(path string) string {
+	device, err := Lookup(path)
+	if err != nil || device.Serial == "" {
+		return path
+	}
+	return "serial:" + device.Serial
+}
@@ -0,0 +1,154 @@
+package etiquette
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"go.afab.re/etiquette/barcode"
+	"go.afab.re/etiquette/monochrome"
+	"go.afab.re/etiquette/zpl"
+)
+
+// Largest ZPL label drawn, in dots each way: 1m at 203 DPI, the longest label the printer can print.
+// Fields are drawn at their size before the label is shrunk to fit the tape, this keeps corrupt labels
+// from using up all the memory.
+const maxZPLDots = 1000 * 10 * zpl.DPI / 254
+
+// ZPL renders a label parsed from ZPL as an image suitable for printing.
+// The label is drawn like a Zebra printer would, rotated if it's landscape, and shrunk if it's wider than the tape.
+// Text is rendered with the regular font of r, and returns the same errors as Text.
+func (r *Renderer) ZPL(b Bounds, label zpl.Label, opts ImageOpts) (*monochrome.Image, error) {
+	type field struct {
+		rect image.Rectangle
+		draw func(dst *image.Gray, rect image.Rectangle)
+	}
+
+	var fields []field
+	canvas := image.Rect(0, 0, label.Width, label.Length)
+	for i, f := range label.Fields {
+		var size image.Point
+		// ascent is the distance from the top of the field to its baseline.
+		var ascent int
+
+		switch f.Kind {
+		case zpl.FieldText:
+			if f.Height > maxZPLDots {
+				return nil, fmt.Errorf("field %d: text %d dots high is larger than %d", i, f.Height, maxZPLDots)
+			}
+
+			text, err := r.check(f.Data, TextOpts{})
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i, err)
+			}
+
+			// Font heights are the size of the em square.
+			face, err := r.face(float64(f.Height)*72/zpl.DPI, zpl.DPI, StyleRegular, HintingFull)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i, err)
+			}
+
+			ascent = face.Metrics().Ascent.Ceil()
+			size = image.Pt(font.MeasureString(face, text).Ceil(), face.Metrics().Height.Ceil())
+			fields = append(fields, field{
+				draw: func(dst *image.Gray, rect image.Rectangle) {
+					d := font.Drawer{
+						Dst:  dst,
+						Src:  image.Black,
+						Face: face,
+						Dot:  fixed.P(rect.Min.X, rect.Min.Y+ascent),
+					}
+					d.DrawString(text)
+				},
+			})
+
+		case zpl.FieldCode128:
+			code, err := barcode.Code128(f.Data)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", i, err)
+			}
+			// Zebra printers draw barcodes from their first bar, without a quiet zone.
+			bars, module := trimQuietZone(code), f.Module
+
+			size = image.Pt(bars.Bounds().Dx()*module, f.Height)
+			ascent = size.Y
+			fields = append(fields, field{
+				draw: func(dst *image.Gray, rect image.Rectangle) {
+					for x := 0; x < bars.Bounds().Dx(); x++ {
+						if bars.BlackAt(bars.Bounds().Min.X+x, bars.Bounds().Min.Y) {
+							bar := image.Rect(rect.Min.X+x*module, rect.Min.Y, rect.Min.X+(x+1)*module, rect.Max.Y)
+							draw.Draw(dst, bar, image.Black, image.Point{}, draw.Src)
+						}
+					}
+				},
+			})
+
+		case zpl.FieldGraphic:
+			graphic := f.Graphic
+			size = graphic.Bounds().Size()
+			ascent = size.Y
+			fields = append(fields, field{
+				draw: func(dst *image.Gray, rect image.Rectangle) {
+					draw.DrawMask(dst, rect, image.Black, image.Point{}, blackMask{graphic}, graphic.Bounds().Min, draw.Over)
+				},
+			})
+
+		default:
+			return nil, fmt.Errorf("field %d: unknown kind %d", i, f.Kind)
+		}
+
+		origin := f.Origin
+		if f.Baseline {
+			origin.Y -= ascent
+		}
+		fields[len(fields)-1].rect = image.Rectangle{origin, origin.Add(size)}
+		canvas = canvas.Union(fields[len(fields)-1].rect)
+	}
+
+	if canvas.Empty() {
+		return nil, fmt.Errorf("empty label")
+	}
+	if canvas.Dx() > maxZPLDots || canvas.Dy() > maxZPLDots {
+		return nil, fmt.Errorf("label of %dx%d dots is larger than %dx%d", canvas.Dx(), canvas.Dy(), maxZPLDots, maxZPLDots)
+	}
+
+	dst := image.NewGray(canvas)
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	for _, f := range fields {
+		f.draw(dst, f.rect)
+	}
+
+	return fitPage(b, dst, image.Pt(zpl.DPI, zpl.DPI), false, opts)
+}
+
+// trimQuietZone crops the white space before the first bar and after the last bar of a linear barcode.
+func trimQuietZone(code *monochrome.Image) *monochrome.Image {
+	r := code.Bounds()
+	for r.Min.X < r.Max.X && !code.BlackAt(r.Min.X, r.Min.Y) {
+		r.Min.X++
+	}
+	for r.Max.X > r.Min.X && !code.BlackAt(r.Max.X-1, r.Min.Y) {
+		r.Max.X--
+	}
+	return code.Crop(r)
+}
+
+// blackMask is an alpha mask that is opaque where a monochrome image is black.
+type blackMask struct {
+	*monochrome.Image
+}
+
+func (m blackMask) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (m blackMask) At(x, y int) color.Color {
+	if m.BlackAt(x, y) {
+		return color.Opaque
+	}
+	return color.Transparent
+}
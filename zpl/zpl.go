@@ -0,0 +1,423 @@
+// Package zpl parses a subset of the Zebra Programming Language (ZPL II),
+// so labels from software that only emits ZPL can be printed.
+//
+// Supported commands are:
+//   - ^XA and ^XZ, the start and end of a label.
+//   - ^PW and ^LL, the width and length of the label.
+//   - ^LH, the label home that field origins are relative to.
+//   - ^PQ, the number of copies.
+//   - ^FO and ^FT, the origin of a field from its top left, or from its baseline.
+//   - ^A and ^CF, the height of text. Fonts are always the regular font of the renderer.
+//   - ^BY and ^BC, Code 128 barcodes. The interpretation line isn't printed.
+//   - ^GF, ASCII hex graphic fields, compressed or not.
+//   - ^FD, ^FH and ^FS, the data of a field, hex escapes in it, and the end of a field.
+//   - ^FX, comments.
+//
+// Other commands are ignored, and listed in the Unsupported commands of a label.
+package zpl
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// DPI is the resolution of ZPL coordinates, that of most Zebra printers.
+const DPI = 203
+
+// Label is a label, from ^XA to ^XZ.
+type Label struct {
+	// Width and Length of the label in dots, set by ^PW and ^LL. 0 if not set.
+	Width, Length int
+	// Copies of the label to print, at least 1.
+	Copies int
+
+	Fields []Field
+
+	// Unsupported are the commands that were ignored, like ^CI, in the order they first appear.
+	Unsupported []string
+}
+
+type FieldKind int
+
+const (
+	FieldText FieldKind = iota
+	FieldCode128
+	FieldGraphic
+)
+
+// Field is something drawn on a label.
+type Field struct {
+	Kind FieldKind
+	// Origin is the top left of the field in dots,
+	// or the left of its baseline (the bottom of barcodes and graphics) if Baseline is set.
+	Origin   image.Point
+	Baseline bool
+
+	// Data is the text, or the data of the barcode.
+	Data string
+	// Height of text or barcodes, in dots.
+	Height int
+	// Module is the width of the narrowest bar of barcodes, in dots.
+	Module int
+	// Graphic is the image of graphic fields.
+	Graphic *monochrome.Image
+}
+
+// Defaults, from the ZPL II programming guide.
+const (
+	defaultFontHeight    = 9
+	defaultModule        = 2
+	defaultBarcodeHeight = 10
+)
+
+// parser is the state of the label being parsed.
+type parser struct {
+	label   Label
+	ignored map[string]bool
+
+	home       image.Point
+	fontHeight int
+	module     int
+	barHeight  int
+
+	// Current field.
+	field Field
+	hex   byte
+}
+
+// Parse parses the labels of a ZPL stream.
+func Parse(r io.Reader) ([]Label, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []Label
+	var p *parser
+	for len(data) != 0 {
+		start := bytes.IndexAny(data, "^~")
+		if start == -1 {
+			break
+		}
+		data = data[start:]
+
+		// Parameters run until the next command.
+		end := bytes.IndexAny(data[1:], "^~")
+		if end == -1 {
+			end = len(data)
+		} else {
+			end++
+		}
+		cmd, params := parseCommand(data[:end])
+		data = data[end:]
+
+		switch {
+		case cmd == "^XA":
+			p = newParser()
+			continue
+		case p == nil:
+			// Commands outside labels configure the printer.
+			continue
+		case cmd == "^XZ":
+			labels = append(labels, p.label)
+			p = nil
+			continue
+		}
+
+		if err := p.command(cmd, params); err != nil {
+			return nil, fmt.Errorf("label %d: %s: %w", len(labels), cmd, err)
+		}
+	}
+
+	if p != nil {
+		return nil, fmt.Errorf("label %d: missing ^XZ", len(labels))
+	}
+	return labels, nil
+}
+
+// parseCommand splits a command into its name, like ^FO, and its parameters.
+func parseCommand(cmd []byte) (string, string) {
+	// Line breaks are ignored.
+	cmd = bytes.ReplaceAll(cmd, []byte("\r"), nil)
+	cmd = bytes.ReplaceAll(cmd, []byte("\n"), nil)
+
+	// ^A is followed directly by the font name.
+	n := 3
+	if bytes.HasPrefix(bytes.ToUpper(cmd), []byte("^A")) {
+		n = 2
+	}
+	n = min(n, len(cmd))
+
+	return strings.ToUpper(string(cmd[:n])), string(cmd[n:])
+}
+
+func newParser() *parser {
+	p := &parser{
+		label:      Label{Copies: 1},
+		ignored:    make(map[string]bool),
+		fontHeight: defaultFontHeight,
+		module:     defaultModule,
+		barHeight:  defaultBarcodeHeight,
+	}
+	p.resetField()
+	return p
+}
+
+func (p *parser) resetField() {
+	p.field = Field{Origin: p.home}
+	p.hex = 0
+}
+
+func (p *parser) command(cmd, params string) error {
+	args := strings.Split(params, ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	switch cmd {
+	case "^PW":
+		return intArgs(args, &p.label.Width)
+
+	case "^LL":
+		return intArgs(args, &p.label.Length)
+
+	case "^LH":
+		return intArgs(args, &p.home.X, &p.home.Y)
+
+	case "^PQ":
+		if err := intArgs(args, &p.label.Copies); err != nil {
+			return err
+		}
+		p.label.Copies = max(1, p.label.Copies)
+
+	case "^FO", "^FT":
+		var origin image.Point
+		if err := intArgs(args, &origin.X, &origin.Y); err != nil {
+			return err
+		}
+		p.field.Origin = p.home.Add(origin)
+		p.field.Baseline = cmd == "^FT"
+
+	case "^CF":
+		// Font name, height, width.
+		return intArgs(args[1:], &p.fontHeight)
+
+	case "^A":
+		// Font name and orientation, height, width.
+		if len(args[0]) > 1 && args[0][1:] != "N" {
+			return fmt.Errorf("unsupported orientation %q", args[0][1:])
+		}
+		if len(args) > 1 && args[1] != "" {
+			return intArgs(args[1:2], &p.field.Height)
+		}
+
+	case "^BY":
+		// Module width, wide to narrow bar ratio, height.
+		if err := intArgs(args[:1], &p.module); err != nil {
+			return err
+		}
+		if len(args) > 2 {
+			return intArgs(args[2:3], &p.barHeight)
+		}
+
+	case "^BC":
+		// Orientation, height, interpretation line, above, check digit, mode.
+		if args[0] != "" && args[0] != "N" {
+			return fmt.Errorf("unsupported orientation %q", args[0])
+		}
+		p.field.Kind = FieldCode128
+		p.field.Module = p.module
+		p.field.Height = p.barHeight
+		if len(args) > 1 {
+			return intArgs(args[1:2], &p.field.Height)
+		}
+
+	case "^FH":
+		p.hex = '_'
+		if params != "" {
+			p.hex = params[0]
+		}
+
+	case "^FD":
+		data, err := unescape(params, p.hex)
+		if err != nil {
+			return err
+		}
+		p.field.Data = data
+
+	case "^GF":
+		if len(args) < 5 {
+			return fmt.Errorf("expected 5 parameters, got %d", len(args))
+		}
+		if args[0] != "A" {
+			return fmt.Errorf("unsupported compression type %q, only A is", args[0])
+		}
+		var total, rowBytes int
+		if err := intArgs([]string{args[2], args[3]}, &total, &rowBytes); err != nil {
+			return err
+		}
+		graphic, err := graphic(strings.Join(args[4:], ","), total, rowBytes)
+		if err != nil {
+			return err
+		}
+		p.field.Kind = FieldGraphic
+		p.field.Graphic = graphic
+
+	case "^FS":
+		if p.field.Kind == FieldText && p.field.Height == 0 {
+			p.field.Height = p.fontHeight
+		}
+		if p.field.Kind == FieldGraphic || p.field.Data != "" {
+			p.label.Fields = append(p.label.Fields, p.field)
+		}
+		p.resetField()
+
+	case "^FX":
+		// Comment.
+
+	default:
+		if !p.ignored[cmd] {
+			p.ignored[cmd] = true
+			p.label.Unsupported = append(p.label.Unsupported, cmd)
+		}
+	}
+
+	return nil
+}
+
+// Largest integer parameter, the limit of field origins and label lengths in the ZPL II programming guide.
+// It keeps corrupt labels from drawing huge images, or printing endless copies.
+const maxParam = 32000
+
+// intArgs parses leading arguments as integers. Missing or empty arguments leave their value unchanged.
+func intArgs(args []string, values ...*int) error {
+	for i, v := range values {
+		if i >= len(args) || args[i] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(args[i])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return fmt.Errorf("negative parameter %d", n)
+		}
+		if n > maxParam {
+			return fmt.Errorf("parameter %d larger than %d", n, maxParam)
+		}
+		*v = n
+	}
+	return nil
+}
+
+// unescape replaces hex escapes, an indicator followed by two hex digits, with the byte they encode.
+// If indicator is 0, data is returned as is.
+func unescape(data string, indicator byte) (string, error) {
+	if indicator == 0 {
+		return data, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] != indicator {
+			b.WriteByte(data[i])
+			continue
+		}
+
+		if i+3 > len(data) {
+			return "", fmt.Errorf("truncated hex escape %q", data[i:])
+		}
+		n, err := strconv.ParseUint(data[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid hex escape %q", data[i:i+3])
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// Largest graphic field decoded, in bytes, so a corrupt field can't use up all the memory.
+const maxGraphic = 1 << 24
+
+// graphic decodes ASCII hex graphic field data, total bytes of rows of rowBytes each.
+// Runs of a hex digit are compressed with a count before it: G to Y are 1 to 19, g to z are 20 to 400 in steps of 20.
+// A comma fills the rest of the row with 0, an exclamation mark with 1, and a colon repeats the previous row.
+func graphic(data string, total, rowBytes int) (*monochrome.Image, error) {
+	if rowBytes <= 0 || total <= 0 || total%rowBytes != 0 || total > maxGraphic {
+		return nil, fmt.Errorf("invalid graphic field of %d bytes with %d bytes per row", total, rowBytes)
+	}
+	rows := total / rowBytes
+
+	img := monochrome.New(image.Rect(0, 0, rowBytes*8, rows))
+	row := make([]byte, rowBytes)
+	var nibble, count, y int
+
+	endRow := func() {
+		img.SetRow(y, row)
+		y++
+		nibble = 0
+		for i := range row {
+			row[i] = 0
+		}
+	}
+
+	for i := 0; i < len(data) && y < rows; i++ {
+		c := data[i]
+		switch {
+		case c >= 'G' && c <= 'Y':
+			count += int(c-'G') + 1
+			continue
+		case c >= 'g' && c <= 'z':
+			count += (int(c-'g') + 1) * 20
+			continue
+
+		case c == ',' || c == '!':
+			if c == '!' {
+				for n := nibble; n < 2*rowBytes; n++ {
+					row[n/2] |= 0xF << (4 * (1 - n%2))
+				}
+			}
+			endRow()
+
+		case c == ':':
+			if y == 0 {
+				return nil, fmt.Errorf("no row to repeat")
+			}
+			img.CopyRow(y, img, y-1)
+			y++
+			nibble = 0
+
+		default:
+			v, err := strconv.ParseUint(string(c), 16, 8)
+			if err != nil {
+				// Whitespace is ignored.
+				if c == ' ' || c == '\t' {
+					continue
+				}
+				return nil, fmt.Errorf("invalid graphic data %q", c)
+			}
+
+			for n := 0; n < max(1, count) && y < rows; n++ {
+				row[nibble/2] |= byte(v) << (4 * (1 - nibble%2))
+				nibble++
+				if nibble == 2*rowBytes {
+					endRow()
+				}
+			}
+		}
+		count = 0
+	}
+
+	if y < rows && nibble != 0 {
+		endRow()
+	}
+	return img, nil
+}
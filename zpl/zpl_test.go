@@ -0,0 +1,195 @@
+package zpl
+
+import (
+	"strings"
+	"testing"
+
+	"go.afab.re/etiquette/monochrome"
+)
+
+// rows returns the rows of img, with # for black pixels and . for white ones.
+func rows(img *monochrome.Image) []string {
+	var rows []string
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		var row strings.Builder
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			if img.BlackAt(x, y) {
+				row.WriteByte('#')
+			} else {
+				row.WriteByte('.')
+			}
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
+
+func TestGraphic(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		data     string
+		total    int
+		rowBytes int
+		want     []string
+	}{
+		{
+			name:     "hex",
+			data:     "F00F 81A5",
+			total:    4,
+			rowBytes: 2,
+			want: []string{
+				"####........####",
+				"#......##.#..#.#",
+			},
+		},
+		{
+			name:     "counts",
+			data:     "IF0 HF",
+			total:    2,
+			rowBytes: 1,
+			want: []string{
+				"########",
+				"####....",
+			},
+		},
+		{
+			name:     "repeat past a row",
+			data:     "KF",
+			total:    2,
+			rowBytes: 1,
+			want: []string{
+				"########",
+				"########",
+			},
+		},
+		{
+			// Counts add up: g and H are 22 nibbles.
+			name:     "twenty",
+			data:     "gHF",
+			total:    11,
+			rowBytes: 11,
+			want: []string{
+				strings.Repeat("#", 88),
+			},
+		},
+		{
+			name:     "fill rows",
+			data:     "8,C!,",
+			total:    6,
+			rowBytes: 2,
+			want: []string{
+				"#...............",
+				"##..############",
+				"................",
+			},
+		},
+		{
+			name:     "repeat rows",
+			data:     "A5A5::1",
+			total:    8,
+			rowBytes: 2,
+			want: []string{
+				"#.#..#.##.#..#.#",
+				"#.#..#.##.#..#.#",
+				"#.#..#.##.#..#.#",
+				"...#............",
+			},
+		},
+		{
+			// Missing data is white.
+			name:     "short",
+			data:     "F",
+			total:    4,
+			rowBytes: 2,
+			want: []string{
+				"####............",
+				"................",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			img, err := graphic(test.data, test.total, test.rowBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := rows(img)
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestGraphicErrors(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		data     string
+		total    int
+		rowBytes int
+	}{
+		{"no rows", "FF", 0, 1},
+		{"no row bytes", "FF", 2, 0},
+		{"partial row", "FF", 3, 2},
+		{"too large", "FF", maxGraphic + 1, 1},
+		{"repeat first row", ":FF", 2, 1},
+		{"invalid data", "F?", 2, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := graphic(test.data, test.total, test.rowBytes); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	labels, err := Parse(strings.NewReader("^XA^PW400^LL200^LH10,20^FO5,6^A0N,30^FDHello^FS^BY3^FT50,150^BCN,60^FD123^FS^PQ2^CI28^XZ"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 {
+		t.Fatalf("got %d labels, want 1", len(labels))
+	}
+
+	l := labels[0]
+	if l.Width != 400 || l.Length != 200 || l.Copies != 2 {
+		t.Errorf("got %dx%d dots, %d copies, want 400x200 dots, 2 copies", l.Width, l.Length, l.Copies)
+	}
+	if strings.Join(l.Unsupported, " ") != "^CI" {
+		t.Errorf("got unsupported commands %v, want ^CI", l.Unsupported)
+	}
+	if len(l.Fields) != 2 {
+		t.Fatalf("got %d fields, want 2", len(l.Fields))
+	}
+
+	text, code := l.Fields[0], l.Fields[1]
+	if text.Kind != FieldText || text.Data != "Hello" || text.Origin.X != 15 || text.Origin.Y != 26 || text.Height != 30 || text.Baseline {
+		t.Errorf("got text field %+v", text)
+	}
+	if code.Kind != FieldCode128 || code.Data != "123" || code.Origin.X != 60 || code.Origin.Y != 170 || code.Height != 60 || code.Module != 3 || !code.Baseline {
+		t.Errorf("got barcode field %+v", code)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, zpl string
+	}{
+		{"missing end", "^XA^FDHello^FS"},
+		{"negative", "^XA^FO-1,0^FDHello^FS^XZ"},
+		{"huge width", "^XA^PW99999999^XZ"},
+		{"huge length", "^XA^LL32001^XZ"},
+		{"huge home", "^XA^LH0,40000^XZ"},
+		{"huge origin", "^XA^FO1000000,0^FDHello^FS^XZ"},
+		{"huge text", "^XA^A0N,50000^FDHello^FS^XZ"},
+		{"huge copies", "^XA^PQ100000^XZ"},
+		{"rotated", "^XA^A0R,30^FDHello^FS^XZ"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(test.zpl)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}